@@ -6,6 +6,7 @@ import (
 
 type AccessScopesService interface {
 	List(context.Context, interface{}) ([]AccessScope, error)
+	MissingScopes(context.Context) (ScopeSet, error)
 }
 
 type AccessScope struct {
@@ -34,3 +35,19 @@ func (s *AccessScopesServiceOp) List(ctx context.Context, options interface{}) (
 	err = s.client.Do(req, resource)
 	return resource.AccessScopes, err
 }
+
+// MissingScopes returns the scopes of the app which aren't granted to the
+// oauth token, e.g. after an app upgrade added scopes. See App.ReauthorizeUrl
+// to request them.
+func (s *AccessScopesServiceOp) MissingScopes(ctx context.Context) (ScopeSet, error) {
+	scopes, err := s.List(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	handles := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		handles = append(handles, scope.Handle)
+	}
+	return NewScopeSet(handles...).Missing(s.client.app.Scopes()), nil
+}
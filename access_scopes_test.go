@@ -35,3 +35,25 @@ func TestAccessScopesServiceOp_List(t *testing.T) {
 		t.Errorf("AccessScopes.List returned %+v, expected %+v", expected, expected)
 	}
 }
+
+func TestAccessScopesServiceOp_MissingScopes(t *testing.T) {
+	setup()
+	defer teardown()
+
+	httpmock.RegisterResponder(
+		"GET",
+		"https://fooshop.myshopify.com/admin/oauth/access_scopes.json",
+		httpmock.NewStringResponder(200, `{"access_scopes":[{"handle":"write_products"},{"handle":"read_orders"}]}`),
+	)
+
+	client.app.Scope = "read_products,write_orders,read_customers"
+	missing, err := client.AccessScopes.MissingScopes(context.Background())
+	if err != nil {
+		t.Fatalf("AccessScopes.MissingScopes returned an error: %v", err)
+	}
+
+	expected := NewScopeSet("write_orders", "read_customers")
+	if !reflect.DeepEqual(missing, expected) {
+		t.Errorf("AccessScopes.MissingScopes returned %v, expected %v", missing, expected)
+	}
+}
@@ -0,0 +1,192 @@
+package goshopify
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+)
+
+// BulkNode is an object of the JSONL result of a bulk query, with the objects
+// of its nested connections.
+type BulkNode struct {
+	// Id is the global id of the object, if it was queried
+	Id string
+
+	// Typename is the __typename of the object if it was queried, or the
+	// resource type of its global id, e.g. ProductVariant
+	Typename string
+
+	// Data is the JSON line of the object
+	Data json.RawMessage
+
+	// Children are the objects of the nested connections, in the order of
+	// the result
+	Children []*BulkNode
+}
+
+// Decode unmarshals the object into v, a pointer to a struct. The children
+// are decoded into the slice fields tagged with their type name, e.g.
+//
+//	type Product struct {
+//		Id       string    `json:"id"`
+//		Title    string    `json:"title"`
+//		Variants []Variant `json:"-" bulk:"ProductVariant"`
+//	}
+//
+// Children without a matching field are ignored.
+func (n *BulkNode) Decode(v interface{}) error {
+	if err := json.Unmarshal(n.Data, v); err != nil {
+		return fmt.Errorf("decoding bulk object %s: %w", n.Id, err)
+	}
+	if len(n.Children) == 0 {
+		return nil
+	}
+
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.Elem().Kind() != reflect.Struct {
+		return nil
+	}
+	rv = rv.Elem()
+
+	fields := map[string]reflect.Value{}
+	for i := 0; i < rv.NumField(); i++ {
+		typename := rv.Type().Field(i).Tag.Get("bulk")
+		if typename != "" && rv.Field(i).Kind() == reflect.Slice {
+			fields[typename] = rv.Field(i)
+		}
+	}
+
+	for _, child := range n.Children {
+		field, ok := fields[child.Typename]
+		if !ok {
+			continue
+		}
+
+		elemType := field.Type().Elem()
+		elem := reflect.New(elemType)
+		if elemType.Kind() == reflect.Pointer {
+			elem.Elem().Set(reflect.New(elemType.Elem()))
+			if err := child.Decode(elem.Elem().Interface()); err != nil {
+				return err
+			}
+		} else if err := child.Decode(elem.Interface()); err != nil {
+			return err
+		}
+		field.Set(reflect.Append(field, elem.Elem()))
+	}
+	return nil
+}
+
+// BulkDecoder reads the JSONL result of a bulk query, see
+// BulkOperationService.Download. Nested connections are returned as lines
+// referring to their parent with __parentId, the decoder attaches them to
+// their parent so each top level object is returned with its children.
+//
+// Shopify writes the children of an object after it and before the next top
+// level object, the decoder only keeps the current top level object in
+// memory.
+type BulkDecoder struct {
+	r       *bufio.Reader
+	next    *BulkNode
+	pending map[string]*BulkNode
+	err     error
+}
+
+// NewBulkDecoder returns a BulkDecoder reading from r.
+func NewBulkDecoder(r io.Reader) *BulkDecoder {
+	return &BulkDecoder{r: bufio.NewReader(r)}
+}
+
+type bulkLine struct {
+	Id       string `json:"id"`
+	Typename string `json:"__typename"`
+	ParentId string `json:"__parentId"`
+}
+
+// Next returns the next top level object with its children, and io.EOF once
+// all objects were returned.
+func (d *BulkDecoder) Next() (*BulkNode, error) {
+	for d.err == nil {
+		line, err := d.r.ReadBytes('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return nil, err
+		}
+		if err != nil {
+			d.err = err
+		}
+
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+
+		var meta bulkLine
+		if err := json.Unmarshal(line, &meta); err != nil {
+			return nil, fmt.Errorf("decoding bulk result line: %w", err)
+		}
+		node := &BulkNode{Id: meta.Id, Typename: meta.Typename, Data: line}
+		if node.Typename == "" {
+			if gid, err := ParseGID(meta.Id); err == nil {
+				node.Typename = gid.ResourceType
+			}
+		}
+
+		if meta.ParentId != "" {
+			parent, ok := d.pending[meta.ParentId]
+			if !ok {
+				return nil, fmt.Errorf("bulk object %s refers to unknown parent %s", meta.Id, meta.ParentId)
+			}
+			parent.Children = append(parent.Children, node)
+			if node.Id != "" {
+				d.pending[node.Id] = node
+			}
+			continue
+		}
+
+		// a new top level object, the previous one is complete
+		previous := d.next
+		d.next = node
+		d.pending = map[string]*BulkNode{}
+		if node.Id != "" {
+			d.pending[node.Id] = node
+		}
+		if previous != nil {
+			return previous, nil
+		}
+	}
+
+	if d.next != nil {
+		node := d.next
+		d.next, d.pending = nil, nil
+		return node, nil
+	}
+	return nil, d.err
+}
+
+// DecodeBulkResult decodes each top level object of a bulk query result into
+// a T, with its children, see BulkNode.Decode, and calls fn with it. It stops
+// at the first error returned by fn.
+func DecodeBulkResult[T any](r io.Reader, fn func(*T) error) error {
+	d := NewBulkDecoder(r)
+	for {
+		node, err := d.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		v := new(T)
+		if err := node.Decode(v); err != nil {
+			return err
+		}
+		if err := fn(v); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package goshopify
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestBulkDecoderNested(t *testing.T) {
+	type image struct {
+		Src string `json:"src"`
+	}
+	type variant struct {
+		Id     string   `json:"id"`
+		Images []*image `json:"-" bulk:"Image"`
+	}
+	type product struct {
+		Id       string     `json:"id"`
+		Variants []*variant `json:"-" bulk:"ProductVariant"`
+		Images   []image    `json:"-" bulk:"Image"`
+	}
+
+	result := `{"id":"gid://shopify/Product/1"}
+{"id":"gid://shopify/ProductVariant/11","__parentId":"gid://shopify/Product/1"}
+{"src":"a.png","__typename":"Image","__parentId":"gid://shopify/ProductVariant/11"}
+{"src":"b.png","__typename":"Image","__parentId":"gid://shopify/Product/1"}
+{"id":"gid://shopify/Metafield/9","__parentId":"gid://shopify/Product/1"}
+
+{"id":"gid://shopify/Product/2"}`
+
+	d := NewBulkDecoder(strings.NewReader(result))
+
+	node, err := d.Next()
+	if err != nil {
+		t.Fatalf("BulkDecoder.Next returned error: %v", err)
+	}
+	if node.Id != "gid://shopify/Product/1" || node.Typename != "Product" || len(node.Children) != 3 {
+		t.Errorf("BulkDecoder.Next returned %+v", node)
+	}
+
+	var p product
+	if err := node.Decode(&p); err != nil {
+		t.Fatalf("BulkNode.Decode returned error: %v", err)
+	}
+	if len(p.Variants) != 1 || len(p.Variants[0].Images) != 1 || p.Variants[0].Images[0].Src != "a.png" ||
+		len(p.Images) != 1 || p.Images[0].Src != "b.png" {
+		t.Errorf("BulkNode.Decode returned %+v", p)
+	}
+
+	node, err = d.Next()
+	if err != nil || node.Id != "gid://shopify/Product/2" || len(node.Children) != 0 {
+		t.Errorf("BulkDecoder.Next returned %+v, %v, expected the last product", node, err)
+	}
+
+	if _, err := d.Next(); !errors.Is(err, io.EOF) {
+		t.Errorf("BulkDecoder.Next returned error %v, expected io.EOF", err)
+	}
+}
+
+func TestBulkDecoderErrors(t *testing.T) {
+	cases := []struct {
+		name   string
+		result string
+	}{
+		{"invalid line", "{\"id\":\"gid://shopify/Product/1\"}\nnot json\n"},
+		{"unknown parent", "{\"id\":\"gid://shopify/Product/1\"}\n{\"id\":\"gid://shopify/ProductVariant/1\",\"__parentId\":\"gid://shopify/Product/2\"}\n"},
+	}
+
+	for _, c := range cases {
+		err := DecodeBulkResult(strings.NewReader(c.result), func(v *map[string]interface{}) error { return nil })
+		if err == nil {
+			t.Errorf("%s: DecodeBulkResult returned no error", c.name)
+		}
+	}
+
+	stop := errors.New("stop")
+	err := DecodeBulkResult(strings.NewReader("{\"id\":\"gid://shopify/Product/1\"}\n{\"id\":\"gid://shopify/Product/2\"}\n"),
+		func(v *map[string]interface{}) error { return stop })
+	if !errors.Is(err, stop) {
+		t.Errorf("DecodeBulkResult returned error %v, expected the error of the callback", err)
+	}
+
+	if _, err := NewBulkDecoder(strings.NewReader("")).Next(); !errors.Is(err, io.EOF) {
+		t.Errorf("BulkDecoder.Next of an empty result returned error %v, expected io.EOF", err)
+	}
+}
@@ -0,0 +1,367 @@
+package goshopify
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"time"
+)
+
+// BulkOperationService is an interface to interact with the bulk operations
+// of the GraphQL Admin API, which run large queries and mutations
+// asynchronously.
+// See https://shopify.dev/docs/api/usage/bulk-operations/queries
+type BulkOperationService interface {
+	RunQuery(ctx context.Context, query string) (*BulkOperation, error)
+	RunMutation(ctx context.Context, mutation string, variables io.Reader) (*BulkOperation, error)
+	Current(ctx context.Context, operationType BulkOperationType) (*BulkOperation, error)
+	Get(ctx context.Context, id string) (*BulkOperation, error)
+	Cancel(ctx context.Context, id string) (*BulkOperation, error)
+	Wait(ctx context.Context, operation *BulkOperation) (*BulkOperation, error)
+	Download(ctx context.Context, operation *BulkOperation) (io.ReadCloser, error)
+}
+
+// BulkOperationServiceOp handles communication with the bulk operations
+// related methods of the GraphQL Admin API.
+type BulkOperationServiceOp struct {
+	client *Client
+}
+
+// BulkOperationStatus is the status of a bulk operation
+type BulkOperationStatus string
+
+const (
+	BulkOperationStatusCreated   BulkOperationStatus = "CREATED"
+	BulkOperationStatusRunning   BulkOperationStatus = "RUNNING"
+	BulkOperationStatusCompleted BulkOperationStatus = "COMPLETED"
+	BulkOperationStatusCanceling BulkOperationStatus = "CANCELING"
+	BulkOperationStatusCanceled  BulkOperationStatus = "CANCELED"
+	BulkOperationStatusFailed    BulkOperationStatus = "FAILED"
+	BulkOperationStatusExpired   BulkOperationStatus = "EXPIRED"
+)
+
+// Done reports whether the operation is over, successfully or not.
+func (s BulkOperationStatus) Done() bool {
+	switch s {
+	case BulkOperationStatusCompleted, BulkOperationStatusCanceled, BulkOperationStatusFailed, BulkOperationStatusExpired:
+		return true
+	}
+	return false
+}
+
+// BulkOperationType is the type of a bulk operation
+type BulkOperationType string
+
+const (
+	BulkOperationTypeQuery    BulkOperationType = "QUERY"
+	BulkOperationTypeMutation BulkOperationType = "MUTATION"
+)
+
+// ErrBulkOperationFailed is wrapped by the error returned by
+// BulkOperationService.Wait when the operation didn't complete.
+var ErrBulkOperationFailed = errors.New("bulk operation failed")
+
+var (
+	// bulkOperationPollInterval is the first interval between polls of
+	// BulkOperationService.Wait, doubled after each poll up to
+	// bulkOperationMaxPollInterval
+	bulkOperationPollInterval    = time.Second
+	bulkOperationMaxPollInterval = 30 * time.Second
+)
+
+// BulkOperation represents a bulk query or mutation
+type BulkOperation struct {
+	Id          string              `json:"id"`
+	Status      BulkOperationStatus `json:"status"`
+	Type        BulkOperationType   `json:"type"`
+	ErrorCode   string              `json:"errorCode"`
+	CreatedAt   *time.Time          `json:"createdAt"`
+	CompletedAt *time.Time          `json:"completedAt"`
+	ObjectCount int64               `json:"objectCount,string"`
+	FileSize    int64               `json:"fileSize,string"`
+	Query       string              `json:"query"`
+
+	// Url is the JSONL result of the operation, which expires a week after
+	// the operation completed. It is empty if the operation returned no
+	// object.
+	Url string `json:"url"`
+
+	// PartialDataUrl is the partial JSONL result of a failed operation
+	PartialDataUrl string `json:"partialDataUrl"`
+}
+
+// BulkOperationWebhook is the payload of the bulk_operations/finish webhook
+type BulkOperationWebhook struct {
+	AdminGraphqlApiId string     `json:"admin_graphql_api_id"`
+	Status            string     `json:"status"`
+	Type              string     `json:"type"`
+	ErrorCode         string     `json:"error_code"`
+	CreatedAt         *time.Time `json:"created_at"`
+	CompletedAt       *time.Time `json:"completed_at"`
+}
+
+const bulkOperationFields = `id status type errorCode createdAt completedAt objectCount fileSize url partialDataUrl query`
+
+// bulkOperationPayload is the payload of the bulk operation mutations
+type bulkOperationPayload struct {
+	BulkOperation *BulkOperation `json:"bulkOperation"`
+}
+
+// RunQuery starts a bulk query, the query must contain a single top level
+// connection field. Only one bulk query can run at a time per shop.
+func (s *BulkOperationServiceOp) RunQuery(ctx context.Context, query string) (*BulkOperation, error) {
+	q := `mutation bulkOperationRunQuery($query: String!) {
+		bulkOperationRunQuery(query: $query) {
+			bulkOperation { ` + bulkOperationFields + ` }
+			userErrors { field message }
+		}
+	}`
+
+	var resp struct {
+		Payload bulkOperationPayload `json:"bulkOperationRunQuery"`
+	}
+	err := s.client.GraphQL.Mutate(ctx, q, map[string]interface{}{"query": query}, &resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Payload.BulkOperation, nil
+}
+
+// RunMutation uploads the JSONL variables, one line of variables per
+// mutation, and starts a bulk mutation running mutation with each of them.
+// Only one bulk mutation can run at a time per shop.
+// See https://shopify.dev/docs/api/usage/bulk-operations/imports
+func (s *BulkOperationServiceOp) RunMutation(ctx context.Context, mutation string, variables io.Reader) (*BulkOperation, error) {
+	path, err := s.stageVariables(ctx, variables)
+	if err != nil {
+		return nil, err
+	}
+
+	q := `mutation bulkOperationRunMutation($mutation: String!, $stagedUploadPath: String!) {
+		bulkOperationRunMutation(mutation: $mutation, stagedUploadPath: $stagedUploadPath) {
+			bulkOperation { ` + bulkOperationFields + ` }
+			userErrors { field message }
+		}
+	}`
+
+	var resp struct {
+		Payload bulkOperationPayload `json:"bulkOperationRunMutation"`
+	}
+	vars := map[string]interface{}{"mutation": mutation, "stagedUploadPath": path}
+	err = s.client.GraphQL.Mutate(ctx, q, vars, &resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Payload.BulkOperation, nil
+}
+
+// stageVariables uploads the variables of a bulk mutation and returns their
+// staged upload path
+func (s *BulkOperationServiceOp) stageVariables(ctx context.Context, variables io.Reader) (string, error) {
+	q := `mutation stagedUploadsCreate($input: [StagedUploadInput!]!) {
+		stagedUploadsCreate(input: $input) {
+			stagedTargets { url resourceUrl parameters { name value } }
+			userErrors { field message }
+		}
+	}`
+
+	var resp struct {
+		StagedUploadsCreate struct {
+			StagedTargets []struct {
+				Url        string `json:"url"`
+				Parameters []struct {
+					Name  string `json:"name"`
+					Value string `json:"value"`
+				} `json:"parameters"`
+			} `json:"stagedTargets"`
+		} `json:"stagedUploadsCreate"`
+	}
+	vars := map[string]interface{}{
+		"input": []map[string]string{{
+			"resource":   "BULK_MUTATION_VARIABLES",
+			"filename":   "bulk_op_vars",
+			"mimeType":   "text/jsonl",
+			"httpMethod": "POST",
+		}},
+	}
+	err := s.client.GraphQL.Mutate(ctx, q, vars, &resp)
+	if err != nil {
+		return "", err
+	}
+	if len(resp.StagedUploadsCreate.StagedTargets) == 0 {
+		return "", errors.New("no staged upload target returned")
+	}
+	target := resp.StagedUploadsCreate.StagedTargets[0]
+
+	// the file must be the last field of the form
+	body := new(bytes.Buffer)
+	form := multipart.NewWriter(body)
+	var path string
+	for _, param := range target.Parameters {
+		if param.Name == "key" {
+			path = param.Value
+		}
+		if err := form.WriteField(param.Name, param.Value); err != nil {
+			return "", err
+		}
+	}
+	file, err := form.CreateFormFile("file", "bulk_op_vars")
+	if err != nil {
+		return "", err
+	}
+	if _, err := io.Copy(file, variables); err != nil {
+		return "", err
+	}
+	if err := form.Close(); err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target.Url, body)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", form.FormDataContentType())
+
+	uploadResp, err := s.transferClient().Do(req)
+	if err != nil {
+		return "", fmt.Errorf("uploading bulk mutation variables: %w", err)
+	}
+	defer uploadResp.Body.Close()
+	if uploadResp.StatusCode < http.StatusOK || uploadResp.StatusCode >= http.StatusMultipleChoices {
+		return "", fmt.Errorf("uploading bulk mutation variables: unexpected status %s", uploadResp.Status)
+	}
+
+	return path, nil
+}
+
+// Current returns the last bulk operation of the given type started by the
+// app, nil if there is none.
+func (s *BulkOperationServiceOp) Current(ctx context.Context, operationType BulkOperationType) (*BulkOperation, error) {
+	if operationType == "" {
+		operationType = BulkOperationTypeQuery
+	}
+
+	q := `query currentBulkOperation($type: BulkOperationType!) {
+		currentBulkOperation(type: $type) { ` + bulkOperationFields + ` }
+	}`
+
+	var resp struct {
+		CurrentBulkOperation *BulkOperation `json:"currentBulkOperation"`
+	}
+	err := s.client.GraphQL.Query(ctx, q, map[string]interface{}{"type": operationType}, &resp)
+	return resp.CurrentBulkOperation, err
+}
+
+// Get returns the bulk operation with the given id, e.g. the
+// AdminGraphqlApiId of a bulk_operations/finish webhook.
+func (s *BulkOperationServiceOp) Get(ctx context.Context, id string) (*BulkOperation, error) {
+	q := `query bulkOperation($id: ID!) {
+		node(id: $id) { ... on BulkOperation { ` + bulkOperationFields + ` } }
+	}`
+
+	var resp struct {
+		Node *BulkOperation `json:"node"`
+	}
+	err := s.client.GraphQL.Query(ctx, q, map[string]interface{}{"id": id}, &resp)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Node == nil {
+		return nil, fmt.Errorf("bulk operation %s not found", id)
+	}
+	return resp.Node, nil
+}
+
+// Cancel requests the cancellation of a running bulk operation.
+func (s *BulkOperationServiceOp) Cancel(ctx context.Context, id string) (*BulkOperation, error) {
+	q := `mutation bulkOperationCancel($id: ID!) {
+		bulkOperationCancel(id: $id) {
+			bulkOperation { ` + bulkOperationFields + ` }
+			userErrors { field message }
+		}
+	}`
+
+	var resp struct {
+		Payload bulkOperationPayload `json:"bulkOperationCancel"`
+	}
+	err := s.client.GraphQL.Mutate(ctx, q, map[string]interface{}{"id": id}, &resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Payload.BulkOperation, nil
+}
+
+// Wait polls the current bulk operation, with an exponential backoff, until
+// operation is done and returns it. The error wraps ErrBulkOperationFailed
+// if the operation failed, was canceled or expired; its partial result may
+// still be available. Apps subscribed to the bulk_operations/finish webhook
+// can call Get once notified instead.
+func (s *BulkOperationServiceOp) Wait(ctx context.Context, operation *BulkOperation) (*BulkOperation, error) {
+	interval := bulkOperationPollInterval
+	for !operation.Status.Done() {
+		if err := sleepContext(ctx, interval); err != nil {
+			return operation, err
+		}
+		interval = min(interval*2, bulkOperationMaxPollInterval)
+
+		current, err := s.Current(ctx, operation.Type)
+		if err != nil {
+			return operation, err
+		}
+		if current == nil || current.Id != operation.Id {
+			// another operation started since
+			current, err = s.Get(ctx, operation.Id)
+			if err != nil {
+				return operation, err
+			}
+		}
+		operation = current
+	}
+
+	if operation.Status != BulkOperationStatusCompleted {
+		return operation, fmt.Errorf("%w: %s %s %s", ErrBulkOperationFailed, operation.Id, operation.Status, operation.ErrorCode)
+	}
+	return operation, nil
+}
+
+// Download returns the JSONL result of a completed operation, or its partial
+// result if it failed, see NewBulkDecoder to decode it. The result is empty
+// if the operation returned no object. The caller must close it.
+func (s *BulkOperationServiceOp) Download(ctx context.Context, operation *BulkOperation) (io.ReadCloser, error) {
+	url := operation.Url
+	if url == "" {
+		url = operation.PartialDataUrl
+	}
+	if url == "" {
+		return io.NopCloser(bytes.NewReader(nil)), nil
+	}
+
+	// the result is hosted outside of Shopify, the request is not
+	// authenticated
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.transferClient().Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("downloading bulk operation result: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("downloading bulk operation result: unexpected status %s", resp.Status)
+	}
+	return resp.Body, nil
+}
+
+// transferClient returns the http client of the client without its timeout,
+// which would cover reading a whole result or uploading all the variables.
+// Transfers are bounded by their context instead.
+func (s *BulkOperationServiceOp) transferClient() *http.Client {
+	c := *s.client.Client
+	c.Timeout = 0
+	return &c
+}
@@ -0,0 +1,259 @@
+package goshopify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newBulkTestServer returns a client of a stand-in for Shopify and the
+// storage of bulk operation results, answering GraphQL requests with graphql
+func newBulkTestServer(t *testing.T, graphql func(query string, vars map[string]interface{}) string) (*Client, *httptest.Server, *http.ServeMux) {
+	mux := http.NewServeMux()
+	mux.HandleFunc(fmt.Sprintf("/admin/api/%s/graphql.json", testApiVersion), func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Query     string                 `json:"query"`
+			Variables map[string]interface{} `json:"variables"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("invalid graphql request: %v", err)
+		}
+		fmt.Fprint(w, graphql(body.Query, body.Variables))
+	})
+
+	server := httptest.NewTLSServer(mux)
+	t.Cleanup(server.Close)
+
+	c := MustNewClient(App{}, "fooshop", "abcd", WithVersion(testApiVersion), WithHTTPClient(server.Client()))
+	c.baseURL, _ = url.Parse(server.URL)
+	return c, server, mux
+}
+
+func fastBulkOperationPolling(t *testing.T) {
+	interval, maxInterval := bulkOperationPollInterval, bulkOperationMaxPollInterval
+	bulkOperationPollInterval, bulkOperationMaxPollInterval = time.Millisecond, 2*time.Millisecond
+	t.Cleanup(func() {
+		bulkOperationPollInterval, bulkOperationMaxPollInterval = interval, maxInterval
+	})
+}
+
+type bulkTestVariant struct {
+	Id    string `json:"id"`
+	Title string `json:"title"`
+}
+
+type bulkTestProduct struct {
+	Id       string            `json:"id"`
+	Title    string            `json:"title"`
+	Variants []bulkTestVariant `json:"-" bulk:"ProductVariant"`
+}
+
+func TestBulkOperationRunQuery(t *testing.T) {
+	fastBulkOperationPolling(t)
+
+	var server *httptest.Server
+	polls := 0
+	c, server, mux := newBulkTestServer(t, func(query string, vars map[string]interface{}) string {
+		switch {
+		case strings.Contains(query, "bulkOperationRunQuery"):
+			if vars["query"] != "{ products { edges { node { id title variants { edges { node { id title } } } } } } }" {
+				t.Errorf("bulkOperationRunQuery called with %v", vars)
+			}
+			return `{"data":{"bulkOperationRunQuery":{"bulkOperation":{"id":"gid://shopify/BulkOperation/1","status":"CREATED","type":"QUERY","objectCount":"0"},"userErrors":[]}}}`
+		case strings.Contains(query, "currentBulkOperation"):
+			if vars["type"] != "QUERY" {
+				t.Errorf("currentBulkOperation called with %v", vars)
+			}
+			polls++
+			if polls < 3 {
+				return `{"data":{"currentBulkOperation":{"id":"gid://shopify/BulkOperation/1","status":"RUNNING","type":"QUERY","objectCount":"2"}}}`
+			}
+			return fmt.Sprintf(`{"data":{"currentBulkOperation":{"id":"gid://shopify/BulkOperation/1","status":"COMPLETED","type":"QUERY","objectCount":"5","fileSize":"312","url":"%s/result.jsonl"}}}`, server.URL)
+		}
+		t.Errorf("unexpected query %s", query)
+		return `{}`
+	})
+	mux.HandleFunc("/result.jsonl", func(w http.ResponseWriter, r *http.Request) {
+		if token := r.Header.Get("X-Shopify-Access-Token"); token != "" {
+			t.Errorf("result downloaded with the access token")
+		}
+		fmt.Fprint(w, `{"id":"gid://shopify/Product/1","title":"Shirt"}
+{"id":"gid://shopify/ProductVariant/11","title":"S","__parentId":"gid://shopify/Product/1"}
+{"id":"gid://shopify/ProductVariant/12","title":"M","__parentId":"gid://shopify/Product/1"}
+{"id":"gid://shopify/Product/2","title":"Hat"}
+{"id":"gid://shopify/ProductVariant/21","title":"One size","__parentId":"gid://shopify/Product/2"}
+`)
+	})
+
+	ctx := context.Background()
+	op, err := c.BulkOperation.RunQuery(ctx, "{ products { edges { node { id title variants { edges { node { id title } } } } } } }")
+	if err != nil {
+		t.Fatalf("BulkOperation.RunQuery returned error: %v", err)
+	}
+	if op.Id != "gid://shopify/BulkOperation/1" || op.Status != BulkOperationStatusCreated {
+		t.Errorf("BulkOperation.RunQuery returned %+v", op)
+	}
+
+	op, err = c.BulkOperation.Wait(ctx, op)
+	if err != nil {
+		t.Fatalf("BulkOperation.Wait returned error: %v", err)
+	}
+	if op.Status != BulkOperationStatusCompleted || op.ObjectCount != 5 || op.FileSize != 312 || polls != 3 {
+		t.Errorf("BulkOperation.Wait returned %+v after %d polls", op, polls)
+	}
+
+	result, err := c.BulkOperation.Download(ctx, op)
+	if err != nil {
+		t.Fatalf("BulkOperation.Download returned error: %v", err)
+	}
+	defer result.Close()
+
+	var products []bulkTestProduct
+	err = DecodeBulkResult(result, func(p *bulkTestProduct) error {
+		products = append(products, *p)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("DecodeBulkResult returned error: %v", err)
+	}
+
+	if len(products) != 2 || products[0].Title != "Shirt" || len(products[0].Variants) != 2 ||
+		products[0].Variants[1].Title != "M" || len(products[1].Variants) != 1 {
+		t.Errorf("DecodeBulkResult returned %+v", products)
+	}
+}
+
+func TestBulkOperationWaitFailed(t *testing.T) {
+	fastBulkOperationPolling(t)
+
+	c, _, _ := newBulkTestServer(t, func(query string, vars map[string]interface{}) string {
+		switch {
+		case strings.Contains(query, "currentBulkOperation"):
+			// a later operation replaced it
+			return `{"data":{"currentBulkOperation":{"id":"gid://shopify/BulkOperation/2","status":"RUNNING","type":"QUERY"}}}`
+		case strings.Contains(query, "node(id: $id)"):
+			if vars["id"] != "gid://shopify/BulkOperation/1" {
+				t.Errorf("node called with %v", vars)
+			}
+			return `{"data":{"node":{"id":"gid://shopify/BulkOperation/1","status":"FAILED","type":"QUERY","errorCode":"TIMEOUT","partialDataUrl":"https://storage.example.com/partial.jsonl"}}}`
+		}
+		t.Errorf("unexpected query %s", query)
+		return `{}`
+	})
+
+	op, err := c.BulkOperation.Wait(context.Background(), &BulkOperation{Id: "gid://shopify/BulkOperation/1", Status: BulkOperationStatusRunning, Type: BulkOperationTypeQuery})
+	if !errors.Is(err, ErrBulkOperationFailed) {
+		t.Errorf("BulkOperation.Wait returned error %v, expected ErrBulkOperationFailed", err)
+	}
+	if op.ErrorCode != "TIMEOUT" || op.PartialDataUrl == "" {
+		t.Errorf("BulkOperation.Wait returned %+v, expected the failed operation", op)
+	}
+}
+
+func TestBulkOperationRunQueryUserErrors(t *testing.T) {
+	c, _, _ := newBulkTestServer(t, func(query string, vars map[string]interface{}) string {
+		return `{"data":{"bulkOperationRunQuery":{"bulkOperation":null,"userErrors":[{"field":["query"],"message":"A bulk query operation for this app and shop is already in progress"}]}}}`
+	})
+
+	op, err := c.BulkOperation.RunQuery(context.Background(), "{ products { edges { node { id } } } }")
+	var userErrs UserErrors
+	if !errors.As(err, &userErrs) || !strings.Contains(err.Error(), "already in progress") || op != nil {
+		t.Errorf("BulkOperation.RunQuery returned %v, %v, expected the user error", op, err)
+	}
+}
+
+func TestBulkOperationRunMutation(t *testing.T) {
+	var server *httptest.Server
+	c, server, mux := newBulkTestServer(t, func(query string, vars map[string]interface{}) string {
+		switch {
+		case strings.Contains(query, "stagedUploadsCreate"):
+			return fmt.Sprintf(`{"data":{"stagedUploadsCreate":{"stagedTargets":[{"url":"%s/upload","resourceUrl":null,"parameters":[{"name":"key","value":"tmp/1/bulk/vars"},{"name":"policy","value":"p0l1cy"}]}],"userErrors":[]}}}`, server.URL)
+		case strings.Contains(query, "bulkOperationRunMutation"):
+			if vars["stagedUploadPath"] != "tmp/1/bulk/vars" || !strings.Contains(vars["mutation"].(string), "productCreate") {
+				t.Errorf("bulkOperationRunMutation called with %v", vars)
+			}
+			return `{"data":{"bulkOperationRunMutation":{"bulkOperation":{"id":"gid://shopify/BulkOperation/3","status":"CREATED","type":"MUTATION"},"userErrors":[]}}}`
+		}
+		t.Errorf("unexpected query %s", query)
+		return `{}`
+	})
+
+	variables := "{\"input\":{\"title\":\"Shirt\"}}\n{\"input\":{\"title\":\"Hat\"}}\n"
+	uploaded := false
+	mux.HandleFunc("/upload", func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			t.Fatalf("invalid upload: %v", err)
+		}
+		if r.FormValue("key") != "tmp/1/bulk/vars" || r.FormValue("policy") != "p0l1cy" {
+			t.Errorf("uploaded with fields %v", r.MultipartForm.Value)
+		}
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			t.Fatalf("upload without file: %v", err)
+		}
+		data, _ := io.ReadAll(file)
+		if string(data) != variables {
+			t.Errorf("uploaded %q, expected %q", data, variables)
+		}
+		uploaded = true
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	mutation := `mutation call($input: ProductInput!) { productCreate(input: $input) { product { id } userErrors { field message } } }`
+	op, err := c.BulkOperation.RunMutation(context.Background(), mutation, strings.NewReader(variables))
+	if err != nil {
+		t.Fatalf("BulkOperation.RunMutation returned error: %v", err)
+	}
+	if !uploaded || op.Id != "gid://shopify/BulkOperation/3" || op.Type != BulkOperationTypeMutation {
+		t.Errorf("BulkOperation.RunMutation returned %+v", op)
+	}
+}
+
+func TestBulkOperationDownloadEmpty(t *testing.T) {
+	c, _, _ := newBulkTestServer(t, nil)
+
+	result, err := c.BulkOperation.Download(context.Background(), &BulkOperation{Status: BulkOperationStatusCompleted})
+	if err != nil {
+		t.Fatalf("BulkOperation.Download returned error: %v", err)
+	}
+	defer result.Close()
+	if data, _ := io.ReadAll(result); len(data) != 0 {
+		t.Errorf("BulkOperation.Download returned %q, expected an empty result", data)
+	}
+}
+
+func TestBulkOperationDownloadSlow(t *testing.T) {
+	c, server, mux := newBulkTestServer(t, nil)
+	c.Client.Timeout = 50 * time.Millisecond
+
+	// the result streams for longer than the timeout of the client
+	mux.HandleFunc("/result.jsonl", func(w http.ResponseWriter, r *http.Request) {
+		for i := 0; i < 4; i++ {
+			fmt.Fprintf(w, "{\"id\":\"gid://shopify/Product/%d\"}\n", i)
+			w.(http.Flusher).Flush()
+			time.Sleep(25 * time.Millisecond)
+		}
+	})
+
+	result, err := c.BulkOperation.Download(context.Background(), &BulkOperation{Url: server.URL + "/result.jsonl"})
+	if err != nil {
+		t.Fatalf("BulkOperation.Download returned error: %v", err)
+	}
+	defer result.Close()
+
+	data, err := io.ReadAll(result)
+	if err != nil {
+		t.Fatalf("reading the result returned error: %v", err)
+	}
+	if lines := strings.Count(string(data), "\n"); lines != 4 {
+		t.Errorf("BulkOperation.Download returned %d lines, expected 4", lines)
+	}
+}
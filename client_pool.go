@@ -0,0 +1,201 @@
+package goshopify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// defaultPoolIdleTimeout is how long a ClientPool keeps unused clients
+const defaultPoolIdleTimeout = 30 * time.Minute
+
+// ErrShopNotInstalled is returned by ClientPool.Get when the TokenStore has no
+// token for the shop.
+var ErrShopNotInstalled = errors.New("shopify app not installed on shop")
+
+// ClientPool manages the clients of an app installed on many shops. Clients
+// are created on first use with the token of the shop in the TokenStore, and
+// share a single http.Client, hence its connection pool. Each shop gets its
+// own rate limiter, as Shopify limits requests per shop.
+//
+//	pool := goshopify.NewClientPool(app, store, goshopify.WithVersion("2024-04"))
+//	client, err := pool.Get(ctx, "fooshop.myshopify.com")
+//
+// Clients unused for IdleTimeout are evicted, and recreated from the
+// TokenStore if needed again. It is safe for concurrent use.
+type ClientPool struct {
+	app        App
+	store      TokenStore
+	opts       []Option
+	httpClient *http.Client
+
+	mu        sync.Mutex
+	clients   map[string]*pooledClient
+	lastSweep time.Time
+
+	// generations counts the invalidations of each shop, so a client created
+	// from a token read before an invalidation is not pooled
+	generations map[string]uint64
+
+	// IdleTimeout is how long an unused client is kept, defaults to 30
+	// minutes if not positive.
+	IdleTimeout time.Duration
+
+	// RateLimitPlan sizes the rate limiter of each shop, defaults to
+	// RateLimitPlanStandard. The limiters adjust to the actual plan of the
+	// shop from the responses.
+	RateLimitPlan RateLimitPlan
+
+	// Internal testing use only.
+	now func() time.Time
+}
+
+type pooledClient struct {
+	client   *Client
+	lastUsed time.Time
+}
+
+// NewClientPool returns a ClientPool creating clients of app with the tokens
+// in store. The options are applied to every client, after those set by the
+// pool, and may replace the shared http.Client with WithHTTPClient.
+func NewClientPool(app App, store TokenStore, opts ...Option) *ClientPool {
+	return &ClientPool{
+		app:   app,
+		store: store,
+		opts:  opts,
+		httpClient: &http.Client{
+			Timeout: time.Second * defaultHttpTimeout,
+		},
+		clients:       map[string]*pooledClient{},
+		generations:   map[string]uint64{},
+		IdleTimeout:   defaultPoolIdleTimeout,
+		RateLimitPlan: RateLimitPlanStandard,
+		now:           time.Now,
+	}
+}
+
+// Get returns the client of shop, creating it from the token in the
+// TokenStore if needed. It returns ErrShopNotInstalled if there is no token.
+func (p *ClientPool) Get(ctx context.Context, shop string) (*Client, error) {
+	shop = ShopFullName(shop)
+
+	for {
+		c, generation := p.lookup(shop)
+		if c != nil {
+			return c, nil
+		}
+
+		c, err := p.create(ctx, shop)
+		if err != nil {
+			return nil, err
+		}
+
+		p.mu.Lock()
+		// the shop was invalidated since its token was read, e.g. it
+		// uninstalled the app, the token is read again
+		if p.generations[shop] != generation {
+			p.mu.Unlock()
+			continue
+		}
+
+		// another caller may have created the client meanwhile
+		if entry, ok := p.clients[shop]; ok {
+			entry.lastUsed = p.now()
+			c = entry.client
+		} else {
+			p.clients[shop] = &pooledClient{client: c, lastUsed: p.now()}
+		}
+		p.mu.Unlock()
+		return c, nil
+	}
+}
+
+// create returns a new client of shop with its token in the TokenStore
+func (p *ClientPool) create(ctx context.Context, shop string) (*Client, error) {
+	token, err := p.store.GetToken(ctx, shop)
+	if err != nil {
+		return nil, fmt.Errorf("getting token of %s: %w", shop, err)
+	}
+	if token == nil {
+		return nil, fmt.Errorf("%w: %s", ErrShopNotInstalled, shop)
+	}
+
+	opts := append([]Option{
+		WithHTTPClient(p.httpClient),
+		WithRateLimiter(NewLeakyBucket(p.RateLimitPlan)),
+		WithTokenRefresh(token, p.store),
+	}, p.opts...)
+	return NewClient(p.app, shop, token.AccessToken, opts...)
+}
+
+// lookup returns the pooled client of shop, evicting idle clients first, and
+// the generation of shop when it has no client
+func (p *ClientPool) lookup(shop string) (*Client, uint64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	idleTimeout := p.IdleTimeout
+	if idleTimeout <= 0 {
+		idleTimeout = defaultPoolIdleTimeout
+	}
+
+	now := p.now()
+	if now.Sub(p.lastSweep) >= idleTimeout/2 {
+		for s, entry := range p.clients {
+			if now.Sub(entry.lastUsed) >= idleTimeout {
+				delete(p.clients, s)
+			}
+		}
+		p.lastSweep = now
+	}
+
+	entry, ok := p.clients[shop]
+	if !ok {
+		return nil, p.generations[shop]
+	}
+	entry.lastUsed = now
+	return entry.client, 0
+}
+
+// Invalidate removes the client of shop from the pool, e.g. once its token
+// was replaced in the TokenStore. The next Get creates a new client.
+func (p *ClientPool) Invalidate(shop string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	shop = ShopFullName(shop)
+	delete(p.clients, shop)
+	p.generations[shop]++
+}
+
+// Len returns the number of clients in the pool.
+func (p *ClientPool) Len() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return len(p.clients)
+}
+
+// HandleUninstalled deletes the token of the shop of an app/uninstalled
+// webhook from the TokenStore, as Shopify revoked it, and invalidates its
+// client.
+//
+//	handler.OnAppUninstalled(pool.HandleUninstalled)
+func (p *ClientPool) HandleUninstalled(ctx context.Context, meta WebhookMeta, shop *Shop) error {
+	domain := meta.ShopDomain
+	if domain == "" && shop != nil {
+		domain = shop.MyshopifyDomain
+	}
+	if domain == "" {
+		return errors.New("app/uninstalled webhook without shop domain")
+	}
+
+	// the token is deleted first, so it can't be read again to create a
+	// client once the shop is invalidated
+	err := p.store.DeleteToken(ctx, ShopFullName(domain))
+	p.Invalidate(domain)
+	return err
+}
@@ -0,0 +1,188 @@
+package goshopify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/jarcoal/httpmock"
+)
+
+func newTestClientPool() (*ClientPool, *MemoryTokenStore) {
+	store := NewMemoryTokenStore()
+	store.SaveToken(context.Background(), &OAuthToken{Shop: "fooshop.myshopify.com", AccessToken: "footoken"})
+	store.SaveToken(context.Background(), &OAuthToken{Shop: "barshop.myshopify.com", AccessToken: "bartoken"})
+
+	pool := NewClientPool(app, store, WithVersion(testApiVersion))
+	httpmock.ActivateNonDefault(pool.httpClient)
+	return pool, store
+}
+
+func TestClientPoolGet(t *testing.T) {
+	setup()
+	defer teardown()
+
+	pool, _ := newTestClientPool()
+
+	httpmock.RegisterResponder("GET", fmt.Sprintf("https://fooshop.myshopify.com/%s/shop.json", client.pathPrefix),
+		func(req *http.Request) (*http.Response, error) {
+			if token := req.Header.Get("X-Shopify-Access-Token"); token != "footoken" {
+				t.Errorf("request sent with token %q, expected footoken", token)
+			}
+			return httpmock.NewStringResponse(200, `{"shop":{"id":1}}`), nil
+		})
+
+	foo, err := pool.Get(context.Background(), "fooshop")
+	if err != nil {
+		t.Fatalf("ClientPool.Get returned error: %v", err)
+	}
+	if _, err := foo.Shop.Get(context.Background(), nil); err != nil {
+		t.Errorf("Shop.Get returned error: %v", err)
+	}
+
+	again, _ := pool.Get(context.Background(), "fooshop.myshopify.com")
+	if again != foo {
+		t.Errorf("ClientPool.Get created another client for fooshop")
+	}
+
+	bar, err := pool.Get(context.Background(), "barshop.myshopify.com")
+	if err != nil {
+		t.Fatalf("ClientPool.Get returned error: %v", err)
+	}
+	if bar == foo || bar.Client != foo.Client {
+		t.Errorf("ClientPool.Get returned clients not sharing the http client")
+	}
+	if bar.rateLimiter == nil || bar.rateLimiter == foo.rateLimiter {
+		t.Errorf("ClientPool.Get returned clients sharing the rate limiter")
+	}
+	if pool.Len() != 2 {
+		t.Errorf("ClientPool.Len() = %d, expected 2", pool.Len())
+	}
+}
+
+func TestClientPoolGetNotInstalled(t *testing.T) {
+	setup()
+	defer teardown()
+
+	pool, _ := newTestClientPool()
+
+	if _, err := pool.Get(context.Background(), "bazshop"); !errors.Is(err, ErrShopNotInstalled) {
+		t.Errorf("ClientPool.Get returned error %v, expected ErrShopNotInstalled", err)
+	}
+	if pool.Len() != 0 {
+		t.Errorf("ClientPool.Len() = %d, expected 0", pool.Len())
+	}
+}
+
+func TestClientPoolEvictsIdle(t *testing.T) {
+	setup()
+	defer teardown()
+
+	pool, _ := newTestClientPool()
+	now := time.Now()
+	pool.now = func() time.Time { return now }
+
+	foo, _ := pool.Get(context.Background(), "fooshop")
+	now = now.Add(20 * time.Minute)
+	pool.Get(context.Background(), "barshop")
+	now = now.Add(15 * time.Minute)
+
+	if again, _ := pool.Get(context.Background(), "barshop"); again == nil || pool.Len() != 1 {
+		t.Errorf("ClientPool.Len() = %d, expected the idle fooshop client to be evicted", pool.Len())
+	}
+	if again, _ := pool.Get(context.Background(), "fooshop"); again == foo {
+		t.Errorf("ClientPool.Get returned the evicted client")
+	}
+}
+
+func TestClientPoolZeroIdleTimeout(t *testing.T) {
+	setup()
+	defer teardown()
+
+	pool, _ := newTestClientPool()
+	pool.IdleTimeout = 0
+
+	foo, _ := pool.Get(context.Background(), "fooshop")
+	if again, _ := pool.Get(context.Background(), "fooshop"); again != foo {
+		t.Errorf("ClientPool.Get with a zero IdleTimeout created another client")
+	}
+}
+
+func TestClientPoolHandleUninstalled(t *testing.T) {
+	setup()
+	defer teardown()
+
+	pool, store := newTestClientPool()
+	foo, _ := pool.Get(context.Background(), "fooshop")
+
+	meta := WebhookMeta{Topic: WebhookTopicAppUninstalled, ShopDomain: "fooshop.myshopify.com"}
+	if err := pool.HandleUninstalled(context.Background(), meta, &Shop{}); err != nil {
+		t.Fatalf("ClientPool.HandleUninstalled returned error: %v", err)
+	}
+
+	if pool.Len() != 0 {
+		t.Errorf("ClientPool.Len() = %d, expected the client to be invalidated", pool.Len())
+	}
+	if token, _ := store.GetToken(context.Background(), "fooshop.myshopify.com"); token != nil {
+		t.Errorf("stored token %+v after uninstall, expected none", token)
+	}
+	if c, err := pool.Get(context.Background(), "fooshop"); c == foo || !errors.Is(err, ErrShopNotInstalled) {
+		t.Errorf("ClientPool.Get returned %v, %v after uninstall, expected ErrShopNotInstalled", c, err)
+	}
+}
+
+// blockingTokenStore lets a test run between the read of a token and its use
+type blockingTokenStore struct {
+	*MemoryTokenStore
+	read    chan struct{}
+	release chan struct{}
+}
+
+func (s *blockingTokenStore) GetToken(ctx context.Context, shop string) (*OAuthToken, error) {
+	token, err := s.MemoryTokenStore.GetToken(ctx, shop)
+	if s.read != nil {
+		s.read <- struct{}{}
+		<-s.release
+	}
+	return token, err
+}
+
+func TestClientPoolGetDuringUninstall(t *testing.T) {
+	setup()
+	defer teardown()
+
+	_, memory := newTestClientPool()
+	store := &blockingTokenStore{MemoryTokenStore: memory, read: make(chan struct{}), release: make(chan struct{})}
+	pool := NewClientPool(app, store, WithVersion(testApiVersion))
+
+	type result struct {
+		client *Client
+		err    error
+	}
+	got := make(chan result)
+	go func() {
+		c, err := pool.Get(context.Background(), "fooshop")
+		got <- result{c, err}
+	}()
+
+	// the token is read, then the shop uninstalls the app before the client
+	// is pooled
+	<-store.read
+	meta := WebhookMeta{Topic: WebhookTopicAppUninstalled, ShopDomain: "fooshop.myshopify.com"}
+	if err := pool.HandleUninstalled(context.Background(), meta, nil); err != nil {
+		t.Fatalf("ClientPool.HandleUninstalled returned error: %v", err)
+	}
+	store.read = nil
+	close(store.release)
+
+	r := <-got
+	if !errors.Is(r.err, ErrShopNotInstalled) {
+		t.Errorf("ClientPool.Get returned %v, %v, expected ErrShopNotInstalled", r.client, r.err)
+	}
+	if pool.Len() != 0 {
+		t.Errorf("ClientPool.Len() = %d, expected the uninstalled shop not to be pooled", pool.Len())
+	}
+}
@@ -15,6 +15,8 @@ type CollectionService interface {
 	Get(ctx context.Context, collectionId uint64, options interface{}) (*Collection, error)
 	ListProducts(ctx context.Context, collectionId uint64, options interface{}) ([]Product, error)
 	ListProductsWithPagination(ctx context.Context, collectionId uint64, options interface{}) ([]Product, *Pagination, error)
+	ListProductsPages(collectionId uint64, options interface{}) *PageIterator[Product]
+	ListProductsIter(collectionId uint64, options interface{}) *ListIterator[Product]
 }
 
 // CollectionServiceOp handles communication with the collection related methods of
@@ -71,3 +73,17 @@ func (s *CollectionServiceOp) ListProductsWithPagination(ctx context.Context, co
 
 	return resource.Products, pagination, nil
 }
+
+// ListProductsPages returns an iterator lazily walking the pages of products for a collection
+func (s *CollectionServiceOp) ListProductsPages(collectionId uint64, options interface{}) *PageIterator[Product] {
+	return newPageIterator(func(ctx context.Context, options interface{}) ([]Product, *Pagination, error) {
+		return s.ListProductsWithPagination(ctx, collectionId, options)
+	}, options)
+}
+
+// ListProductsIter returns an iterator lazily walking the products for a collection one at a time
+func (s *CollectionServiceOp) ListProductsIter(collectionId uint64, options interface{}) *ListIterator[Product] {
+	return newListIterator(func(ctx context.Context, options interface{}) ([]Product, *Pagination, error) {
+		return s.ListProductsWithPagination(ctx, collectionId, options)
+	}, options)
+}
@@ -0,0 +1,170 @@
+package goshopify
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/jarcoal/httpmock"
+)
+
+// The tests in this file share a single client between many goroutines and
+// are meant to be run with the race detector, i.e. go test -race
+
+const concurrentWorkers = 50
+
+func newConcurrentTestClient(opts ...Option) *Client {
+	c := MustNewClient(app, "fooshop", "abcd", opts...)
+	httpmock.ActivateNonDefault(c.Client)
+
+	httpmock.RegisterResponder("GET", fmt.Sprintf("https://fooshop.myshopify.com/%s/products/1.json", c.pathPrefix),
+		func(req *http.Request) (*http.Response, error) {
+			resp := httpmock.NewStringResponse(http.StatusOK, `{"product": {"id": 1}}`)
+			resp.Header.Set(callLimitHeader, "1/40")
+			resp.Header.Set("X-Shopify-API-Version", testApiVersion)
+			return resp, nil
+		})
+	httpmock.RegisterResponder("POST", fmt.Sprintf("https://fooshop.myshopify.com/%s/graphql.json", c.pathPrefix),
+		httpmock.NewStringResponder(http.StatusOK, `{
+			"data": {"foo": "bar"},
+			"extensions": {
+				"cost": {
+					"requestedQueryCost": 1,
+					"actualQueryCost": 1,
+					"throttleStatus": {"maximumAvailable": 1000, "currentlyAvailable": 999, "restoreRate": 50}
+				}
+			}
+		}`))
+
+	return c
+}
+
+func hammer(t *testing.T, fn func() error) {
+	var wg sync.WaitGroup
+	errs := make(chan error, concurrentWorkers)
+
+	for i := 0; i < concurrentWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := fn(); err != nil {
+				errs <- err
+			}
+		}()
+	}
+
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Error(err)
+	}
+}
+
+func TestClientConcurrentRequests(t *testing.T) {
+	c := newConcurrentTestClient()
+	defer httpmock.DeactivateAndReset()
+
+	hammer(t, func() error {
+		_, err := c.Product.Get(context.Background(), 1, nil)
+		if err != nil {
+			return err
+		}
+
+		err = c.GraphQL.Query(context.Background(), "query", nil, nil)
+		if err != nil {
+			return err
+		}
+
+		rateLimits := c.GetRateLimits()
+		if rateLimits.GraphQLCost != nil {
+			rateLimits.GraphQLCost.RequestedQueryCost++
+		}
+
+		return nil
+	})
+
+	rateLimits := c.GetRateLimits()
+	if rateLimits.BucketSize != 40 {
+		t.Errorf("GetRateLimits().BucketSize = %d, expected 40", rateLimits.BucketSize)
+	}
+	if rateLimits.GraphQLCost == nil || rateLimits.GraphQLCost.RequestedQueryCost != 1 {
+		t.Errorf("GetRateLimits().GraphQLCost = %#v, expected a requested query cost of 1", rateLimits.GraphQLCost)
+	}
+	if c.apiVersion != testApiVersion {
+		t.Errorf("apiVersion = %s, expected %s", c.apiVersion, testApiVersion)
+	}
+}
+
+func TestClientConcurrentRequestsWithLimiters(t *testing.T) {
+	c := newConcurrentTestClient(
+		WithVersion(testApiVersion),
+		WithRetry(maxRetries),
+		WithRateLimiter(NewLeakyBucket(RateLimitPlanPlus)),
+		WithGraphQLThrottler(NewGraphQLCostThrottler()),
+	)
+	defer httpmock.DeactivateAndReset()
+
+	hammer(t, func() error {
+		_, err := c.Product.Get(context.Background(), 1, nil)
+		if err != nil {
+			return err
+		}
+
+		return c.GraphQL.Query(context.Background(), "query", nil, nil)
+	})
+}
+
+func TestClientConcurrentAttempts(t *testing.T) {
+	c := MustNewClient(app, "fooshop", "abcd", WithVersion(testApiVersion), WithRetry(maxRetries))
+	httpmock.ActivateNonDefault(c.Client)
+	defer httpmock.DeactivateAndReset()
+
+	// odd products fail once before succeeding, even products succeed
+	var mu sync.Mutex
+	failed := map[string]bool{}
+	httpmock.RegisterRegexpResponder("GET", regexp.MustCompile(`/products/\d+\.json$`),
+		func(req *http.Request) (*http.Response, error) {
+			mu.Lock()
+			defer mu.Unlock()
+
+			id, _ := strconv.Atoi(strings.TrimSuffix(path.Base(req.URL.Path), ".json"))
+			if id%2 == 1 && !failed[req.URL.Path] {
+				failed[req.URL.Path] = true
+				return httpmock.NewStringResponse(http.StatusServiceUnavailable, ""), nil
+			}
+			return httpmock.NewStringResponse(http.StatusOK, `{"product": {}}`), nil
+		})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(id int) {
+			defer wg.Done()
+
+			req, err := c.NewRequest(context.Background(), "GET", fmt.Sprintf("%s/products/%d.json", c.pathPrefix, id), nil, nil)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+
+			response, err := c.do(req, nil)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+
+			expected := 1 + id%2
+			if response.Attempts != expected {
+				t.Errorf("product %d took %d attempts, expected %d", id, response.Attempts, expected)
+			}
+		}(i)
+	}
+	wg.Wait()
+}
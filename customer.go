@@ -20,6 +20,8 @@ type CustomerService interface {
 	List(context.Context, interface{}) ([]Customer, error)
 	ListAll(context.Context, interface{}) ([]Customer, error)
 	ListWithPagination(ctx context.Context, options interface{}) ([]Customer, *Pagination, error)
+	Pages(interface{}) *PageIterator[Customer]
+	Iter(interface{}) *ListIterator[Customer]
 	Count(context.Context, interface{}) (int, error)
 	Get(context.Context, uint64, interface{}) (*Customer, error)
 	Search(context.Context, interface{}) ([]Customer, error)
@@ -117,25 +119,17 @@ func (s *CustomerServiceOp) List(ctx context.Context, options interface{}) ([]Cu
 
 // ListAll Lists all customers, iterating over pages
 func (s *CustomerServiceOp) ListAll(ctx context.Context, options interface{}) ([]Customer, error) {
-	collector := []Customer{}
-
-	for {
-		entities, pagination, err := s.ListWithPagination(ctx, options)
-
-		if err != nil {
-			return collector, err
-		}
-
-		collector = append(collector, entities...)
-
-		if pagination.NextPageOptions == nil {
-			break
-		}
+	return listAll(ctx, s.ListWithPagination, options)
+}
 
-		options = pagination.NextPageOptions
-	}
+// Pages returns an iterator lazily walking the pages of customers
+func (s *CustomerServiceOp) Pages(options interface{}) *PageIterator[Customer] {
+	return newPageIterator(s.ListWithPagination, options)
+}
 
-	return collector, nil
+// Iter returns an iterator lazily walking customers one at a time
+func (s *CustomerServiceOp) Iter(options interface{}) *ListIterator[Customer] {
+	return newListIterator(s.ListWithPagination, options)
 }
 
 // ListWithPagination lists customers and return pagination to retrieve next/previous results.
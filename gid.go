@@ -0,0 +1,174 @@
+package goshopify
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const gidPrefix = "gid://shopify/"
+
+// Resource types of the global ids of the resources most often mixed between
+// the REST and GraphQL APIs.
+const (
+	GIDTypeBulkOperation    = "BulkOperation"
+	GIDTypeCollection       = "Collection"
+	GIDTypeCustomer         = "Customer"
+	GIDTypeDraftOrder       = "DraftOrder"
+	GIDTypeFulfillment      = "Fulfillment"
+	GIDTypeFulfillmentOrder = "FulfillmentOrder"
+	GIDTypeInventoryItem    = "InventoryItem"
+	GIDTypeLocation         = "Location"
+	GIDTypeMetafield        = "Metafield"
+	GIDTypeOrder            = "Order"
+	GIDTypeProduct          = "Product"
+	GIDTypeProductImage     = "ProductImage"
+	GIDTypeProductVariant   = "ProductVariant"
+)
+
+// ErrInvalidGID is wrapped by the errors returned when a global id can't be
+// parsed.
+var ErrInvalidGID = errors.New("invalid shopify global id")
+
+// GID is the global id of a resource in the GraphQL APIs, e.g.
+// gid://shopify/Product/123, the AdminGraphqlApiId of the REST resources.
+// The zero GID is encoded as null in JSON.
+// See https://shopify.dev/docs/api/usage/gids
+type GID struct {
+	// ResourceType is the GraphQL type of the resource, e.g. Product
+	ResourceType string
+
+	// Id is the id of the resource, the REST id of most resources
+	Id string
+
+	// Query holds the parameters following the id, e.g. key=abc for
+	// gid://shopify/Cart/c1?key=abc, without the leading "?"
+	Query string
+}
+
+// NewGID returns the global id of the resource of the given type with the
+// given REST id, e.g. NewGID(GIDTypeProduct, 123).
+func NewGID(resourceType string, id uint64) GID {
+	return GID{ResourceType: resourceType, Id: strconv.FormatUint(id, 10)}
+}
+
+// ParseGID parses a global id such as gid://shopify/Product/123.
+func ParseGID(s string) (GID, error) {
+	rest, ok := strings.CutPrefix(s, gidPrefix)
+	if !ok {
+		return GID{}, fmt.Errorf("%w: %q", ErrInvalidGID, s)
+	}
+
+	var g GID
+	rest, g.Query, _ = strings.Cut(rest, "?")
+	g.ResourceType, g.Id, _ = strings.Cut(rest, "/")
+	if err := g.Validate(); err != nil {
+		return GID{}, fmt.Errorf("%w: %q", ErrInvalidGID, s)
+	}
+	return g, nil
+}
+
+// ParseGIDOf parses a global id which must be of the given resource type,
+// e.g. ParseGIDOf(GIDTypeOrder, order.AdminGraphqlApiId).
+func ParseGIDOf(resourceType, s string) (GID, error) {
+	g, err := ParseGID(s)
+	if err != nil {
+		return GID{}, err
+	}
+	if !g.Is(resourceType) {
+		return GID{}, fmt.Errorf("%w: %q is not a %s", ErrInvalidGID, s, resourceType)
+	}
+	return g, nil
+}
+
+// Validate returns an error if the resource type or the id is missing or
+// contains a "/".
+func (g GID) Validate() error {
+	if g.ResourceType == "" || strings.Contains(g.ResourceType, "/") {
+		return fmt.Errorf("%w: invalid resource type %q", ErrInvalidGID, g.ResourceType)
+	}
+	if g.Id == "" || strings.Contains(g.Id, "/") {
+		return fmt.Errorf("%w: invalid id %q", ErrInvalidGID, g.Id)
+	}
+	return nil
+}
+
+// IsZero reports whether g is the zero GID.
+func (g GID) IsZero() bool {
+	return g == GID{}
+}
+
+// Is reports whether g is the global id of a resource of the given type.
+func (g GID) Is(resourceType string) bool {
+	return g.ResourceType == resourceType
+}
+
+// String formats g as gid://shopify/{ResourceType}/{Id}, followed by its
+// query if any. The zero GID is formatted as an empty string.
+func (g GID) String() string {
+	if g.IsZero() {
+		return ""
+	}
+	s := gidPrefix + g.ResourceType + "/" + g.Id
+	if g.Query != "" {
+		s += "?" + g.Query
+	}
+	return s
+}
+
+// Uint64 returns the id as the REST id of the resource.
+func (g GID) Uint64() (uint64, error) {
+	id, err := strconv.ParseUint(g.Id, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %s has no numeric id", ErrInvalidGID, g)
+	}
+	return id, nil
+}
+
+// MarshalJSON implements json.Marshaler
+func (g GID) MarshalJSON() ([]byte, error) {
+	if g.IsZero() {
+		return []byte("null"), nil
+	}
+	return json.Marshal(g.String())
+}
+
+// UnmarshalJSON implements json.Unmarshaler, accepting a global id, null or
+// an empty string.
+func (g *GID) UnmarshalJSON(data []byte) error {
+	var s *string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidGID, data)
+	}
+	if s == nil || *s == "" {
+		*g = GID{}
+		return nil
+	}
+
+	parsed, err := ParseGID(*s)
+	if err != nil {
+		return err
+	}
+	*g = parsed
+	return nil
+}
+
+// GIDToId returns the REST id of the global id s, which must be of the given
+// resource type, e.g. GIDToId(GIDTypeProduct, "gid://shopify/Product/123")
+// returns 123.
+func GIDToId(resourceType, s string) (uint64, error) {
+	g, err := ParseGIDOf(resourceType, s)
+	if err != nil {
+		return 0, err
+	}
+	return g.Uint64()
+}
+
+// IdToGID returns the global id of the resource of the given type with the
+// given REST id, e.g. IdToGID(GIDTypeProduct, 123) returns
+// gid://shopify/Product/123.
+func IdToGID(resourceType string, id uint64) string {
+	return NewGID(resourceType, id).String()
+}
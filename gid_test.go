@@ -0,0 +1,113 @@
+package goshopify
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestParseGID(t *testing.T) {
+	cases := []struct {
+		in       string
+		expected GID
+	}{
+		{"gid://shopify/Product/123", GID{ResourceType: "Product", Id: "123"}},
+		{"gid://shopify/ProductVariant/456", GID{ResourceType: "ProductVariant", Id: "456"}},
+		{"gid://shopify/Cart/c1-7a2abe82733a34e84aa472d57fb5c3c1?key=abc", GID{ResourceType: "Cart", Id: "c1-7a2abe82733a34e84aa472d57fb5c3c1", Query: "key=abc"}},
+	}
+
+	for _, c := range cases {
+		g, err := ParseGID(c.in)
+		if err != nil {
+			t.Errorf("ParseGID(%q) returned error: %v", c.in, err)
+			continue
+		}
+		if g != c.expected {
+			t.Errorf("ParseGID(%q) = %+v, expected %+v", c.in, g, c.expected)
+		}
+		if s := g.String(); s != c.in {
+			t.Errorf("GID.String() = %q, expected %q", s, c.in)
+		}
+	}
+}
+
+func TestParseGIDInvalid(t *testing.T) {
+	invalid := []string{
+		"",
+		"123",
+		"gid://shopify/Product",
+		"gid://shopify/Product/",
+		"gid://shopify//123",
+		"gid://shopify/Product/123/456",
+		"gid://other/Product/123",
+		"https://shopify/Product/123",
+	}
+
+	for _, s := range invalid {
+		if _, err := ParseGID(s); !errors.Is(err, ErrInvalidGID) {
+			t.Errorf("ParseGID(%q) returned error %v, expected ErrInvalidGID", s, err)
+		}
+	}
+}
+
+func TestGIDConversions(t *testing.T) {
+	g := NewGID(GIDTypeProduct, 123)
+	if s := g.String(); s != "gid://shopify/Product/123" {
+		t.Errorf("NewGID().String() = %q", s)
+	}
+	if !g.Is(GIDTypeProduct) || g.Is(GIDTypeProductVariant) {
+		t.Errorf("GID.Is returned unexpected results for %s", g)
+	}
+	if id, err := g.Uint64(); err != nil || id != 123 {
+		t.Errorf("GID.Uint64() = %d, %v, expected 123", id, err)
+	}
+
+	if s := IdToGID(GIDTypeOrder, 450789469); s != "gid://shopify/Order/450789469" {
+		t.Errorf("IdToGID() = %q", s)
+	}
+	if id, err := GIDToId(GIDTypeOrder, "gid://shopify/Order/450789469"); err != nil || id != 450789469 {
+		t.Errorf("GIDToId() = %d, %v, expected 450789469", id, err)
+	}
+	if _, err := GIDToId(GIDTypeOrder, "gid://shopify/Product/450789469"); !errors.Is(err, ErrInvalidGID) {
+		t.Errorf("GIDToId() of another resource type returned error %v, expected ErrInvalidGID", err)
+	}
+
+	cart, _ := ParseGID("gid://shopify/Cart/c1-abc?key=def")
+	if _, err := cart.Uint64(); !errors.Is(err, ErrInvalidGID) {
+		t.Errorf("GID.Uint64() of a non numeric id returned error %v, expected ErrInvalidGID", err)
+	}
+
+	if !(GID{}).IsZero() || (GID{}).String() != "" || (GID{}).Validate() == nil {
+		t.Errorf("the zero GID should be empty and invalid")
+	}
+}
+
+func TestGIDJSON(t *testing.T) {
+	type resource struct {
+		Id       GID  `json:"id"`
+		ParentId GID  `json:"parent_id"`
+		Other    *GID `json:"other,omitempty"`
+	}
+
+	var r resource
+	if err := json.Unmarshal([]byte(`{"id":"gid://shopify/Product/1","parent_id":null}`), &r); err != nil {
+		t.Fatalf("json.Unmarshal returned error: %v", err)
+	}
+	if r.Id != NewGID(GIDTypeProduct, 1) || !r.ParentId.IsZero() {
+		t.Errorf("json.Unmarshal decoded %+v", r)
+	}
+
+	data, err := json.Marshal(r)
+	if err != nil {
+		t.Fatalf("json.Marshal returned error: %v", err)
+	}
+	if expected := `{"id":"gid://shopify/Product/1","parent_id":null}`; string(data) != expected {
+		t.Errorf("json.Marshal returned %s, expected %s", data, expected)
+	}
+
+	for _, invalid := range []string{`{"id":"123"}`, `{"id":123}`} {
+		if err := json.Unmarshal([]byte(invalid), &r); !errors.Is(err, ErrInvalidGID) {
+			t.Errorf("json.Unmarshal(%s) returned error %v, expected ErrInvalidGID", invalid, err)
+		}
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
+	"log/slog"
 	"net/http"
 	"net/url"
 	"path"
@@ -17,6 +18,7 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/google/go-querystring/query"
@@ -45,8 +47,15 @@ type App struct {
 	Scope       string
 	Password    string
 	Client      *Client // see GetAccessToken
+
+	// ExpiringOfflineTokens requests expiring offline access tokens, which
+	// come with a refresh token, when exchanging authorization codes and
+	// session tokens, see WithTokenRefresh
+	ExpiringOfflineTokens bool
 }
 
+// RateLimitInfo holds the rate limit state reported by Shopify, see
+// Client.GetRateLimits.
 type RateLimitInfo struct {
 	RequestCount      int
 	BucketSize        int
@@ -54,6 +63,20 @@ type RateLimitInfo struct {
 	RetryAfterSeconds float64
 }
 
+// clone returns a deep copy which shares no pointers with r
+func (r RateLimitInfo) clone() RateLimitInfo {
+	if r.GraphQLCost != nil {
+		cost := *r.GraphQLCost
+		if cost.ActualQueryCost != nil {
+			actual := *cost.ActualQueryCost
+			cost.ActualQueryCost = &actual
+		}
+		r.GraphQLCost = &cost
+	}
+
+	return r
+}
+
 // Client manages communication with the Shopify API.
 type Client struct {
 	// HTTP client used to communicate with the Shopify API.
@@ -72,15 +95,60 @@ type Client struct {
 	pathPrefix string
 
 	// version you're currently using of the api, defaults to "stable"
+	// guarded by mu as it is set from the first response when using stable
 	apiVersion string
 
 	// A permanent access token
 	token string
 
+	// when the token expires, zero if it doesn't, see WithTokenExpiry
+	// guarded by mu with the token as they are renewed by refreshAccessToken
+	tokenExpiresAt time.Time
+
+	// renews the token once it expired, see WithTokenRefresh and
+	// WithClientCredentials
+	refreshToken          string
+	refreshTokenExpiresAt time.Time
+	tokenStore            TokenStore
+	clientCredentials     bool
+	refreshMu             sync.Mutex
+
+	// rejects unknown webhook topics, see WithWebhookTopicValidation
+	validateWebhookTopics bool
+
+	// authenticates Storefront API requests instead of the access token,
+	// see NewStorefrontClient
+	storefrontToken       string
+	storefrontTokenHeader string
+
 	// max number of retries, defaults to 0 for no retries see WithRetry option
-	retries  int
-	attempts int
+	retries int
+
+	// decides which failed requests are retried, see WithRetryPolicy option
+	retryPolicy RetryPolicy
+
+	// throttles REST requests before they are sent, see WithRateLimiter
+	rateLimiter RateLimiter
+
+	// schedules GraphQL queries by cost before they are sent, see WithGraphQLThrottler
+	graphQLThrottler GraphQLThrottler
+
+	// wraps every API call, see WithMiddleware
+	middleware []Middleware
+
+	// masks sensitive values before they are logged, see WithRedactor
+	redactor *Redactor
 
+	// guards the state updated from responses, so the client can be shared
+	// between goroutines
+	mu         sync.RWMutex
+	rateLimits RateLimitInfo
+
+	// RateLimits is a copy of the rate limit state, updated after each
+	// response.
+	//
+	// Deprecated: reading it races with the requests in flight, use
+	// GetRateLimits.
 	RateLimits RateLimitInfo
 
 	// Services used for communicating with the API
@@ -125,6 +193,7 @@ type Client struct {
 	GiftCard                   GiftCardService
 	FulfillmentOrder           FulfillmentOrderService
 	GraphQL                    GraphQLService
+	BulkOperation              BulkOperationService
 	AssignedFulfillmentOrder   AssignedFulfillmentOrderService
 	FulfillmentEvent           FulfillmentEventService
 	FulfillmentRequest         FulfillmentRequestService
@@ -184,6 +253,10 @@ func (e ResponseDecodingError) Error() string {
 	return e.Message
 }
 
+// ErrTokenExpired is returned, without sending the request, when the access
+// token of the client expired, see WithTokenExpiry.
+var ErrTokenExpired = errors.New("shopify access token expired")
+
 // An error specific to a rate-limiting response. Embeds the ResponseError to
 // allow consumers to handle it the same was a normal ResponseError.
 type RateLimitError struct {
@@ -240,8 +313,14 @@ func (c *Client) NewRequest(ctx context.Context, method, relPath string, body, o
 	req.Header.Add("Accept", "application/json")
 	req.Header.Add("User-Agent", UserAgent)
 
-	if c.token != "" {
-		req.Header.Add("X-Shopify-Access-Token", c.token)
+	c.mu.RLock()
+	token := c.token
+	c.mu.RUnlock()
+
+	if c.storefrontToken != "" {
+		c.setStorefrontHeaders(req)
+	} else if token != "" {
+		req.Header.Add(accessTokenHeader, token)
 	} else if c.app.Password != "" {
 		req.SetBasicAuth(c.app.ApiKey, c.app.Password)
 	}
@@ -283,6 +362,7 @@ func NewClient(app App, shopName, token string, opts ...Option) (*Client, error)
 			Timeout: time.Second * defaultHttpTimeout,
 		},
 		log:        &LeveledLogger{},
+		redactor:   NewRedactor(),
 		app:        app,
 		baseURL:    baseURL,
 		token:      token,
@@ -331,6 +411,7 @@ func NewClient(app App, shopName, token string, opts ...Option) (*Client, error)
 	c.GiftCard = &GiftCardServiceOp{client: c}
 	c.FulfillmentOrder = &FulfillmentOrderServiceOp{client: c}
 	c.GraphQL = &GraphQLServiceOp{client: c}
+	c.BulkOperation = &BulkOperationServiceOp{client: c}
 	c.AssignedFulfillmentOrder = &AssignedFulfillmentOrderServiceOp{client: c}
 	c.FulfillmentEvent = &FulfillmentEventServiceOp{client: c}
 	c.FulfillmentRequest = &FulfillmentRequestServiceOp{client: c}
@@ -361,10 +442,47 @@ func (c *Client) Do(req *http.Request, v interface{}) error {
 
 // doGetHeaders executes a request, decoding the response into `v` and also returns any response headers.
 func (c *Client) doGetHeaders(req *http.Request, v interface{}) (http.Header, error) {
+	response, err := c.do(req, v)
+	if err != nil {
+		return nil, err
+	}
+	return response.Header, nil
+}
+
+// do executes a request through the middleware chain, decoding the response
+// into `v`, and returns the metadata of the call. The metadata is returned
+// even if the call failed and is recorded into the *Response registered with
+// WithResponseCapture.
+func (c *Client) do(req *http.Request, v interface{}) (*Response, error) {
+	return c.doHandler(req, func(req *http.Request) (*Response, error) {
+		return c.send(req, v)
+	})
+}
+
+// doHandler executes a request with handler through the middleware chain,
+// see do.
+func (c *Client) doHandler(req *http.Request, handler Handler) (*Response, error) {
+	response, err := c.chain(handler)(req)
+	if response == nil {
+		// a middleware short-circuited the call
+		response = new(Response)
+	}
+
+	if capture := capturedResponse(req.Context()); capture != nil {
+		*capture = *response
+	}
+
+	return response, err
+}
+
+// send executes a request, retrying it according to the retry policy, and
+// decodes the response into `v`.
+func (c *Client) send(req *http.Request, v interface{}) (*Response, error) {
 	var resp *http.Response
 	var err error
-	retries := c.retries
-	c.attempts = 0
+	retryPolicy := c.getRetryPolicy()
+	start := time.Now()
+	response := new(Response)
 	c.logRequest(req)
 
 	// copy request body so it can be re-used
@@ -373,115 +491,285 @@ func (c *Client) doGetHeaders(req *http.Request, v interface{}) (http.Header, er
 		body, err = ioutil.ReadAll(req.Body)
 		defer req.Body.Close()
 		if err != nil {
-			return nil, err
+			return response, err
 		}
 	}
 
+	refreshed := false
 	for {
-		c.attempts++
-		req.Body = ioutil.NopCloser(bytes.NewBuffer(body))
-		resp, err = c.Client.Do(req)
-		c.logResponse(resp)
-		if err != nil {
-			return nil, err // http client errors, not api responses
+		if err := c.ensureToken(req); err != nil {
+			return response, err
 		}
 
-		respErr := CheckResponseError(resp)
-		if respErr == nil {
-			break // no errors, break out of the retry loop
+		response.Attempts++
+
+		if c.rateLimiter != nil && !isGraphQLRequest(req) {
+			err = c.rateLimiter.Wait(req.Context())
+			if err != nil {
+				return response, err
+			}
 		}
 
-		// retry scenario, close resp and any continue will retry
-		resp.Body.Close()
+		req.Body = ioutil.NopCloser(bytes.NewBuffer(body))
+		attemptStart := time.Now()
+		resp, err = c.Client.Do(req)
+		c.logResponse(req, resp, err, time.Since(attemptStart))
 
-		if retries <= 1 {
-			return nil, respErr
-		}
+		if err == nil {
+			response.update(resp)
 
-		if rateLimitErr, isRetryErr := respErr.(RateLimitError); isRetryErr {
-			// back off and retry
+			if c.rateLimiter != nil {
+				if used, size, ok := parseCallLimit(resp.Header); ok {
+					c.rateLimiter.Observe(used, size)
+				}
+			}
 
-			wait := time.Duration(rateLimitErr.RetryAfter) * time.Second
-			c.log.Debugf("rate limited waiting %s", wait.String())
-			time.Sleep(wait)
-			retries--
-			continue
-		}
+			if resp.StatusCode == http.StatusTooManyRequests {
+				response.RateLimited++
+			}
+
+			err = CheckResponseError(resp)
+			if err == nil {
+				break // no errors, break out of the retry loop
+			}
+
+			// retry scenario, close resp and any continue will retry
+			resp.Body.Close()
 
-		var doRetry bool
-		switch resp.StatusCode {
-		case http.StatusServiceUnavailable:
-			c.log.Debugf("service unavailable, retrying")
-			doRetry = true
-			retries--
+			if resp.StatusCode == http.StatusUnauthorized && !refreshed && c.canRefresh() {
+				refreshed = true
+				if err := c.refreshAccessToken(req); err != nil {
+					return response, err
+				}
+				continue
+			}
+		} else {
+			resp = nil // http client errors, not api responses
 		}
 
-		if doRetry {
-			continue
+		wait, retry := retryPolicy.ShouldRetry(RetryAttempt{
+			Request:  req,
+			Response: resp,
+			Err:      err,
+			Attempt:  response.Attempts,
+			Elapsed:  time.Since(start),
+		})
+		if !retry {
+			return response, err
 		}
 
-		// no retry attempts, just return the err
-		return nil, respErr
+		response.RetryWait += wait
+		c.log.Debugf("request failed with %q, retrying in %s", err, wait)
+		if sleepErr := sleepContext(req.Context(), wait); sleepErr != nil {
+			return response, sleepErr
+		}
 	}
 
 	defer resp.Body.Close()
 
-	if c.apiVersion == defaultApiVersion && resp.Header.Get("X-Shopify-API-Version") != "" {
+	c.mu.Lock()
+	if c.apiVersion == defaultApiVersion && response.ApiVersion != "" {
 		// if using stable on first request set the api version
-		c.apiVersion = resp.Header.Get("X-Shopify-API-Version")
+		c.apiVersion = response.ApiVersion
 		c.log.Infof("api version not set, now using %s", c.apiVersion)
 	}
+	c.mu.Unlock()
+
+	if response.DeprecatedReason != "" {
+		c.log.Warnf("deprecated api call %s %s: %s", req.Method, req.URL.Path, response.DeprecatedReason)
+	}
 
 	if v != nil {
 		decoder := json.NewDecoder(resp.Body)
 		err := decoder.Decode(&v)
 		if err != nil {
-			return nil, err
+			return response, err
 		}
 	}
 
-	if s := strings.Split(resp.Header.Get("X-Shopify-Shop-Api-Call-Limit"), "/"); len(s) == 2 {
-		c.RateLimits.RequestCount, _ = strconv.Atoi(s[0])
-		c.RateLimits.BucketSize, _ = strconv.Atoi(s[1])
+	if gr, ok := v.(*graphQLResponse); ok {
+		response.updateGraphQL(gr)
 	}
 
-	c.RateLimits.RetryAfterSeconds, _ = strconv.ParseFloat(resp.Header.Get("Retry-After"), 64)
+	c.mu.Lock()
+	if s := strings.Split(resp.Header.Get(callLimitHeader), "/"); len(s) == 2 {
+		c.rateLimits.RequestCount, _ = strconv.Atoi(s[0])
+		c.rateLimits.BucketSize, _ = strconv.Atoi(s[1])
+	}
+
+	c.rateLimits.RetryAfterSeconds, _ = strconv.ParseFloat(resp.Header.Get("Retry-After"), 64)
+	c.RateLimits = c.rateLimits.clone()
+	c.mu.Unlock()
 
-	return resp.Header, nil
+	return response, nil
+}
+
+// GetRateLimits returns a snapshot of the rate limit state reported by the
+// most recent responses. It is safe to call while requests are in flight.
+func (c *Client) GetRateLimits() RateLimitInfo {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.rateLimits.clone()
+}
+
+// setGraphQLRateLimits records the cost reported by a GraphQL response
+func (c *Client) setGraphQLRateLimits(cost GraphQLCost, retryAfterSeconds float64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.rateLimits.GraphQLCost = &cost
+	c.rateLimits.RetryAfterSeconds = retryAfterSeconds
+	c.RateLimits = c.rateLimits.clone()
+}
+
+// isGraphQLRequest reports whether the request targets the GraphQL endpoint,
+// which is limited by query cost rather than the REST leaky bucket.
+func isGraphQLRequest(req *http.Request) bool {
+	return strings.HasSuffix(req.URL.Path, "/graphql.json")
 }
 
 func (c *Client) logRequest(req *http.Request) {
 	if req == nil {
 		return
 	}
+	if l, ok := c.log.(*SlogLogger); ok {
+		c.slogRequest(l, req)
+		return
+	}
+	if !c.debugEnabled() {
+		return
+	}
 	if req.URL != nil {
-		c.log.Debugf("%s: %s", req.Method, req.URL.String())
+		c.log.Debugf("%s: %s", req.Method, c.redactURL(req.URL))
 	}
 	c.logBody(&req.Body, "SENT: %s")
 }
 
-func (c *Client) logResponse(res *http.Response) {
-	if res == nil {
+func (c *Client) logResponse(req *http.Request, res *http.Response, err error, elapsed time.Duration) {
+	if l, ok := c.log.(*SlogLogger); ok && req != nil {
+		c.slogResponse(l, req, res, err, elapsed)
+		return
+	}
+	if res == nil || !c.debugEnabled() {
 		return
 	}
 
-	c.log.Debugf("Shopify X-Request-Id: %s", res.Header.Get("X-Request-Id"))
+	c.log.Debugf("Shopify X-Request-Id: %s", res.Header.Get(requestIdHeader))
 	c.log.Debugf("RECV %d: %s", res.StatusCode, res.Status)
 	c.logBody(&res.Body, "RESP: %s")
 }
 
+// debugEnabled reports whether the logger of the client emits debug
+// messages, which is assumed for loggers that can't tell
+func (c *Client) debugEnabled() bool {
+	if l, ok := c.log.(*LeveledLogger); ok {
+		return l.Level >= LevelDebug
+	}
+	return true
+}
+
 func (c *Client) logBody(body *io.ReadCloser, format string) {
-	if body == nil {
+	if b := c.readBody(body); b != nil {
+		c.log.Debugf(format, b)
+	}
+}
+
+// slogRequest logs a request as a structured record at debug level
+func (c *Client) slogRequest(l *SlogLogger, req *http.Request) {
+	ctx := req.Context()
+	if !l.Logger.Enabled(ctx, slog.LevelDebug) {
 		return
 	}
-	b, err := ioutil.ReadAll(*body)
-	if err != nil && !errors.Is(err, io.EOF) {
+
+	attrs := []slog.Attr{slog.String("method", req.Method)}
+	if req.URL != nil {
+		attrs = append(attrs, slog.String("url", c.redactURL(req.URL)))
+	}
+	if b := c.readBody(&req.Body); b != nil {
+		attrs = append(attrs, slog.Any("body", b))
+	}
+
+	l.Logger.LogAttrs(ctx, slog.LevelDebug, "shopify request", attrs...)
+}
+
+// slogResponse logs the outcome of an attempt as a structured record, at warn
+// level if it failed and at debug level otherwise
+func (c *Client) slogResponse(l *SlogLogger, req *http.Request, res *http.Response, err error, elapsed time.Duration) {
+	ctx := req.Context()
+	level := slog.LevelDebug
+	if err != nil || (res != nil && res.StatusCode >= http.StatusBadRequest) {
+		level = slog.LevelWarn
+	}
+	if !l.Logger.Enabled(ctx, level) {
 		return
 	}
-	if len(b) > 0 {
-		c.log.Debugf(format, string(b))
+
+	attrs := []slog.Attr{slog.String("method", req.Method)}
+	if req.URL != nil {
+		attrs = append(attrs, slog.String("path", req.URL.Path))
+	}
+	if res != nil {
+		attrs = append(attrs,
+			slog.Int("status", res.StatusCode),
+			slog.String("request_id", res.Header.Get(requestIdHeader)))
+	}
+	attrs = append(attrs, slog.Duration("duration", elapsed))
+	if err != nil {
+		attrs = append(attrs, slog.String("error", err.Error()))
+	}
+	if res != nil && l.Logger.Enabled(ctx, slog.LevelDebug) {
+		if b := c.readBody(&res.Body); b != nil {
+			attrs = append(attrs, slog.Any("body", b))
+		}
+	}
+
+	l.Logger.LogAttrs(ctx, level, "shopify response", attrs...)
+}
+
+// loggedBody is a request or response body which is only redacted once it
+// is formatted by a logger
+type loggedBody struct {
+	b        []byte
+	redactor *Redactor
+}
+
+func (b *loggedBody) String() string {
+	if b.redactor == nil {
+		return string(b.b)
+	}
+	return string(b.redactor.Redact(b.b))
+}
+
+// LogValue implements slog.LogValuer
+func (b *loggedBody) LogValue() slog.Value {
+	return slog.StringValue(b.String())
+}
+
+// readBody reads a body so it can be logged, replacing it with a copy that
+// can be read again. It returns nil if the body is empty.
+func (c *Client) readBody(body *io.ReadCloser) *loggedBody {
+	if body == nil || *body == nil {
+		return nil
+	}
+	b, err := ioutil.ReadAll(*body)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil
 	}
 	*body = ioutil.NopCloser(bytes.NewBuffer(b))
+
+	if len(b) == 0 {
+		return nil
+	}
+	return &loggedBody{b: b, redactor: c.redactor}
+}
+
+// redactURL returns the url with sensitive query values masked
+func (c *Client) redactURL(u *url.URL) string {
+	if c.redactor == nil {
+		return u.String()
+	}
+	return c.redactor.RedactURL(u)
 }
 
 func wrapSpecificError(r *http.Response, err ResponseError) error {
@@ -461,10 +461,10 @@ func TestRetry(t *testing.T) {
 			t.Error("error creating request: ", err)
 		}
 
-		err = client.Do(req, body)
+		response, err := client.do(req, body)
 
-		if client.attempts != c.retries {
-			t.Errorf("Do(): attempts do not match retries %#v, actual %#v", client.attempts, c.retries)
+		if response.Attempts != c.retries {
+			t.Errorf("Do(): attempts do not match retries %#v, actual %#v", response.Attempts, c.retries)
 		}
 
 		if err != nil {
@@ -953,8 +953,10 @@ func TestDoRateLimit(t *testing.T) {
 				if !reflect.DeepEqual(err, c.expected) {
 					t.Errorf("Do(): expected error %#v, actual %#v", c.expected, err)
 				}
+			} else if err == nil && !reflect.DeepEqual(client.GetRateLimits(), c.expected) {
+				t.Errorf("%s: expected %#v, actual %#v", c.description, c.expected, client.GetRateLimits())
 			} else if err == nil && !reflect.DeepEqual(client.RateLimits, c.expected) {
-				t.Errorf("%s: expected %#v, actual %#v", c.description, c.expected, client.RateLimits)
+				t.Errorf("%s: expected client.RateLimits %#v, actual %#v", c.description, c.expected, client.RateLimits)
 			}
 		})
 	}
@@ -2,7 +2,13 @@ package goshopify
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"math"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
 	"time"
 )
 
@@ -11,6 +17,7 @@ import (
 // See https://shopify.dev/docs/admin-api/graphql/reference
 type GraphQLService interface {
 	Query(context.Context, string, interface{}, interface{}) error
+	Mutate(context.Context, string, interface{}, interface{}) error
 }
 
 // GraphQLServiceOp handles communication with the graphql endpoint of
@@ -20,8 +27,8 @@ type GraphQLServiceOp struct {
 }
 
 type graphQLResponse struct {
-	Data       interface{}        `json:"data"`
-	Errors     []graphQLError     `json:"errors"`
+	Data       json.RawMessage    `json:"data"`
+	Errors     []GraphQLError     `json:"errors"`
 	Extensions *graphQLExtensions `json:"extensions"`
 }
 
@@ -43,29 +50,162 @@ type GraphQLThrottleStatus struct {
 	RestoreRate        float64 `json:"restoreRate"`
 }
 
-type graphQLError struct {
+// GraphQLError is an error of the errors list of a GraphQL response
+// See https://shopify.dev/docs/api/usage/response-codes#graphql
+type GraphQLError struct {
 	Message    string                  `json:"message"`
-	Extensions *graphQLErrorExtensions `json:"extensions"`
-	Locations  []graphQLErrorLocation  `json:"locations"`
+	Extensions *GraphQLErrorExtensions `json:"extensions"`
+	Locations  []GraphQLErrorLocation  `json:"locations"`
+
+	// Path is the path of the field in error, made of field names and list
+	// indexes, e.g. ["products", 0, "title"]
+	Path []interface{} `json:"path"`
 }
 
-type graphQLErrorExtensions struct {
-	Code          string
-	Documentation string
+func (e GraphQLError) Error() string {
+	return e.Message
+}
+
+// Code returns the code of the error, e.g. THROTTLED or ACCESS_DENIED, and an
+// empty string if it has none.
+func (e GraphQLError) Code() string {
+	if e.Extensions == nil {
+		return ""
+	}
+	return e.Extensions.Code
+}
+
+// GraphQLErrorExtensions holds the code of a GraphQLError
+type GraphQLErrorExtensions struct {
+	Code          string `json:"code"`
+	Documentation string `json:"documentation"`
 }
 
 const (
 	graphQLErrorCodeThrottled = "THROTTLED"
 )
 
-type graphQLErrorLocation struct {
+// GraphQLErrorLocation is the position in the query of a GraphQLError
+type GraphQLErrorLocation struct {
 	Line   int `json:"line"`
 	Column int `json:"column"`
 }
 
+// GraphQLResponseError is returned by GraphQLService when the response has
+// errors. It is also a ResponseError with the messages of the errors, and
+// errors.As finds the first GraphQLError.
+type GraphQLResponseError struct {
+	ResponseError
+
+	// GraphQLErrors are the errors of the response, with their code, path
+	// and locations
+	GraphQLErrors []GraphQLError
+
+	// PartialData is set if the response has data besides the errors, e.g.
+	// when only some fields failed. The data is decoded as usual.
+	PartialData bool
+}
+
+// Unwrap returns the ResponseError and the GraphQLErrors
+func (e GraphQLResponseError) Unwrap() []error {
+	errs := []error{e.ResponseError}
+	for _, err := range e.GraphQLErrors {
+		errs = append(errs, err)
+	}
+	return errs
+}
+
+// UserError is an error returned by a mutation in its userErrors field, e.g.
+// because of an invalid input.
+type UserError struct {
+	// Field is the path of the input field in error, e.g. ["input", "title"]
+	Field   []string `json:"field"`
+	Message string   `json:"message"`
+
+	// Code is only returned by some mutations, e.g. TAKEN or BLANK
+	Code string `json:"code,omitempty"`
+}
+
+func (e UserError) Error() string {
+	if len(e.Field) == 0 {
+		return e.Message
+	}
+	return strings.Join(e.Field, ".") + ": " + e.Message
+}
+
+// UserErrors is returned by GraphQLService.Mutate when the payload of the
+// mutation has userErrors, use errors.As to get them:
+//
+//	var userErrs goshopify.UserErrors
+//	if errors.As(err, &userErrs) {
+//		// the input is invalid
+//	}
+type UserErrors []UserError
+
+func (e UserErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, ", ")
+}
+
+// userErrorsError returns userErrors as an error, nil if there are none
+func userErrorsError(userErrors []UserError) error {
+	if len(userErrors) == 0 {
+		return nil
+	}
+	return UserErrors(userErrors)
+}
+
+// findUserErrors returns the userErrors of the mutation payloads of data
+func findUserErrors(data json.RawMessage) []UserError {
+	var payloads map[string]json.RawMessage
+	if err := json.Unmarshal(data, &payloads); err != nil {
+		return nil
+	}
+
+	// sorted so the errors of several mutations are in a stable order
+	fields := make([]string, 0, len(payloads))
+	for field := range payloads {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	var userErrors []UserError
+	for _, field := range fields {
+		var payload struct {
+			UserErrors []UserError `json:"userErrors"`
+		}
+		if err := json.Unmarshal(payloads[field], &payload); err == nil {
+			userErrors = append(userErrors, payload.UserErrors...)
+		}
+	}
+	return userErrors
+}
+
 // Query creates a graphql query against the Shopify API
-// the "data" portion of the response is unmarshalled into resp
+// the "data" portion of the response is unmarshalled into resp, also when
+// the response has errors, see GraphQLResponseError
 func (s *GraphQLServiceOp) Query(ctx context.Context, q string, vars, resp interface{}) error {
+	_, err := s.query(ctx, q, vars, resp)
+	return err
+}
+
+// Mutate runs a graphql mutation like Query, and returns the userErrors of
+// its payload, if any, as UserErrors. The data is unmarshalled into resp
+// in any case.
+func (s *GraphQLServiceOp) Mutate(ctx context.Context, m string, vars, resp interface{}) error {
+	data, err := s.query(ctx, m, vars, resp)
+	if err != nil {
+		return err
+	}
+	return userErrorsError(findUserErrors(data))
+}
+
+// query sends a graphql query, retrying it when throttled, unmarshals the
+// "data" portion of the response into resp and returns it
+func (s *GraphQLServiceOp) query(ctx context.Context, q string, vars, resp interface{}) (json.RawMessage, error) {
 	data := struct {
 		Query     string      `json:"query"`
 		Variables interface{} `json:"variables"`
@@ -74,60 +214,124 @@ func (s *GraphQLServiceOp) Query(ctx context.Context, q string, vars, resp inter
 		Variables: vars,
 	}
 
-	attempts := 0
+	req, err := s.client.NewRequest(ctx, "POST", path.Join(s.client.pathPrefix, "graphql.json"), data, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// the throttled attempts are retried within a single call of the
+	// middleware, which sees the metadata of all the attempts
+	var gr graphQLResponse
+	_, err = s.client.doHandler(req, func(req *http.Request) (*Response, error) {
+		return s.send(req, q, vars, &gr)
+	})
+
+	var responseError GraphQLResponseError
+	if err != nil && !errors.As(err, &responseError) {
+		return nil, err
+	}
+
+	if len(gr.Data) > 0 && resp != nil {
+		if decodeErr := json.Unmarshal(gr.Data, resp); decodeErr != nil && err == nil {
+			err = decodeErr
+		}
+	}
+
+	return gr.Data, err
+}
+
+// send sends the query until it isn't throttled or the retry policy gives up,
+// decoding the response into gr, and returns the metadata of all attempts
+func (s *GraphQLServiceOp) send(req *http.Request, q string, vars interface{}, gr *graphQLResponse) (*Response, error) {
+	ctx := req.Context()
+	response := new(Response)
+	start := time.Now()
 
 	for {
-		gr := graphQLResponse{
-			Data: resp,
+		*gr = graphQLResponse{}
+
+		var reserved int
+		if s.client.graphQLThrottler != nil {
+			var err error
+			reserved, err = s.client.graphQLThrottler.Wait(ctx, q, vars)
+			if err != nil {
+				return response, err
+			}
 		}
 
-		err := s.client.Post(ctx, "graphql.json", data, &gr)
+		if response.Attempts > 0 && req.GetBody != nil {
+			// the body was consumed by the previous attempt
+			body, err := req.GetBody()
+			if err != nil {
+				return response, err
+			}
+			req.Body = body
+		}
 
-		// internal attempts count towards outer total
-		attempts += 1
+		attempt, err := s.client.send(req, gr)
+		response.merge(attempt)
+
+		if s.client.graphQLThrottler != nil {
+			var cost *GraphQLCost
+			if gr.Extensions != nil {
+				cost = &gr.Extensions.Cost
+			}
+			s.client.graphQLThrottler.Observe(q, vars, reserved, cost)
+		}
 
 		var retryAfterSecs float64
 
 		if gr.Extensions != nil {
 			retryAfterSecs = gr.Extensions.Cost.RetryAfterSeconds()
-			s.client.RateLimits.GraphQLCost = &gr.Extensions.Cost
-			s.client.RateLimits.RetryAfterSeconds = retryAfterSecs
+			s.client.setGraphQLRateLimits(gr.Extensions.Cost, retryAfterSecs)
 		}
 
-		if len(gr.Errors) > 0 {
-			responseError := ResponseError{Status: 200}
-			var doRetry bool
-
-			for _, err := range gr.Errors {
-				if err.Extensions != nil && err.Extensions.Code == graphQLErrorCodeThrottled {
-					if attempts >= s.client.retries {
-						return RateLimitError{
-							RetryAfter: int(math.Ceil(retryAfterSecs)),
-							ResponseError: ResponseError{
-								Status:  200,
-								Message: err.Message,
-							},
-						}
-					}
-
-					// only need to retry graphql throttled retries
-					doRetry = true
-				}
+		if err != nil || len(gr.Errors) == 0 {
+			return response, err
+		}
 
-				responseError.Errors = append(responseError.Errors, err.Message)
-			}
+		responseError := GraphQLResponseError{
+			ResponseError: ResponseError{Status: 200},
+			GraphQLErrors: gr.Errors,
+			PartialData:   len(gr.Data) > 0 && string(gr.Data) != "null",
+		}
+		var rateLimitErr *RateLimitError
 
-			if doRetry {
-				wait := time.Duration(math.Ceil(retryAfterSecs)) * time.Second
-				s.client.log.Debugf("rate limited waiting %s", wait.String())
-				time.Sleep(wait)
-				continue
+		for _, err := range gr.Errors {
+			if err.Code() == graphQLErrorCodeThrottled {
+				rateLimitErr = &RateLimitError{
+					RetryAfter: int(math.Ceil(retryAfterSecs)),
+					ResponseError: ResponseError{
+						Status:  200,
+						Message: err.Message,
+					},
+				}
 			}
 
-			err = responseError
+			responseError.Errors = append(responseError.Errors, err.Message)
 		}
 
-		return err
+		// only need to retry graphql throttled retries
+		if rateLimitErr == nil {
+			return response, responseError
+		}
+
+		// internal attempts count towards outer total
+		wait, retry := s.client.getRetryPolicy().ShouldRetry(RetryAttempt{
+			Err:     *rateLimitErr,
+			Attempt: response.Attempts,
+			Elapsed: time.Since(start),
+		})
+		if !retry {
+			return response, *rateLimitErr
+		}
+
+		response.RetryWait += wait
+		s.client.log.Debugf("rate limited waiting %s", wait.String())
+		err = sleepContext(ctx, wait)
+		if err != nil {
+			return response, err
+		}
 	}
 }
 
@@ -0,0 +1,127 @@
+package goshopify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// graphQLConnection is a Relay connection, see
+// https://shopify.dev/docs/api/usage/pagination-graphql
+type graphQLConnection[T any] struct {
+	Edges []struct {
+		Node T `json:"node"`
+	} `json:"edges"`
+	Nodes    []T `json:"nodes"`
+	PageInfo struct {
+		HasNextPage bool   `json:"hasNextPage"`
+		EndCursor   string `json:"endCursor"`
+	} `json:"pageInfo"`
+}
+
+// GraphQLPages returns an iterator lazily walking the pages of a connection.
+// The query must take a $cursor variable, passed as the after argument of the
+// connection, and select the pageInfo of the connection along with its edges
+// or nodes:
+//
+//	query := `query($cursor: String) {
+//		products(first: 250, after: $cursor) {
+//			nodes { id title }
+//			pageInfo { hasNextPage endCursor }
+//		}
+//	}`
+//	pages := goshopify.GraphQLPages[Product](client.GraphQL, query, nil, "products")
+//
+// path is the dot separated path of the connection in the data of the
+// response, e.g. "product.variants". vars are sent with every page. Pages are
+// fetched until hasNextPage is false, waiting between pages for the points
+// spent by the previous page to be restored when the cost throttle is
+// exhausted. Pagination().NextPageOptions.PageInfo is the cursor of the next
+// page, the iteration can be resumed from it by setting it as the cursor in
+// vars.
+func GraphQLPages[T any](gql GraphQLService, query string, vars map[string]interface{}, path string) *PageIterator[T] {
+	return newPageIterator(graphQLConnectionFetcher[T](gql, query, vars, path), nil)
+}
+
+// GraphQLIter returns an iterator lazily walking the nodes of a connection one
+// at a time, see GraphQLPages.
+func GraphQLIter[T any](gql GraphQLService, query string, vars map[string]interface{}, path string) *ListIterator[T] {
+	return newListIterator(graphQLConnectionFetcher[T](gql, query, vars, path), nil)
+}
+
+// graphQLConnectionFetcher returns a pageFetcher querying the page of the
+// connection after the cursor in the PageInfo of the options
+func graphQLConnectionFetcher[T any](gql GraphQLService, query string, vars map[string]interface{}, path string) pageFetcher[T] {
+	var wait time.Duration
+
+	return func(ctx context.Context, options interface{}) ([]T, *Pagination, error) {
+		if wait > 0 {
+			if err := sleepContext(ctx, wait); err != nil {
+				return nil, nil, err
+			}
+		}
+
+		pageVars := make(map[string]interface{}, len(vars)+1)
+		for k, v := range vars {
+			pageVars[k] = v
+		}
+		if opts, ok := options.(*ListOptions); ok && opts != nil && opts.PageInfo != "" {
+			pageVars["cursor"] = opts.PageInfo
+		} else if _, ok := pageVars["cursor"]; !ok {
+			pageVars["cursor"] = nil
+		}
+
+		var resp Response
+		var data json.RawMessage
+		err := gql.Query(WithResponseCapture(ctx, &resp), query, pageVars, &data)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		wait = 0
+		if cost := resp.RateLimits.GraphQLCost; cost != nil && cost.ThrottleStatus.RestoreRate > 0 {
+			wait = time.Duration(cost.RetryAfterSeconds() * float64(time.Second))
+		}
+
+		for _, field := range strings.Split(path, ".") {
+			if len(data) == 0 {
+				// a parent of the connection is null, e.g. a missing product
+				return nil, nil, nil
+			}
+			var fields map[string]json.RawMessage
+			if err := json.Unmarshal(data, &fields); err != nil {
+				return nil, nil, fmt.Errorf("decoding connection %s: %w", path, err)
+			}
+			data = fields[field]
+		}
+
+		var connection *graphQLConnection[T]
+		if len(data) == 0 {
+			return nil, nil, nil
+		}
+		if err := json.Unmarshal(data, &connection); err != nil {
+			return nil, nil, fmt.Errorf("decoding connection %s: %w", path, err)
+		}
+		if connection == nil {
+			return nil, nil, nil
+		}
+
+		page := connection.Nodes
+		if len(connection.Edges) > 0 {
+			page = make([]T, len(connection.Edges))
+			for i, edge := range connection.Edges {
+				page[i] = edge.Node
+			}
+		}
+
+		var pagination *Pagination
+		if connection.PageInfo.HasNextPage {
+			pagination = &Pagination{
+				NextPageOptions: &ListOptions{PageInfo: connection.PageInfo.EndCursor},
+			}
+		}
+		return page, pagination, nil
+	}
+}
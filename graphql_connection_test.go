@@ -0,0 +1,142 @@
+package goshopify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/jarcoal/httpmock"
+)
+
+type connectionTestProduct struct {
+	Id    string `json:"id"`
+	Title string `json:"title"`
+}
+
+// registerConnectionResponder serves the pages keyed by the cursor variable,
+// "" for the first page, and records the variables of each request
+func registerConnectionResponder(t *testing.T, pages map[string]string) *[]map[string]interface{} {
+	var requests []map[string]interface{}
+	httpmock.RegisterResponder("POST", fmt.Sprintf("https://fooshop.myshopify.com/%s/graphql.json", client.pathPrefix),
+		func(req *http.Request) (*http.Response, error) {
+			var body struct {
+				Variables map[string]interface{} `json:"variables"`
+			}
+			data, _ := io.ReadAll(req.Body)
+			json.Unmarshal(data, &body)
+			requests = append(requests, body.Variables)
+
+			cursor, _ := body.Variables["cursor"].(string)
+			page, ok := pages[cursor]
+			if !ok {
+				t.Errorf("unexpected cursor %q", cursor)
+			}
+			return httpmock.NewStringResponse(200, page), nil
+		})
+	return &requests
+}
+
+func TestGraphQLIter(t *testing.T) {
+	setup()
+	defer teardown()
+
+	requests := registerConnectionResponder(t, map[string]string{
+		"":   `{"data":{"products":{"nodes":[{"id":"gid://shopify/Product/1","title":"Shirt"},{"id":"gid://shopify/Product/2","title":"Hat"}],"pageInfo":{"hasNextPage":true,"endCursor":"c1"}}}}`,
+		"c1": `{"data":{"products":{"nodes":[],"pageInfo":{"hasNextPage":true,"endCursor":"c2"}}}}`,
+		"c2": `{"data":{"products":{"nodes":[{"id":"gid://shopify/Product/3","title":"Socks"}],"pageInfo":{"hasNextPage":false,"endCursor":"c3"}}}}`,
+	})
+
+	query := `query($cursor: String, $status: String) { products(first: 2, after: $cursor, query: $status) { nodes { id title } pageInfo { hasNextPage endCursor } } }`
+	products := GraphQLIter[connectionTestProduct](client.GraphQL, query, map[string]interface{}{"status": "status:active"}, "products")
+
+	var titles []string
+	for products.Next(context.Background()) {
+		titles = append(titles, products.Value().Title)
+	}
+	if err := products.Err(); err != nil {
+		t.Fatalf("GraphQLIter returned error: %v", err)
+	}
+
+	if expected := []string{"Shirt", "Hat", "Socks"}; !reflect.DeepEqual(titles, expected) {
+		t.Errorf("GraphQLIter returned %v, expected %v", titles, expected)
+	}
+
+	expectedRequests := []map[string]interface{}{
+		{"status": "status:active", "cursor": nil},
+		{"status": "status:active", "cursor": "c1"},
+		{"status": "status:active", "cursor": "c2"},
+	}
+	if !reflect.DeepEqual(*requests, expectedRequests) {
+		t.Errorf("GraphQLIter sent variables %v, expected %v", *requests, expectedRequests)
+	}
+}
+
+func TestGraphQLPagesEdges(t *testing.T) {
+	setup()
+	defer teardown()
+
+	registerConnectionResponder(t, map[string]string{
+		"c1": `{"data":{"product":{"variants":{"edges":[{"cursor":"c2","node":{"id":"gid://shopify/ProductVariant/2","title":"M"}}],"pageInfo":{"hasNextPage":true,"endCursor":"c2"}}}}}`,
+	})
+
+	query := `query($id: ID!, $cursor: String) { product(id: $id) { variants(first: 1, after: $cursor) { edges { cursor node { id title } } pageInfo { hasNextPage endCursor } } } }`
+	vars := map[string]interface{}{"id": "gid://shopify/Product/1", "cursor": "c1"}
+	pages := GraphQLPages[connectionTestProduct](client.GraphQL, query, vars, "product.variants")
+
+	if !pages.Next(context.Background()) {
+		t.Fatalf("GraphQLPages returned error: %v", pages.Err())
+	}
+	expected := []connectionTestProduct{{Id: "gid://shopify/ProductVariant/2", Title: "M"}}
+	if !reflect.DeepEqual(pages.Page(), expected) {
+		t.Errorf("GraphQLPages returned %v, expected %v", pages.Page(), expected)
+	}
+	if pagination := pages.Pagination(); pagination == nil || pagination.NextPageOptions.PageInfo != "c2" {
+		t.Errorf("GraphQLPages returned pagination %+v, expected the c2 cursor", pagination)
+	}
+}
+
+func TestGraphQLPagesNullParent(t *testing.T) {
+	setup()
+	defer teardown()
+
+	registerConnectionResponder(t, map[string]string{"": `{"data":{"product":null}}`})
+
+	pages := GraphQLPages[connectionTestProduct](client.GraphQL, "query", nil, "product.variants")
+	if !pages.Next(context.Background()) || len(pages.Page()) != 0 {
+		t.Errorf("GraphQLPages returned %v, %v, expected an empty page", pages.Page(), pages.Err())
+	}
+	if pages.Next(context.Background()) || pages.Err() != nil {
+		t.Errorf("GraphQLPages returned another page or error %v", pages.Err())
+	}
+}
+
+func TestGraphQLPagesThrottle(t *testing.T) {
+	setup()
+	defer teardown()
+
+	cost := `"extensions":{"cost":{"requestedQueryCost":52,"actualQueryCost":50,"throttleStatus":{"maximumAvailable":1000,"currentlyAvailable":0,"restoreRate":1000}}}`
+	registerConnectionResponder(t, map[string]string{
+		"":   `{"data":{"products":{"nodes":[{"id":"1"}],"pageInfo":{"hasNextPage":true,"endCursor":"c1"}}},` + cost + `}`,
+		"c1": `{"data":{"products":{"nodes":[{"id":"2"}],"pageInfo":{"hasNextPage":false,"endCursor":"c2"}}},` + cost + `}`,
+	})
+
+	start := time.Now()
+	var products []connectionTestProduct
+	pages := GraphQLPages[connectionTestProduct](client.GraphQL, "query", nil, "products")
+	for pages.Next(context.Background()) {
+		products = append(products, pages.Page()...)
+	}
+	if err := pages.Err(); err != nil || len(products) != 2 {
+		t.Fatalf("GraphQLPages returned %v, %v", products, err)
+	}
+
+	// the 50 points of the first page are restored in 50ms
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("GraphQLPages fetched the next page after %s, expected to wait for the cost throttle", elapsed)
+	}
+}
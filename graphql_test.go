@@ -2,6 +2,7 @@ package goshopify
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"reflect"
@@ -288,12 +289,13 @@ func TestGraphQLQueryWithThrottledError(t *testing.T) {
 		t.Errorf("GraphQL.Query rle.RetryAfter is %d but expected %d", rle.RetryAfter, int(expectedRetryAfterSeconds))
 	}
 
-	if client.RateLimits.GraphQLCost == nil {
-		t.Errorf("GraphQL.Query should have assigned client.RateLimits.GraphQLCost")
+	rateLimits := client.GetRateLimits()
+	if rateLimits.GraphQLCost == nil {
+		t.Errorf("GraphQL.Query should have assigned client.GetRateLimits().GraphQLCost")
 	}
 
-	if client.RateLimits.RetryAfterSeconds != expectedRetryAfterSeconds {
-		t.Errorf("GraphQL.Query client.RateLimits.RetryAfterSeconds is %f but expected %f", client.RateLimits.RetryAfterSeconds, expectedRetryAfterSeconds)
+	if rateLimits.RetryAfterSeconds != expectedRetryAfterSeconds {
+		t.Errorf("GraphQL.Query client.GetRateLimits().RetryAfterSeconds is %f but expected %f", rateLimits.RetryAfterSeconds, expectedRetryAfterSeconds)
 	}
 }
 
@@ -371,3 +373,145 @@ func TestGraphQLCostRetryAfterSeconds(t *testing.T) {
 func makeIntPointer(v int) *int {
 	return &v
 }
+
+func TestGraphQLQueryWithTypedErrors(t *testing.T) {
+	setup()
+	defer teardown()
+
+	httpmock.RegisterResponder(
+		"POST",
+		fmt.Sprintf("https://fooshop.myshopify.com/%s/graphql.json", client.pathPrefix),
+		httpmock.NewStringResponder(200, `{
+			"data":{"products":[{"title":"Shirt","secret":null}]},
+			"errors":[{
+				"message":"Access denied for secret field.",
+				"locations":[{"line":1,"column":27}],
+				"path":["products",0,"secret"],
+				"extensions":{"code":"ACCESS_DENIED","documentation":"https://shopify.dev/api/usage/access-scopes"}
+			}]
+		}`),
+	)
+
+	resp := struct {
+		Products []struct {
+			Title string `json:"title"`
+		} `json:"products"`
+	}{}
+	err := client.GraphQL.Query(context.Background(), "query { products { title secret } }", nil, &resp)
+
+	var responseErr GraphQLResponseError
+	if !errors.As(err, &responseErr) {
+		t.Fatalf("GraphQL.Query returned error %v, expected a GraphQLResponseError", err)
+	}
+	if !responseErr.PartialData {
+		t.Errorf("GraphQLResponseError.PartialData is false, expected partial data")
+	}
+	if len(resp.Products) != 1 || resp.Products[0].Title != "Shirt" {
+		t.Errorf("GraphQL.Query decoded %+v, expected the partial data", resp)
+	}
+
+	var gqlErr GraphQLError
+	if !errors.As(err, &gqlErr) {
+		t.Fatalf("GraphQL.Query returned error %v, expected a GraphQLError", err)
+	}
+	expectedPath := []interface{}{"products", float64(0), "secret"}
+	if gqlErr.Code() != "ACCESS_DENIED" || !reflect.DeepEqual(gqlErr.Path, expectedPath) ||
+		!reflect.DeepEqual(gqlErr.Locations, []GraphQLErrorLocation{{Line: 1, Column: 27}}) {
+		t.Errorf("GraphQL.Query returned %+v", gqlErr)
+	}
+
+	var responseError ResponseError
+	if !errors.As(err, &responseError) || responseError.Status != 200 || err.Error() != "Access denied for secret field." {
+		t.Errorf("GraphQL.Query returned error %v, expected a ResponseError", err)
+	}
+}
+
+func TestGraphQLQueryResponseErrorCompatibility(t *testing.T) {
+	setup()
+	defer teardown()
+
+	httpmock.RegisterResponder(
+		"POST",
+		fmt.Sprintf("https://fooshop.myshopify.com/%s/graphql.json", client.pathPrefix),
+		httpmock.NewStringResponder(200, `{"errors":[{"message":"Field 'foo' doesn't exist"},{"message":"Invalid query"}]}`),
+	)
+
+	err := client.GraphQL.Query(context.Background(), "query { foo }", nil, nil)
+
+	// callers matching the ResponseError returned before GraphQLResponseError
+	responseErr := &ResponseError{}
+	if !errors.As(err, responseErr) {
+		t.Fatalf("GraphQL.Query returned error %v, expected it to match a ResponseError", err)
+	}
+	expected := ResponseError{Status: 200, Errors: []string{"Field 'foo' doesn't exist", "Invalid query"}}
+	if !reflect.DeepEqual(*responseErr, expected) {
+		t.Errorf("ResponseError = %+v, expected %+v", *responseErr, expected)
+	}
+	if err.Error() != expected.Error() {
+		t.Errorf("GraphQL.Query returned error %q, expected %q", err.Error(), expected.Error())
+	}
+}
+
+func TestGraphQLMutateWithUserErrors(t *testing.T) {
+	setup()
+	defer teardown()
+
+	httpmock.RegisterResponder(
+		"POST",
+		fmt.Sprintf("https://fooshop.myshopify.com/%s/graphql.json", client.pathPrefix),
+		httpmock.NewStringResponder(200, `{"data":{"productCreate":{"product":null,"userErrors":[
+			{"field":["input","title"],"message":"Title can't be blank","code":"BLANK"},
+			{"field":null,"message":"Too many products"}
+		]}}}`),
+	)
+
+	resp := struct {
+		ProductCreate struct {
+			Product *struct {
+				Id string `json:"id"`
+			} `json:"product"`
+		} `json:"productCreate"`
+	}{}
+	err := client.GraphQL.Mutate(context.Background(), "mutation { productCreate(input: {}) { product { id } userErrors { field message code } } }", nil, &resp)
+
+	var userErrs UserErrors
+	if !errors.As(err, &userErrs) {
+		t.Fatalf("GraphQL.Mutate returned error %v, expected UserErrors", err)
+	}
+	expected := UserErrors{
+		{Field: []string{"input", "title"}, Message: "Title can't be blank", Code: "BLANK"},
+		{Message: "Too many products"},
+	}
+	if !reflect.DeepEqual(userErrs, expected) {
+		t.Errorf("GraphQL.Mutate returned %+v, expected %+v", userErrs, expected)
+	}
+	if err.Error() != "input.title: Title can't be blank, Too many products" {
+		t.Errorf("GraphQL.Mutate returned error message %s", err.Error())
+	}
+}
+
+func TestGraphQLMutate(t *testing.T) {
+	setup()
+	defer teardown()
+
+	httpmock.RegisterResponder(
+		"POST",
+		fmt.Sprintf("https://fooshop.myshopify.com/%s/graphql.json", client.pathPrefix),
+		httpmock.NewStringResponder(200, `{"data":{"productCreate":{"product":{"id":"gid://shopify/Product/1"},"userErrors":[]}}}`),
+	)
+
+	resp := struct {
+		ProductCreate struct {
+			Product struct {
+				Id string `json:"id"`
+			} `json:"product"`
+		} `json:"productCreate"`
+	}{}
+	err := client.GraphQL.Mutate(context.Background(), "mutation { productCreate(input: {}) { product { id } userErrors { field message } } }", nil, &resp)
+	if err != nil {
+		t.Errorf("GraphQL.Mutate returned error: %v", err)
+	}
+	if resp.ProductCreate.Product.Id != "gid://shopify/Product/1" {
+		t.Errorf("GraphQL.Mutate decoded %+v", resp)
+	}
+}
@@ -0,0 +1,204 @@
+package goshopify
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultGraphQLQueryCost is the estimated cost of a query that has not
+	// been sent before
+	defaultGraphQLQueryCost = 50
+
+	// maxGraphQLCostEstimates bounds the number of queries whose cost is
+	// remembered, the least recently used ones are forgotten first
+	maxGraphQLCostEstimates = 1000
+)
+
+// GraphQLThrottler is used to schedule GraphQL queries by their cost before
+// they are sent, see WithGraphQLThrottler.
+type GraphQLThrottler interface {
+	// Wait blocks until the estimated cost of the query with its variables
+	// is available or the context is done, and returns the cost reserved for
+	// the query.
+	Wait(ctx context.Context, query string, variables interface{}) (int, error)
+
+	// Observe is called once the query completed with the cost it reserved
+	// and the cost reported by Shopify, which is nil if the response did not
+	// include one.
+	Observe(query string, variables interface{}, reserved int, cost *GraphQLCost)
+}
+
+// GraphQLCostThrottler is a client side implementation of the GraphQL
+// calculated query cost limit. Queries are admitted in the order they call
+// Wait once their estimated cost fits in the available points, so a single
+// GraphQLCostThrottler can be shared by all goroutines querying a shop.
+//
+// The estimated cost of a query is the requestedQueryCost Shopify reported
+// the last time the query was sent with the same variables, as they change
+// the cost of e.g. a page size. The costs of the 1000 most recently sent
+// queries are remembered. The bucket follows the throttleStatus
+// reported by Shopify, so the maximum and restore rate of the shop's plan are
+// picked up after the first response.
+type GraphQLCostThrottler struct {
+	mu          sync.Mutex
+	maximum     float64
+	restoreRate float64
+	available   float64
+	last        time.Time
+
+	// estimates maps the costKeys of the queries to their element in recent,
+	// which holds the costEstimates from the most to the least recently used
+	estimates map[string]*list.Element
+	recent    *list.List
+
+	// Internal testing use only.
+	now   func() time.Time
+	sleep func(context.Context, time.Duration) error
+}
+
+// NewGraphQLCostThrottler returns a throttler with the bucket of the standard
+// Shopify plans, 1000 points restoring at 50 points per second.
+func NewGraphQLCostThrottler() *GraphQLCostThrottler {
+	return &GraphQLCostThrottler{
+		maximum:     1000,
+		restoreRate: 50,
+		available:   1000,
+		estimates:   map[string]*list.Element{},
+		recent:      list.New(),
+		now:         time.Now,
+		sleep:       sleepContext,
+	}
+}
+
+// Wait reserves the estimated cost of the query, blocking until the points
+// are available.
+func (t *GraphQLCostThrottler) Wait(ctx context.Context, query string, variables interface{}) (int, error) {
+	key := costKey(query, variables)
+
+	t.mu.Lock()
+	t.restore()
+	cost := defaultGraphQLQueryCost
+	if e, ok := t.estimates[key]; ok {
+		t.recent.MoveToFront(e)
+		cost = e.Value.(*costEstimate).cost
+	}
+	if float64(cost) > t.maximum {
+		cost = int(t.maximum)
+	}
+	t.available -= float64(cost)
+	wait := time.Duration(-t.available / t.restoreRate * float64(time.Second))
+	t.mu.Unlock()
+
+	if wait <= 0 {
+		return cost, nil
+	}
+
+	err := t.sleep(ctx, wait)
+	if err != nil {
+		// give back the reservation, the query is not going to be sent
+		t.mu.Lock()
+		t.restore()
+		t.available += float64(cost)
+		t.mu.Unlock()
+		return 0, err
+	}
+
+	return cost, nil
+}
+
+// Observe refunds the difference between the reserved and actual cost of the
+// query and corrects the bucket with the throttle status reported by Shopify.
+func (t *GraphQLCostThrottler) Observe(query string, variables interface{}, reserved int, cost *GraphQLCost) {
+	key := costKey(query, variables)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.restore()
+
+	if cost == nil {
+		// nothing is known about what the query cost, assume it didn't run
+		t.available += float64(reserved)
+		return
+	}
+
+	t.estimate(key, cost.RequestedQueryCost)
+
+	// throttled queries don't run and report no actual cost
+	spent := 0
+	if cost.ActualQueryCost != nil {
+		spent = *cost.ActualQueryCost
+	}
+	t.available += float64(reserved - spent)
+
+	status := cost.ThrottleStatus
+	if status.MaximumAvailable > 0 {
+		t.maximum = status.MaximumAvailable
+	}
+	if status.RestoreRate > 0 {
+		t.restoreRate = status.RestoreRate
+	}
+
+	// points reserved by queries still in flight are not part of the
+	// reported status, so only ever drain the bucket down to what Shopify
+	// reports
+	if status.CurrentlyAvailable < t.available {
+		t.available = status.CurrentlyAvailable
+	}
+}
+
+// restore refills the bucket for the time elapsed since the last restore.
+// t.mu must be held.
+func (t *GraphQLCostThrottler) restore() {
+	now := t.now()
+	if !t.last.IsZero() {
+		t.available += now.Sub(t.last).Seconds() * t.restoreRate
+		if t.available > t.maximum {
+			t.available = t.maximum
+		}
+	}
+	t.last = now
+}
+
+// costKey identifies a query with its variables among the estimates, the
+// variables are hashed as they can be large, e.g. the input of a mutation
+func costKey(query string, variables interface{}) string {
+	if variables == nil {
+		return query
+	}
+
+	data, err := json.Marshal(variables)
+	if err != nil {
+		return query
+	}
+	sum := sha256.Sum256(data)
+	return query + "\x00" + string(sum[:])
+}
+
+// costEstimate is the estimated cost of a query
+type costEstimate struct {
+	key  string
+	cost int
+}
+
+// estimate records the estimated cost of the query, forgetting the least
+// recently used estimate if there are too many. t.mu must be held.
+func (t *GraphQLCostThrottler) estimate(key string, cost int) {
+	if e, ok := t.estimates[key]; ok {
+		e.Value.(*costEstimate).cost = cost
+		t.recent.MoveToFront(e)
+		return
+	}
+
+	t.estimates[key] = t.recent.PushFront(&costEstimate{key: key, cost: cost})
+	if t.recent.Len() > maxGraphQLCostEstimates {
+		oldest := t.recent.Back()
+		t.recent.Remove(oldest)
+		delete(t.estimates, oldest.Value.(*costEstimate).key)
+	}
+}
@@ -0,0 +1,237 @@
+package goshopify
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jarcoal/httpmock"
+)
+
+func newTestGraphQLCostThrottler() (*GraphQLCostThrottler, *fakeClock) {
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	throttler := NewGraphQLCostThrottler()
+	throttler.now = clock.Now
+	throttler.sleep = clock.Sleep
+	return throttler, clock
+}
+
+func TestGraphQLCostThrottlerWait(t *testing.T) {
+	throttler, clock := newTestGraphQLCostThrottler()
+
+	// learn the cost of the query
+	reserved, _ := throttler.Wait(context.Background(), "query", nil)
+	if reserved != defaultGraphQLQueryCost {
+		t.Errorf("GraphQLCostThrottler.Wait() reserved %d, expected %d", reserved, defaultGraphQLQueryCost)
+	}
+	throttler.Observe("query", nil, reserved, &GraphQLCost{
+		RequestedQueryCost: 400,
+		ActualQueryCost:    makeIntPointer(400),
+		ThrottleStatus: GraphQLThrottleStatus{
+			MaximumAvailable:   1000,
+			CurrentlyAvailable: 600,
+			RestoreRate:        50,
+		},
+	})
+
+	reserved, _ = throttler.Wait(context.Background(), "query", nil)
+	if reserved != 400 {
+		t.Errorf("GraphQLCostThrottler.Wait() reserved %d, expected 400", reserved)
+	}
+	if len(clock.slept) != 0 {
+		t.Errorf("GraphQLCostThrottler.Wait() slept %v, expected no wait", clock.slept)
+	}
+
+	// 200 points left, 200 missing restore in 4 seconds
+	_, _ = throttler.Wait(context.Background(), "query", nil)
+	expected := []time.Duration{4 * time.Second}
+	if fmt.Sprint(clock.slept) != fmt.Sprint(expected) {
+		t.Errorf("GraphQLCostThrottler.Wait() slept %v, expected %v", clock.slept, expected)
+	}
+}
+
+func TestGraphQLCostThrottlerVariables(t *testing.T) {
+	throttler, _ := newTestGraphQLCostThrottler()
+	query := "query products($first: Int!) { products(first: $first) { edges { node { id } } } }"
+
+	cost := func(requested int) *GraphQLCost {
+		return &GraphQLCost{
+			RequestedQueryCost: requested,
+			ActualQueryCost:    makeIntPointer(requested),
+			ThrottleStatus:     GraphQLThrottleStatus{MaximumAvailable: 1000, CurrentlyAvailable: 1000, RestoreRate: 50},
+		}
+	}
+	throttler.Observe(query, map[string]interface{}{"first": 250}, 0, cost(252))
+	throttler.Observe(query, map[string]interface{}{"first": 1}, 0, cost(3))
+
+	cases := []struct {
+		variables interface{}
+		expected  int
+	}{
+		{map[string]interface{}{"first": 250}, 252},
+		{map[string]interface{}{"first": 1}, 3},
+		{map[string]interface{}{"first": 10}, defaultGraphQLQueryCost},
+		{nil, defaultGraphQLQueryCost},
+	}
+	for _, c := range cases {
+		if reserved, _ := throttler.Wait(context.Background(), query, c.variables); reserved != c.expected {
+			t.Errorf("GraphQLCostThrottler.Wait() with variables %v reserved %d, expected %d", c.variables, reserved, c.expected)
+		}
+	}
+}
+
+func TestGraphQLCostThrottlerObserve(t *testing.T) {
+	cases := []struct {
+		description string
+		cost        *GraphQLCost
+		expected    float64
+	}{
+		{
+			description: "no cost refunds the reservation",
+			cost:        nil,
+			expected:    1000,
+		},
+		{
+			description: "refunds the difference to the actual cost",
+			cost: &GraphQLCost{
+				RequestedQueryCost: 100,
+				ActualQueryCost:    makeIntPointer(10),
+				ThrottleStatus:     GraphQLThrottleStatus{MaximumAvailable: 1000, CurrentlyAvailable: 990, RestoreRate: 50},
+			},
+			expected: 990,
+		},
+		{
+			description: "throttled queries are refunded",
+			cost: &GraphQLCost{
+				RequestedQueryCost: 100,
+				ThrottleStatus:     GraphQLThrottleStatus{MaximumAvailable: 1000, CurrentlyAvailable: 1000, RestoreRate: 50},
+			},
+			expected: 1000,
+		},
+		{
+			description: "follows the status reported by shopify",
+			cost: &GraphQLCost{
+				RequestedQueryCost: 100,
+				ActualQueryCost:    makeIntPointer(10),
+				ThrottleStatus:     GraphQLThrottleStatus{MaximumAvailable: 2000, CurrentlyAvailable: 500, RestoreRate: 100},
+			},
+			expected: 500,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			throttler, _ := newTestGraphQLCostThrottler()
+			reserved, _ := throttler.Wait(context.Background(), "query", nil)
+			throttler.Observe("query", nil, reserved, c.cost)
+
+			if throttler.available != c.expected {
+				t.Errorf("GraphQLCostThrottler available = %v, expected %v", throttler.available, c.expected)
+			}
+		})
+	}
+}
+
+func TestGraphQLCostThrottlerWaitCancelled(t *testing.T) {
+	throttler, _ := newTestGraphQLCostThrottler()
+	throttler.available = 0
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := throttler.Wait(ctx, "query", nil); err != context.Canceled {
+		t.Errorf("GraphQLCostThrottler.Wait() returned %v, expected %v", err, context.Canceled)
+	}
+
+	if throttler.available != 0 {
+		t.Errorf("GraphQLCostThrottler available = %v, expected the cancelled reservation to be returned", throttler.available)
+	}
+}
+
+func TestGraphQLCostThrottlerConcurrent(t *testing.T) {
+	throttler, clock := newTestGraphQLCostThrottler()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 40; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _ = throttler.Wait(context.Background(), "query", nil)
+		}()
+	}
+	wg.Wait()
+
+	// 1000 points fit 20 queries of the default cost, the others wait
+	if len(clock.slept) != 20 {
+		t.Errorf("GraphQLCostThrottler.Wait() slept %d times, expected 20", len(clock.slept))
+	}
+}
+
+func TestWithGraphQLThrottler(t *testing.T) {
+	throttler, clock := newTestGraphQLCostThrottler()
+	c := MustNewClient(app, "fooshop", "abcd", WithVersion(testApiVersion), WithGraphQLThrottler(throttler))
+	httpmock.ActivateNonDefault(c.Client)
+	defer httpmock.DeactivateAndReset()
+
+	httpmock.RegisterResponder("POST", fmt.Sprintf("https://fooshop.myshopify.com/%s/graphql.json", c.pathPrefix),
+		func(req *http.Request) (*http.Response, error) {
+			return httpmock.NewStringResponse(http.StatusOK, `{
+				"data": {"foo": "bar"},
+				"extensions": {
+					"cost": {
+						"requestedQueryCost": 600,
+						"actualQueryCost": 600,
+						"throttleStatus": {"maximumAvailable": 1000, "currentlyAvailable": 400, "restoreRate": 50}
+					}
+				}
+			}`), nil
+		})
+
+	for i := 0; i < 2; i++ {
+		err := c.GraphQL.Query(context.Background(), "query", nil, nil)
+		if err != nil {
+			t.Fatalf("GraphQL.Query returned error: %v", err)
+		}
+	}
+
+	// the second query is estimated at 600 points with only 400 available
+	expected := []time.Duration{4 * time.Second}
+	if fmt.Sprint(clock.slept) != fmt.Sprint(expected) {
+		t.Errorf("GraphQL.Query waited %v, expected %v", clock.slept, expected)
+	}
+}
+
+func TestGraphQLCostThrottlerEstimatesBounded(t *testing.T) {
+	throttler, _ := newTestGraphQLCostThrottler()
+
+	cost := func(requested int) *GraphQLCost {
+		return &GraphQLCost{
+			RequestedQueryCost: requested,
+			ActualQueryCost:    makeIntPointer(requested),
+			ThrottleStatus:     GraphQLThrottleStatus{MaximumAvailable: 1000, CurrentlyAvailable: 1000, RestoreRate: 50},
+		}
+	}
+
+	throttler.Observe("first", nil, 0, cost(10))
+	for i := 0; i < maxGraphQLCostEstimates; i++ {
+		// using the first query keeps it from being forgotten
+		if i == maxGraphQLCostEstimates/2 {
+			throttler.Wait(context.Background(), "first", nil)
+			throttler.Observe("first", nil, 10, cost(10))
+		}
+		throttler.Observe(fmt.Sprintf("query %d", i), nil, 0, cost(20))
+	}
+
+	if n := len(throttler.estimates); n != maxGraphQLCostEstimates {
+		t.Errorf("GraphQLCostThrottler remembered %d estimates, expected %d", n, maxGraphQLCostEstimates)
+	}
+	if reserved, _ := throttler.Wait(context.Background(), "first", nil); reserved != 10 {
+		t.Errorf("GraphQLCostThrottler.Wait() reserved %d for a recently used query, expected 10", reserved)
+	}
+	if reserved, _ := throttler.Wait(context.Background(), "query 0", nil); reserved != defaultGraphQLQueryCost {
+		t.Errorf("GraphQLCostThrottler.Wait() reserved %d for a forgotten query, expected %d", reserved, defaultGraphQLQueryCost)
+	}
+}
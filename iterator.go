@@ -0,0 +1,156 @@
+package goshopify
+
+import (
+	"context"
+)
+
+// pageFetcher retrieves a single page of a cursor paginated listing. It has
+// the same shape as the ListWithPagination methods of the services.
+type pageFetcher[T any] func(ctx context.Context, options interface{}) ([]T, *Pagination, error)
+
+// PageIterator lazily walks the pages of a cursor paginated REST listing.
+// Each call to Next fetches exactly one page using Client.ListWithPagination,
+// so only a single page is held in memory at any time.
+//
+//	pages := client.Order.Pages(options)
+//	for pages.Next(ctx) {
+//		for _, order := range pages.Page() {
+//			...
+//		}
+//	}
+//	if err := pages.Err(); err != nil {
+//		...
+//	}
+type PageIterator[T any] struct {
+	fetch      pageFetcher[T]
+	options    interface{}
+	page       []T
+	pagination *Pagination
+	done       bool
+	err        error
+}
+
+func newPageIterator[T any](fetch pageFetcher[T], options interface{}) *PageIterator[T] {
+	return &PageIterator[T]{
+		fetch:   fetch,
+		options: options,
+	}
+}
+
+// Next fetches the next page. It returns false once there are no more pages
+// or an error occurred, in which case Err returns the error.
+func (it *PageIterator[T]) Next(ctx context.Context) bool {
+	if it.done {
+		return false
+	}
+
+	if err := ctx.Err(); err != nil {
+		it.err = err
+		it.done = true
+		return false
+	}
+
+	page, pagination, err := it.fetch(ctx, it.options)
+	if err != nil {
+		it.err = err
+		it.page = nil
+		it.done = true
+		return false
+	}
+
+	it.page = page
+	it.pagination = pagination
+
+	if pagination == nil || pagination.NextPageOptions == nil {
+		it.done = true
+	} else {
+		it.options = pagination.NextPageOptions
+	}
+
+	return true
+}
+
+// Page returns the entities of the page fetched by the last call to Next.
+func (it *PageIterator[T]) Page() []T {
+	return it.page
+}
+
+// Pagination returns the pagination of the page fetched by the last call to
+// Next. Its NextPageOptions can be stored to resume the listing later on.
+func (it *PageIterator[T]) Pagination() *Pagination {
+	return it.pagination
+}
+
+// Err returns the first error encountered while iterating.
+func (it *PageIterator[T]) Err() error {
+	return it.err
+}
+
+// ListIterator lazily walks the entities of a cursor paginated REST listing
+// one at a time, fetching a new page whenever the current one is exhausted.
+//
+//	orders := client.Order.Iter(options)
+//	for orders.Next(ctx) {
+//		order := orders.Value()
+//		...
+//	}
+//	if err := orders.Err(); err != nil {
+//		...
+//	}
+type ListIterator[T any] struct {
+	pages *PageIterator[T]
+	index int
+	value T
+}
+
+func newListIterator[T any](fetch pageFetcher[T], options interface{}) *ListIterator[T] {
+	return &ListIterator[T]{
+		pages: newPageIterator(fetch, options),
+	}
+}
+
+// Next advances to the next entity, fetching the next page if required. It
+// returns false once the listing is exhausted or an error occurred, in which
+// case Err returns the error.
+func (it *ListIterator[T]) Next(ctx context.Context) bool {
+	for it.index >= len(it.pages.Page()) {
+		if !it.pages.Next(ctx) {
+			var zero T
+			it.value = zero
+			return false
+		}
+		it.index = 0
+	}
+
+	it.value = it.pages.Page()[it.index]
+	it.index++
+	return true
+}
+
+// Value returns the entity the iterator currently points at.
+func (it *ListIterator[T]) Value() T {
+	return it.value
+}
+
+// Pagination returns the pagination of the page the current entity belongs to.
+func (it *ListIterator[T]) Pagination() *Pagination {
+	return it.pages.Pagination()
+}
+
+// Err returns the first error encountered while iterating.
+func (it *ListIterator[T]) Err() error {
+	return it.pages.Err()
+}
+
+// listAll collects every entity of a paginated listing. Entities fetched
+// before an error occurred are returned alongside the error.
+func listAll[T any](ctx context.Context, fetch pageFetcher[T], options interface{}) ([]T, error) {
+	collector := []T{}
+
+	pages := newPageIterator(fetch, options)
+	for pages.Next(ctx) {
+		collector = append(collector, pages.Page()...)
+	}
+
+	return collector, pages.Err()
+}
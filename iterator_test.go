@@ -0,0 +1,230 @@
+package goshopify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/jarcoal/httpmock"
+)
+
+func registerOrderPages() {
+	listURL := fmt.Sprintf("https://fooshop.myshopify.com/%s/orders.json", client.pathPrefix)
+
+	pages := []struct {
+		query string
+		link  string
+		body  string
+	}{
+		{"", `<http://valid.url?page_info=pg2>; rel="next"`, `{"orders": [{"id":1},{"id":2}]}`},
+		{"page_info=pg2", `<http://valid.url?page_info=pg1>; rel="previous", <http://valid.url?page_info=pg3>; rel="next"`, `{"orders": [{"id":3}]}`},
+		{"page_info=pg3", `<http://valid.url?page_info=pg2>; rel="previous"`, `{"orders": [{"id":4},{"id":5}]}`},
+	}
+
+	for _, p := range pages {
+		p := p
+		u := listURL
+		if p.query != "" {
+			u = fmt.Sprintf("%s?%s", listURL, p.query)
+		}
+		httpmock.RegisterResponder("GET", u, func(req *http.Request) (*http.Response, error) {
+			resp := httpmock.NewStringResponse(http.StatusOK, p.body)
+			resp.Header.Add("Link", p.link)
+			return resp, nil
+		})
+	}
+}
+
+func TestPageIterator(t *testing.T) {
+	setup()
+	defer teardown()
+
+	registerOrderPages()
+
+	var pages [][]uint64
+	it := client.Order.Pages(nil)
+	for it.Next(context.Background()) {
+		var ids []uint64
+		for _, o := range it.Page() {
+			ids = append(ids, o.Id)
+		}
+		pages = append(pages, ids)
+	}
+
+	if err := it.Err(); err != nil {
+		t.Fatalf("PageIterator.Err() returned error: %v", err)
+	}
+
+	expected := [][]uint64{{1, 2}, {3}, {4, 5}}
+	if !reflect.DeepEqual(pages, expected) {
+		t.Errorf("PageIterator pages = %v, expected %v", pages, expected)
+	}
+
+	if it.Next(context.Background()) {
+		t.Errorf("PageIterator.Next() returned true after the last page")
+	}
+
+	if calls := httpmock.GetTotalCallCount(); calls != 3 {
+		t.Errorf("PageIterator made %d requests, expected 3", calls)
+	}
+}
+
+func TestListIterator(t *testing.T) {
+	setup()
+	defer teardown()
+
+	registerOrderPages()
+
+	var ids []uint64
+	it := client.Order.Iter(nil)
+	for it.Next(context.Background()) {
+		ids = append(ids, it.Value().Id)
+	}
+
+	if err := it.Err(); err != nil {
+		t.Fatalf("ListIterator.Err() returned error: %v", err)
+	}
+
+	expected := []uint64{1, 2, 3, 4, 5}
+	if !reflect.DeepEqual(ids, expected) {
+		t.Errorf("ListIterator ids = %v, expected %v", ids, expected)
+	}
+}
+
+func TestListIteratorStopEarly(t *testing.T) {
+	setup()
+	defer teardown()
+
+	registerOrderPages()
+
+	it := client.Order.Iter(nil)
+	for i := 0; i < 3 && it.Next(context.Background()); i++ {
+	}
+
+	if it.Value().Id != 3 {
+		t.Errorf("ListIterator.Value().Id = %d, expected 3", it.Value().Id)
+	}
+
+	next := it.Pagination().NextPageOptions
+	if next == nil || next.PageInfo != "pg3" {
+		t.Errorf("ListIterator.Pagination().NextPageOptions = %#v, expected page_info pg3", next)
+	}
+
+	if calls := httpmock.GetTotalCallCount(); calls != 2 {
+		t.Errorf("ListIterator made %d requests, expected 2", calls)
+	}
+}
+
+func TestListIteratorError(t *testing.T) {
+	setup()
+	defer teardown()
+
+	listURL := fmt.Sprintf("https://fooshop.myshopify.com/%s/orders.json", client.pathPrefix)
+	httpmock.RegisterResponder("GET", listURL, func(req *http.Request) (*http.Response, error) {
+		resp := httpmock.NewStringResponse(http.StatusOK, `{"orders": [{"id":1}]}`)
+		resp.Header.Add("Link", `<http://valid.url?paage_info=pg2>; rel="next"`)
+		return resp, nil
+	})
+
+	it := client.Order.Iter(nil)
+	if it.Next(context.Background()) {
+		t.Errorf("ListIterator.Next() returned true, expected false")
+	}
+
+	expected := errors.New("page_info is missing")
+	if it.Err() == nil || it.Err().Error() != expected.Error() {
+		t.Errorf("ListIterator.Err() = %v, expected %v", it.Err(), expected)
+	}
+}
+
+func TestListIteratorContextCancelled(t *testing.T) {
+	setup()
+	defer teardown()
+
+	registerOrderPages()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	it := client.Order.Iter(nil)
+	if it.Next(ctx) {
+		t.Errorf("ListIterator.Next() returned true, expected false")
+	}
+
+	if !errors.Is(it.Err(), context.Canceled) {
+		t.Errorf("ListIterator.Err() = %v, expected %v", it.Err(), context.Canceled)
+	}
+
+	if calls := httpmock.GetTotalCallCount(); calls != 0 {
+		t.Errorf("ListIterator made %d requests, expected 0", calls)
+	}
+}
+
+func TestOrderRiskIter(t *testing.T) {
+	setup()
+	defer teardown()
+
+	httpmock.RegisterResponder("GET", fmt.Sprintf("https://fooshop.myshopify.com/%s/orders/450789469/risks.json", client.pathPrefix),
+		httpmock.NewStringResponder(200, `{"risks": [{"id":1},{"id":2}]}`))
+
+	var ids []uint64
+	it := client.OrderRisk.Iter(450789469, nil)
+	for it.Next(context.Background()) {
+		ids = append(ids, it.Value().Id)
+	}
+
+	if err := it.Err(); err != nil {
+		t.Fatalf("OrderRisk.Iter returned error: %v", err)
+	}
+
+	expected := []uint64{1, 2}
+	if !reflect.DeepEqual(ids, expected) {
+		t.Errorf("OrderRisk.Iter ids = %v, expected %v", ids, expected)
+	}
+}
+
+func TestWebhookIter(t *testing.T) {
+	setup()
+	defer teardown()
+
+	listURL := fmt.Sprintf("https://fooshop.myshopify.com/%s/webhooks.json", client.pathPrefix)
+	httpmock.RegisterResponder("GET", listURL, func(req *http.Request) (*http.Response, error) {
+		resp := httpmock.NewStringResponse(http.StatusOK, `{"webhooks": [{"id":1},{"id":2}]}`)
+		resp.Header.Add("Link", `<http://valid.url?page_info=pg2>; rel="next"`)
+		return resp, nil
+	})
+	httpmock.RegisterResponder("GET", listURL+"?page_info=pg2", func(req *http.Request) (*http.Response, error) {
+		resp := httpmock.NewStringResponse(http.StatusOK, `{"webhooks": [{"id":3}]}`)
+		resp.Header.Add("Link", `<http://valid.url?page_info=pg1>; rel="previous"`)
+		return resp, nil
+	})
+
+	pages := 0
+	pageIt := client.Webhook.Pages(nil)
+	for pageIt.Next(context.Background()) {
+		pages++
+	}
+	if err := pageIt.Err(); err != nil {
+		t.Fatalf("Webhook.Pages returned error: %v", err)
+	}
+	if pages != 2 {
+		t.Errorf("Webhook.Pages walked %d pages, expected 2", pages)
+	}
+
+	var ids []uint64
+	it := client.Webhook.Iter(nil)
+	for it.Next(context.Background()) {
+		ids = append(ids, it.Value().Id)
+	}
+	if err := it.Err(); err != nil {
+		t.Fatalf("Webhook.Iter returned error: %v", err)
+	}
+
+	expected := []uint64{1, 2, 3}
+	if !reflect.DeepEqual(ids, expected) {
+		t.Errorf("Webhook.Iter ids = %v, expected %v", ids, expected)
+	}
+}
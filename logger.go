@@ -1,8 +1,10 @@
 package goshopify
 
 import (
+	"context"
 	"fmt"
 	"io"
+	"log/slog"
 	"os"
 )
 
@@ -83,3 +85,46 @@ func (l *LeveledLogger) stdout() io.Writer {
 
 	return os.Stdout
 }
+
+// SlogLogger adapts a *slog.Logger to LeveledLoggerInterface. API calls are
+// logged as structured records with the method, path, status, request id and
+// duration as attributes, see WithSlogLogger.
+type SlogLogger struct {
+	Logger *slog.Logger
+}
+
+// NewSlogLogger returns a SlogLogger writing to logger, or to slog.Default()
+// if logger is nil.
+func NewSlogLogger(logger *slog.Logger) *SlogLogger {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &SlogLogger{Logger: logger}
+}
+
+// Debugf logs a debug message using Printf conventions.
+func (l *SlogLogger) Debugf(format string, v ...interface{}) {
+	l.logf(slog.LevelDebug, format, v...)
+}
+
+// Errorf logs an error message using Printf conventions.
+func (l *SlogLogger) Errorf(format string, v ...interface{}) {
+	l.logf(slog.LevelError, format, v...)
+}
+
+// Infof logs an informational message using Printf conventions.
+func (l *SlogLogger) Infof(format string, v ...interface{}) {
+	l.logf(slog.LevelInfo, format, v...)
+}
+
+// Warnf logs a warning message using Printf conventions.
+func (l *SlogLogger) Warnf(format string, v ...interface{}) {
+	l.logf(slog.LevelWarn, format, v...)
+}
+
+func (l *SlogLogger) logf(level slog.Level, format string, v ...interface{}) {
+	ctx := context.Background()
+	if l.Logger.Enabled(ctx, level) {
+		l.Logger.Log(ctx, level, fmt.Sprintf(format, v...))
+	}
+}
@@ -2,12 +2,18 @@ package goshopify
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
 	"io/ioutil"
+	"log/slog"
 	"net/http"
 	"net/url"
 	"os"
 	"strings"
 	"testing"
+
+	"github.com/jarcoal/httpmock"
 )
 
 func TestLeveledLogger(t *testing.T) {
@@ -107,19 +113,205 @@ func TestDoGetHeadersDebug(t *testing.T) {
 	err.Reset()
 	out.Reset()
 
-	client.logResponse(nil)
+	client.logResponse(nil, nil, nil, 0)
 	if out.String() != "" {
 		t.Errorf("logResponse expected empty log output received \"%s\"", out.String())
 	}
 
-	client.logResponse(&http.Response{
+	client.logResponse(nil, &http.Response{
 		Status:     http.StatusText(http.StatusOK),
 		StatusCode: http.StatusOK,
 		Header:     map[string][]string{"X-Request-Id": {"00000000-0000-0000-0000-000000000000"}},
 		Body:       ioutil.NopCloser(strings.NewReader("response body")),
-	})
+	}, nil, 0)
 
 	if out.String() != resExpected {
 		t.Errorf("doGetHeadersDebug expected stdout \"%s\" received \"%s\"", resExpected, out.String())
 	}
 }
+
+func TestSlogLogger(t *testing.T) {
+	buf := &bytes.Buffer{}
+	log := NewSlogLogger(slog.New(slog.NewTextHandler(buf, &slog.HandlerOptions{Level: slog.LevelInfo})))
+
+	log.Debugf("debug %s", "log")
+	log.Infof("info %s", "log")
+	log.Warnf("warn %s", "log")
+	log.Errorf("error %s", "log")
+
+	for _, expected := range []string{`level=INFO msg="info log"`, `level=WARN msg="warn log"`, `level=ERROR msg="error log"`} {
+		if !strings.Contains(buf.String(), expected) {
+			t.Errorf("slog logger output %q does not contain %q", buf.String(), expected)
+		}
+	}
+	if strings.Contains(buf.String(), "debug log") {
+		t.Errorf("slog logger output %q contains a debug message below the handler level", buf.String())
+	}
+}
+
+func TestWithSlogLogger(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := slog.New(slog.NewJSONHandler(buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	c := MustNewClient(app, "fooshop", "abcd", WithVersion(testApiVersion), WithSlogLogger(logger))
+	httpmock.ActivateNonDefault(c.Client)
+	defer httpmock.DeactivateAndReset()
+
+	httpmock.RegisterResponder("POST", fmt.Sprintf("https://fooshop.myshopify.com/%s/customers.json", c.pathPrefix),
+		func(req *http.Request) (*http.Response, error) {
+			resp := httpmock.NewStringResponse(http.StatusCreated, `{"customer": {"id": 1, "email": "jon@example.com"}}`)
+			resp.Header.Set(requestIdHeader, "abc-123")
+			return resp, nil
+		})
+
+	_, err := c.Customer.Create(context.Background(), Customer{Email: "jon@example.com", Phone: "+15550100100"})
+	if err != nil {
+		t.Fatalf("Customer.Create returned error: %v", err)
+	}
+
+	if strings.Contains(buf.String(), "jon@example.com") || strings.Contains(buf.String(), "+15550100100") {
+		t.Errorf("slog output contains customer details: %s", buf.String())
+	}
+
+	var records []map[string]interface{}
+	decoder := json.NewDecoder(buf)
+	for decoder.More() {
+		var record map[string]interface{}
+		if err := decoder.Decode(&record); err != nil {
+			t.Fatalf("slog output is not json: %v", err)
+		}
+		records = append(records, record)
+	}
+
+	if len(records) != 2 {
+		t.Fatalf("slog logged %d records, expected 2: %v", len(records), records)
+	}
+
+	request := records[0]
+	if request["msg"] != "shopify request" || request["method"] != "POST" || request["body"] == nil {
+		t.Errorf("request record = %v, expected a POST with its body", request)
+	}
+
+	response := records[1]
+	expected := map[string]interface{}{
+		"level":      "DEBUG",
+		"msg":        "shopify response",
+		"method":     "POST",
+		"path":       fmt.Sprintf("/%s/customers.json", c.pathPrefix),
+		"status":     float64(http.StatusCreated),
+		"request_id": "abc-123",
+	}
+	for key, value := range expected {
+		if response[key] != value {
+			t.Errorf("response record %s = %v, expected %v", key, response[key], value)
+		}
+	}
+	if _, ok := response["duration"]; !ok {
+		t.Errorf("response record %v does not have a duration", response)
+	}
+}
+
+func TestWithSlogLoggerError(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := slog.New(slog.NewTextHandler(buf, &slog.HandlerOptions{Level: slog.LevelWarn}))
+
+	c := MustNewClient(app, "fooshop", "abcd", WithVersion(testApiVersion), WithSlogLogger(logger))
+	httpmock.ActivateNonDefault(c.Client)
+	defer httpmock.DeactivateAndReset()
+
+	httpmock.RegisterResponder("GET", fmt.Sprintf("https://fooshop.myshopify.com/%s/products/1.json", c.pathPrefix),
+		httpmock.NewStringResponder(http.StatusNotFound, `{"errors": "Not Found"}`))
+
+	_, err := c.Product.Get(context.Background(), 1, nil)
+	if err == nil {
+		t.Fatalf("Product.Get should have returned an error")
+	}
+
+	if !strings.Contains(buf.String(), `level=WARN msg="shopify response"`) || !strings.Contains(buf.String(), "status=404") {
+		t.Errorf("slog output %q does not contain the failed response", buf.String())
+	}
+	if strings.Contains(buf.String(), "body=") {
+		t.Errorf("slog output %q contains a body above debug level", buf.String())
+	}
+}
+
+func TestDebugLoggerRedactsOAuthSecret(t *testing.T) {
+	out := &bytes.Buffer{}
+	logger := &LeveledLogger{Level: LevelDebug, stdoutOverride: out, stderrOverride: &bytes.Buffer{}}
+
+	oauthApp := App{ApiKey: "apikey", ApiSecret: "hush"}
+	c := MustNewClient(oauthApp, "fooshop", "", WithLogger(logger))
+	httpmock.ActivateNonDefault(c.Client)
+	defer httpmock.DeactivateAndReset()
+
+	httpmock.RegisterResponder("POST", "https://fooshop.myshopify.com/admin/oauth/access_token",
+		httpmock.NewStringResponder(http.StatusOK, `{"access_token":"footoken"}`))
+
+	oauthApp.Client = c
+	token, err := oauthApp.GetAccessToken(context.Background(), "fooshop", "code")
+	if err != nil || token != "footoken" {
+		t.Fatalf("GetAccessToken returned %s, %v", token, err)
+	}
+
+	if strings.Contains(out.String(), oauthApp.ApiSecret) || strings.Contains(out.String(), "footoken") {
+		t.Errorf("debug output contains secrets: %s", out.String())
+	}
+	if !strings.Contains(out.String(), `"client_secret":"[REDACTED]"`) {
+		t.Errorf("debug output %q does not contain the redacted request", out.String())
+	}
+}
+
+// recordingLogger records the debug messages without formatting them
+type recordingLogger struct {
+	LeveledLogger
+	args [][]interface{}
+}
+
+func (l *recordingLogger) Debugf(format string, v ...interface{}) {
+	l.args = append(l.args, v)
+}
+
+func TestLogBelowDebugLevel(t *testing.T) {
+	loggers := map[string]LeveledLoggerInterface{
+		"LeveledLogger": &LeveledLogger{Level: LevelInfo},
+		"SlogLogger":    NewSlogLogger(slog.New(slog.NewTextHandler(&bytes.Buffer{}, &slog.HandlerOptions{Level: slog.LevelInfo}))),
+	}
+
+	for name, logger := range loggers {
+		c := MustNewClient(app, "fooshop", "abcd", WithLogger(logger))
+
+		body := ioutil.NopCloser(strings.NewReader(`{"password":"secret"}`))
+		req := &http.Request{Method: "POST", URL: &url.URL{Path: "/foo"}, Body: body}
+		c.logRequest(req)
+
+		res := &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: body}
+		c.logResponse(req, res, nil, 0)
+
+		// the bodies are neither read nor redacted
+		if req.Body != body || res.Body != body {
+			t.Errorf("%s below debug level read the bodies", name)
+		}
+	}
+}
+
+func TestLogBodyRedactedLazily(t *testing.T) {
+	logger := &recordingLogger{}
+	c := MustNewClient(app, "fooshop", "abcd", WithLogger(logger))
+
+	c.logRequest(&http.Request{
+		Method: "POST",
+		URL:    &url.URL{Path: "/foo"},
+		Body:   ioutil.NopCloser(strings.NewReader(`{"password":"secret"}`)),
+	})
+
+	if len(logger.args) != 2 {
+		t.Fatalf("logRequest logged %d messages, expected 2", len(logger.args))
+	}
+	body := logger.args[1][0]
+	if _, ok := body.(string); ok {
+		t.Errorf("logRequest formatted the body before it was logged")
+	}
+	if s := fmt.Sprint(body); s != `{"password":"[REDACTED]"}` {
+		t.Errorf("logged body = %s, expected it redacted", s)
+	}
+}
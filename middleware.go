@@ -0,0 +1,36 @@
+package goshopify
+
+import (
+	"net/http"
+)
+
+// Handler performs a single API call, including its retries, and returns the
+// metadata of the call. The response body has already been decoded when it
+// returns. See Middleware.
+type Handler func(req *http.Request) (*Response, error)
+
+// Middleware wraps the Handler performing API calls, see WithMiddleware.
+//
+// Unlike an http.RoundTripper, a middleware is called once per API call
+// rather than once per attempt, after the rate limiter, retries and response
+// decoding are accounted for. It can modify the request before calling next,
+// inspect the returned Response and error, or return without calling next to
+// short-circuit the call.
+//
+//	func Audit(next goshopify.Handler) goshopify.Handler {
+//		return func(req *http.Request) (*goshopify.Response, error) {
+//			res, err := next(req)
+//			log.Println(req.Method, req.URL.Path, res.StatusCode, res.RequestId, err)
+//			return res, err
+//		}
+//	}
+type Middleware func(next Handler) Handler
+
+// chain wraps handler in the middleware of the client, the first registered
+// middleware being the outermost.
+func (c *Client) chain(handler Handler) Handler {
+	for i := len(c.middleware) - 1; i >= 0; i-- {
+		handler = c.middleware[i](handler)
+	}
+	return handler
+}
@@ -0,0 +1,128 @@
+package goshopify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/jarcoal/httpmock"
+)
+
+func newMiddlewareTestClient(middleware ...Middleware) *Client {
+	c := MustNewClient(app, "fooshop", "abcd", WithVersion(testApiVersion), WithRetry(maxRetries), WithMiddleware(middleware...))
+	httpmock.ActivateNonDefault(c.Client)
+	return c
+}
+
+func TestWithMiddlewareOrder(t *testing.T) {
+	var calls []string
+	record := func(name string) Middleware {
+		return func(next Handler) Handler {
+			return func(req *http.Request) (*Response, error) {
+				calls = append(calls, name+" before")
+				res, err := next(req)
+				calls = append(calls, name+" after")
+				return res, err
+			}
+		}
+	}
+
+	c := newMiddlewareTestClient(record("first"), record("second"))
+	defer httpmock.DeactivateAndReset()
+	WithMiddleware(record("third"))(c)
+
+	httpmock.RegisterResponder("GET", fmt.Sprintf("https://fooshop.myshopify.com/%s/products/1.json", c.pathPrefix),
+		func(req *http.Request) (*http.Response, error) {
+			calls = append(calls, "request")
+			return httpmock.NewStringResponse(http.StatusOK, `{"product": {"id": 1}}`), nil
+		})
+
+	product, err := c.Product.Get(context.Background(), 1, nil)
+	if err != nil {
+		t.Fatalf("Product.Get returned error: %v", err)
+	}
+	if product.Id != 1 {
+		t.Errorf("Product.Get returned %#v, expected product 1", product)
+	}
+
+	expected := []string{"first before", "second before", "third before", "request", "third after", "second after", "first after"}
+	if !reflect.DeepEqual(calls, expected) {
+		t.Errorf("middleware called as %v, expected %v", calls, expected)
+	}
+}
+
+func TestWithMiddlewareModifiesRequest(t *testing.T) {
+	c := newMiddlewareTestClient(func(next Handler) Handler {
+		return func(req *http.Request) (*Response, error) {
+			req.Header.Set("X-Audit-Id", "audit-1")
+			return next(req)
+		}
+	})
+	defer httpmock.DeactivateAndReset()
+
+	httpmock.RegisterResponder("GET", fmt.Sprintf("https://fooshop.myshopify.com/%s/products/1.json", c.pathPrefix),
+		func(req *http.Request) (*http.Response, error) {
+			if req.Header.Get("X-Audit-Id") != "audit-1" {
+				return httpmock.NewStringResponse(http.StatusBadRequest, `{"errors": "missing audit id"}`), nil
+			}
+			return httpmock.NewStringResponse(http.StatusOK, `{"product": {"id": 1}}`), nil
+		})
+
+	_, err := c.Product.Get(context.Background(), 1, nil)
+	if err != nil {
+		t.Errorf("Product.Get returned error: %v", err)
+	}
+}
+
+func TestWithMiddlewareSeesRetries(t *testing.T) {
+	calls := 0
+	var seen *Response
+	c := newMiddlewareTestClient(func(next Handler) Handler {
+		return func(req *http.Request) (*Response, error) {
+			calls++
+			res, err := next(req)
+			seen = res
+			return res, err
+		}
+	})
+	defer httpmock.DeactivateAndReset()
+
+	httpmock.RegisterResponder("GET", fmt.Sprintf("https://fooshop.myshopify.com/%s/products/1.json", c.pathPrefix),
+		httpmock.NewStringResponder(http.StatusServiceUnavailable, ""))
+
+	_, err := c.Product.Get(context.Background(), 1, nil)
+	if err == nil {
+		t.Fatalf("Product.Get should have returned an error")
+	}
+	if calls != 1 {
+		t.Errorf("middleware called %d times, expected 1", calls)
+	}
+	if seen == nil || seen.Attempts != maxRetries || seen.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("middleware saw %#v, expected %d attempts ending in 503", seen, maxRetries)
+	}
+}
+
+func TestWithMiddlewareShortCircuit(t *testing.T) {
+	errOpen := errors.New("circuit open")
+	c := newMiddlewareTestClient(func(next Handler) Handler {
+		return func(req *http.Request) (*Response, error) {
+			return nil, errOpen
+		}
+	})
+	defer httpmock.DeactivateAndReset()
+
+	var resp Response
+	_, err := c.Product.Get(WithResponseCapture(context.Background(), &resp), 1, nil)
+	if !errors.Is(err, errOpen) {
+		t.Errorf("Product.Get returned %v, expected %v", err, errOpen)
+	}
+	if httpmock.GetTotalCallCount() != 0 {
+		t.Errorf("Product.Get made %d requests, expected none", httpmock.GetTotalCallCount())
+	}
+	if resp.Attempts != 0 {
+		t.Errorf("Response.Attempts = %d, expected 0", resp.Attempts)
+	}
+}
@@ -14,17 +14,38 @@ import (
 	"net/url"
 	"sort"
 	"strings"
+	"time"
 )
 
 const shopifyChecksumHeader = "X-Shopify-Hmac-Sha256"
 
 var accessTokenRelPath = "admin/oauth/access_token"
 
+// AuthorizeOption customizes the authorization url, see App.AuthorizeUrl
+type AuthorizeOption func(query url.Values)
+
+// WithPerUserGrant requests an online access token, tied to the user who
+// authorizes the app and expiring with their session, instead of an offline
+// access token for the shop.
+// See: https://shopify.dev/docs/apps/auth/access-token-types/online
+func WithPerUserGrant() AuthorizeOption {
+	return func(query url.Values) {
+		query.Set("grant_options[]", "per-user")
+	}
+}
+
+// WithScopes requests scopes instead of the Scope of the app.
+func WithScopes(scopes ScopeSet) AuthorizeOption {
+	return func(query url.Values) {
+		query.Set("scope", scopes.String())
+	}
+}
+
 // Returns a Shopify oauth authorization url for the given shopname and state.
 //
 // State is a unique value that can be used to check the authenticity during a
 // callback from Shopify.
-func (app App) AuthorizeUrl(shopName string, state string) (string, error) {
+func (app App) AuthorizeUrl(shopName string, state string, options ...AuthorizeOption) (string, error) {
 	shopUrl, err := url.Parse(ShopBaseUrl(shopName))
 	if err != nil {
 		return "", err
@@ -35,25 +56,136 @@ func (app App) AuthorizeUrl(shopName string, state string) (string, error) {
 	query.Set("redirect_uri", app.RedirectUrl)
 	query.Set("scope", app.Scope)
 	query.Set("state", state)
+	for _, option := range options {
+		option(query)
+	}
 	shopUrl.RawQuery = query.Encode()
 	return shopUrl.String(), nil
 }
 
+// ReauthorizeUrl returns an authorization url requesting only the missing
+// scopes, e.g. those returned by AccessScopesService.MissingScopes once an
+// app upgrade added scopes, for the merchant to approve them.
+func (app App) ReauthorizeUrl(shopName string, state string, missing ScopeSet, options ...AuthorizeOption) (string, error) {
+	if len(missing) == 0 {
+		return "", errors.New("no missing scopes to request")
+	}
+	return app.AuthorizeUrl(shopName, state, append(options, WithScopes(missing))...)
+}
+
+// Scopes returns the scopes requested by the app.
+func (app App) Scopes() ScopeSet {
+	return ParseScopes(app.Scope)
+}
+
+// OAuthToken is an access token granted to an app by a shop.
+type OAuthToken struct {
+	// Shop is the myshopify domain of the shop, e.g. fooshop.myshopify.com
+	Shop string `json:"shop,omitempty"`
+
+	AccessToken string `json:"access_token"`
+
+	// Scope is the comma separated list of the granted access scopes
+	Scope string `json:"scope,omitempty"`
+
+	// ExpiresIn is the lifetime in seconds of an online access token, as
+	// returned by Shopify
+	ExpiresIn int `json:"expires_in,omitempty"`
+
+	// ExpiresAt is when an online access token expires, computed from
+	// ExpiresIn when the token is received. Nil for offline access tokens.
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+
+	// AssociatedUserScope is the comma separated list of the access scopes
+	// available to the user of an online access token
+	AssociatedUserScope string `json:"associated_user_scope,omitempty"`
+
+	// AssociatedUser is the user of an online access token
+	AssociatedUser *AssociatedUser `json:"associated_user,omitempty"`
+
+	// RefreshToken renews an expiring offline access token, see
+	// App.RefreshAccessToken
+	RefreshToken string `json:"refresh_token,omitempty"`
+
+	// RefreshTokenExpiresIn is the lifetime in seconds of the refresh token,
+	// as returned by Shopify
+	RefreshTokenExpiresIn int `json:"refresh_token_expires_in,omitempty"`
+
+	// RefreshTokenExpiresAt is when the refresh token expires, computed from
+	// RefreshTokenExpiresIn when the token is received
+	RefreshTokenExpiresAt *time.Time `json:"refresh_token_expires_at,omitempty"`
+}
+
+// AssociatedUser is the staff member or collaborator who authorized an online
+// access token.
+type AssociatedUser struct {
+	Id            uint64 `json:"id"`
+	FirstName     string `json:"first_name,omitempty"`
+	LastName      string `json:"last_name,omitempty"`
+	Email         string `json:"email,omitempty"`
+	EmailVerified bool   `json:"email_verified"`
+	AccountOwner  bool   `json:"account_owner"`
+	Locale        string `json:"locale,omitempty"`
+	Collaborator  bool   `json:"collaborator"`
+}
+
+// Online reports whether the token is an online access token, tied to a user.
+func (t *OAuthToken) Online() bool {
+	return t.AssociatedUser != nil
+}
+
+// Scopes returns the scopes granted to the token.
+func (t *OAuthToken) Scopes() ScopeSet {
+	return ParseScopes(t.Scope)
+}
+
+// Expired reports whether the token expired. Offline access tokens without
+// an expiry never expire.
+func (t *OAuthToken) Expired() bool {
+	return t.ExpiresAt != nil && !time.Now().Before(*t.ExpiresAt)
+}
+
+// GetAccessToken exchanges the authorization code received on callback for
+// an access token. Use GetOAuthToken to also get the granted scopes and, for
+// online access tokens, their expiry and user.
 func (app App) GetAccessToken(ctx context.Context, shopName string, code string) (string, error) {
-	type Token struct {
-		Token string `json:"access_token"`
+	token, err := app.GetOAuthToken(ctx, shopName, code)
+	if err != nil {
+		return "", err
 	}
+	return token.AccessToken, nil
+}
 
+// GetOAuthToken exchanges the authorization code received on callback for an
+// access token, with the granted scopes and, for online access tokens, their
+// expiry and user.
+func (app App) GetOAuthToken(ctx context.Context, shopName string, code string) (*OAuthToken, error) {
 	data := struct {
 		ClientId     string `json:"client_id"`
 		ClientSecret string `json:"client_secret"`
 		Code         string `json:"code"`
+		Expiring     string `json:"expiring,omitempty"`
 	}{
 		ClientId:     app.ApiKey,
 		ClientSecret: app.ApiSecret,
 		Code:         code,
+		Expiring:     app.expiring(),
+	}
+
+	return app.requestToken(ctx, shopName, data)
+}
+
+// expiring returns the expiring parameter of the token requests, see
+// ExpiringOfflineTokens
+func (app App) expiring() string {
+	if app.ExpiringOfflineTokens {
+		return "1"
 	}
+	return ""
+}
 
+// requestToken posts a grant to the access token endpoint of the shop
+func (app App) requestToken(ctx context.Context, shopName string, data interface{}) (*OAuthToken, error) {
 	client := app.Client
 	if client == nil {
 		client = MustNewClient(app, shopName, "")
@@ -61,12 +193,64 @@ func (app App) GetAccessToken(ctx context.Context, shopName string, code string)
 
 	req, err := client.NewRequest(ctx, "POST", accessTokenRelPath, data, nil)
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+
+	token := new(OAuthToken)
+	if err := client.Do(req, token); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	token.Shop = ShopFullName(shopName)
+	if token.ExpiresIn > 0 {
+		expiresAt := now.Add(time.Duration(token.ExpiresIn) * time.Second)
+		token.ExpiresAt = &expiresAt
+	}
+	if token.RefreshTokenExpiresIn > 0 {
+		expiresAt := now.Add(time.Duration(token.RefreshTokenExpiresIn) * time.Second)
+		token.RefreshTokenExpiresAt = &expiresAt
+	}
+	return token, nil
+}
+
+// RefreshAccessToken exchanges the refresh token of an expiring offline
+// access token for a new access token and refresh token. The previous refresh
+// token can't be used again, so the new pair must be persisted.
+// See: https://shopify.dev/docs/apps/auth/access-token-types/offline#expiring-offline-access-tokens
+func (app App) RefreshAccessToken(ctx context.Context, shopName string, refreshToken string) (*OAuthToken, error) {
+	data := struct {
+		ClientId     string `json:"client_id"`
+		ClientSecret string `json:"client_secret"`
+		GrantType    string `json:"grant_type"`
+		RefreshToken string `json:"refresh_token"`
+	}{
+		ClientId:     app.ApiKey,
+		ClientSecret: app.ApiSecret,
+		GrantType:    "refresh_token",
+		RefreshToken: refreshToken,
+	}
+
+	return app.requestToken(ctx, shopName, data)
+}
+
+// GetClientCredentialsToken requests an access token for a shop of the
+// organization owning the app with the client credentials grant, without
+// merchant interaction. The token expires after 24 hours, see
+// WithClientCredentials to renew it automatically.
+// See: https://shopify.dev/docs/apps/build/authentication-authorization/access-tokens/client-credentials-grant
+func (app App) GetClientCredentialsToken(ctx context.Context, shopName string) (*OAuthToken, error) {
+	data := struct {
+		ClientId     string `json:"client_id"`
+		ClientSecret string `json:"client_secret"`
+		GrantType    string `json:"grant_type"`
+	}{
+		ClientId:     app.ApiKey,
+		ClientSecret: app.ApiSecret,
+		GrantType:    "client_credentials",
 	}
 
-	token := new(Token)
-	err = client.Do(req, token)
-	return token.Token, err
+	return app.requestToken(ctx, shopName, data)
 }
 
 // Verify a message against a message HMAC
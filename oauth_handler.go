@@ -0,0 +1,204 @@
+package goshopify
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+const (
+	oauthStateCookie = "shopify_oauth_state"
+
+	// oauthStateMaxAge is how long a merchant has to approve the install
+	oauthStateMaxAge = 10 * time.Minute
+)
+
+// OAuthHandler is an http.Handler running the OAuth install flow of an app.
+// Requests without a code parameter begin the flow: the shop parameter is
+// validated and the merchant is redirected to the authorization page of the
+// shop with a random state, also kept in a cookie. Requests with a code are
+// the callback: the HMAC and state are verified, the code is exchanged for an
+// access token and the offline token is saved in the TokenStore.
+//
+//	app := goshopify.App{ApiKey: "...", ApiSecret: "...", RedirectUrl: "https://example.com/auth", Scope: "read_products"}
+//	http.Handle("/auth", goshopify.NewOAuthHandler(app, store))
+//
+// The RedirectUrl of the app must be served by the handler, and allowed in
+// the app configuration. Begin and Callback can also be mounted separately.
+type OAuthHandler struct {
+	app   App
+	store TokenStore
+
+	// OnInstall responds to the callback once the token is saved. By default
+	// the merchant is redirected to the app in the shop admin.
+	OnInstall func(w http.ResponseWriter, r *http.Request, token *OAuthToken)
+
+	// PerUser requests online access tokens, see WithPerUserGrant. Online
+	// tokens are passed to OnInstall, e.g. to keep them in the session of
+	// the user, but not saved in the TokenStore which holds the offline
+	// token of each shop.
+	PerUser bool
+
+	// Logger receives rejected requests and failed token exchanges, defaults
+	// to a LeveledLogger.
+	Logger LeveledLoggerInterface
+}
+
+// NewOAuthHandler returns an OAuthHandler installing app and saving the
+// tokens in store.
+func NewOAuthHandler(app App, store TokenStore) *OAuthHandler {
+	return &OAuthHandler{
+		app:    app,
+		store:  store,
+		Logger: &LeveledLogger{},
+	}
+}
+
+// ServeHTTP implements http.Handler
+func (h *OAuthHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Query().Get("code") != "" {
+		h.Callback(w, r)
+		return
+	}
+	h.Begin(w, r)
+}
+
+// Begin redirects the merchant to the authorization page of the shop given by
+// the shop parameter. The request is rejected if the shop is not a myshopify
+// domain, or if it has an invalid hmac parameter, which Shopify sends when
+// the install starts from the admin.
+func (h *OAuthHandler) Begin(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	shop := query.Get("shop")
+	if !ValidShopDomain(shop) {
+		h.Logger.Warnf("rejected oauth request: invalid shop %q", shop)
+		http.Error(w, "invalid shop", http.StatusBadRequest)
+		return
+	}
+
+	if query.Get("hmac") != "" {
+		if ok, _ := h.app.VerifyAuthorizationURL(r.URL); !ok {
+			h.Logger.Warnf("rejected oauth request for %s: invalid hmac", shop)
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+	}
+
+	state, err := newOAuthState()
+	if err != nil {
+		h.Logger.Errorf("oauth request for %s: %s", shop, err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	var options []AuthorizeOption
+	if h.PerUser {
+		options = append(options, WithPerUserGrant())
+	}
+
+	authUrl, err := h.app.AuthorizeUrl(shop, state, options...)
+	if err != nil {
+		h.Logger.Errorf("oauth request for %s: %s", shop, err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     oauthStateCookie,
+		Value:    state,
+		Path:     "/",
+		MaxAge:   int(oauthStateMaxAge.Seconds()),
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, authUrl, http.StatusFound)
+}
+
+// Callback completes the install once the merchant approved it. It responds
+// with:
+//   - 400 Bad Request if the shop is not a myshopify domain
+//   - 401 Unauthorized if the hmac is invalid
+//   - 403 Forbidden if the state doesn't match the one set by Begin
+//   - 500 Internal Server Error if the code can't be exchanged or the token
+//     can't be saved
+//
+// and calls OnInstall otherwise.
+func (h *OAuthHandler) Callback(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	shop := query.Get("shop")
+	if !ValidShopDomain(shop) {
+		h.Logger.Warnf("rejected oauth callback: invalid shop %q", shop)
+		http.Error(w, "invalid shop", http.StatusBadRequest)
+		return
+	}
+
+	if ok, _ := h.app.VerifyAuthorizationURL(r.URL); !ok {
+		h.Logger.Warnf("rejected oauth callback for %s: invalid hmac", shop)
+		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return
+	}
+
+	cookie, err := r.Cookie(oauthStateCookie)
+	state := query.Get("state")
+	if err != nil || state == "" || subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(state)) != 1 {
+		h.Logger.Warnf("rejected oauth callback for %s: invalid state", shop)
+		http.Error(w, "invalid state", http.StatusForbidden)
+		return
+	}
+
+	// the state is single use
+	http.SetCookie(w, &http.Cookie{
+		Name:     oauthStateCookie,
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	token, err := h.app.GetOAuthToken(r.Context(), shop, query.Get("code"))
+	if err != nil {
+		h.Logger.Errorf("oauth callback for %s: exchanging code: %s", shop, err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	if !token.Online() {
+		if err := h.store.SaveToken(r.Context(), token); err != nil {
+			h.Logger.Errorf("oauth callback for %s: saving token: %s", shop, err)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if h.OnInstall != nil {
+		h.OnInstall(w, r, token)
+		return
+	}
+	http.Redirect(w, r, fmt.Sprintf("https://%s/admin/apps/%s", shop, h.app.ApiKey), http.StatusFound)
+}
+
+// newOAuthState returns a random nonce for the state parameter
+func newOAuthState() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
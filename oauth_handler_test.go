@@ -0,0 +1,283 @@
+package goshopify
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/jarcoal/httpmock"
+)
+
+// signOAuthQuery adds the hmac parameter Shopify signs the query with
+func signOAuthQuery(query url.Values) string {
+	message, _ := url.QueryUnescape(query.Encode())
+	mac := hmac.New(sha256.New, []byte(app.ApiSecret))
+	mac.Write([]byte(message))
+	query.Set("hmac", hex.EncodeToString(mac.Sum(nil)))
+	return query.Encode()
+}
+
+func newOAuthCallback(shop, state, cookie string) *http.Request {
+	query := url.Values{
+		"code":      {"foocode"},
+		"shop":      {shop},
+		"state":     {state},
+		"timestamp": {"1337178173"},
+	}
+	req := httptest.NewRequest(http.MethodGet, "/auth?"+signOAuthQuery(query), nil)
+	if cookie != "" {
+		req.AddCookie(&http.Cookie{Name: oauthStateCookie, Value: cookie})
+	}
+	return req
+}
+
+func TestOAuthHandlerBegin(t *testing.T) {
+	setup()
+	defer teardown()
+
+	handler := NewOAuthHandler(app, NewMemoryTokenStore())
+
+	newBegin := func() *http.Request {
+		query := url.Values{"shop": {"fooshop.myshopify.com"}, "timestamp": {"1337178173"}}
+		return httptest.NewRequest(http.MethodGet, "/auth?"+signOAuthQuery(query), nil)
+	}
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, newBegin())
+
+	if rec.Code != http.StatusFound {
+		t.Fatalf("Begin responded %d, expected %d", rec.Code, http.StatusFound)
+	}
+
+	cookies := rec.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != oauthStateCookie || !cookies[0].HttpOnly || !cookies[0].Secure {
+		t.Fatalf("Begin set cookies %v, expected a secure state cookie", cookies)
+	}
+	state := cookies[0].Value
+	if len(state) < 32 {
+		t.Errorf("state %q is too short", state)
+	}
+
+	location, err := url.Parse(rec.Header().Get("Location"))
+	if err != nil {
+		t.Fatalf("invalid Location: %v", err)
+	}
+	if location.Host != "fooshop.myshopify.com" || location.Path != "/admin/oauth/authorize" {
+		t.Errorf("Begin redirected to %s, expected the authorization page of fooshop", location)
+	}
+	if location.Query().Get("state") != state || location.Query().Get("client_id") != "apikey" {
+		t.Errorf("Begin redirected with query %v, expected state %s", location.Query(), state)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, newBegin())
+	if next := rec.Result().Cookies()[0].Value; next == state {
+		t.Errorf("Begin reused state %s", state)
+	}
+}
+
+func TestOAuthHandlerBeginRejects(t *testing.T) {
+	setup()
+	defer teardown()
+
+	handler := NewOAuthHandler(app, NewMemoryTokenStore())
+
+	cases := []struct {
+		name     string
+		target   string
+		expected int
+	}{
+		{"missing shop", "/auth", http.StatusBadRequest},
+		{"foreign host", "/auth?shop=evil.com", http.StatusBadRequest},
+		{"suffixed host", "/auth?shop=fooshop.myshopify.com.evil.com", http.StatusBadRequest},
+		{"invalid hmac", "/auth?shop=fooshop.myshopify.com&timestamp=1&hmac=00", http.StatusUnauthorized},
+	}
+
+	for _, c := range cases {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, c.target, nil))
+		if rec.Code != c.expected {
+			t.Errorf("%s: Begin responded %d, expected %d", c.name, rec.Code, c.expected)
+		}
+	}
+}
+
+func TestOAuthHandlerCallback(t *testing.T) {
+	setup()
+	defer teardown()
+
+	httpmock.RegisterResponder("POST", "https://fooshop.myshopify.com/admin/oauth/access_token",
+		httpmock.NewStringResponder(200, `{"access_token":"footoken","scope":"read_products"}`))
+
+	app.Client = client
+	store := NewMemoryTokenStore()
+	handler := NewOAuthHandler(app, store)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, newOAuthCallback("fooshop.myshopify.com", "thestate", "thestate"))
+
+	if rec.Code != http.StatusFound {
+		t.Fatalf("Callback responded %d, expected %d", rec.Code, http.StatusFound)
+	}
+	if location := rec.Header().Get("Location"); location != "https://fooshop.myshopify.com/admin/apps/apikey" {
+		t.Errorf("Callback redirected to %s, expected the app in the shop admin", location)
+	}
+	cookies := rec.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].MaxAge >= 0 {
+		t.Errorf("Callback set cookies %v, expected the state cookie to be cleared", cookies)
+	}
+
+	token, err := store.GetToken(context.Background(), "fooshop.myshopify.com")
+	if err != nil {
+		t.Fatalf("GetToken returned error: %v", err)
+	}
+	expected := OAuthToken{Shop: "fooshop.myshopify.com", AccessToken: "footoken", Scope: "read_products"}
+	if token == nil || *token != expected {
+		t.Errorf("stored token %+v, expected %+v", token, expected)
+	}
+}
+
+func TestOAuthHandlerCallbackOnInstall(t *testing.T) {
+	setup()
+	defer teardown()
+
+	httpmock.RegisterResponder("POST", "https://fooshop.myshopify.com/admin/oauth/access_token",
+		httpmock.NewStringResponder(200, `{"access_token":"footoken"}`))
+
+	app.Client = client
+	handler := NewOAuthHandler(app, NewMemoryTokenStore())
+
+	var installed *OAuthToken
+	handler.OnInstall = func(w http.ResponseWriter, r *http.Request, token *OAuthToken) {
+		installed = token
+		w.WriteHeader(http.StatusNoContent)
+	}
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, newOAuthCallback("fooshop.myshopify.com", "thestate", "thestate"))
+
+	if rec.Code != http.StatusNoContent {
+		t.Errorf("Callback responded %d, expected %d", rec.Code, http.StatusNoContent)
+	}
+	if installed == nil || installed.AccessToken != "footoken" {
+		t.Errorf("OnInstall received %+v, expected footoken", installed)
+	}
+}
+
+func TestOAuthHandlerPerUser(t *testing.T) {
+	setup()
+	defer teardown()
+
+	httpmock.RegisterResponder("POST", "https://fooshop.myshopify.com/admin/oauth/access_token",
+		httpmock.NewStringResponder(200, `{"access_token":"usertoken","expires_in":86399,"associated_user":{"id":1}}`))
+
+	app.Client = client
+	store := NewMemoryTokenStore()
+	handler := NewOAuthHandler(app, store)
+	handler.PerUser = true
+
+	var installed *OAuthToken
+	handler.OnInstall = func(w http.ResponseWriter, r *http.Request, token *OAuthToken) {
+		installed = token
+	}
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/auth?shop=fooshop.myshopify.com", nil))
+	location, _ := url.Parse(rec.Header().Get("Location"))
+	if grant := location.Query().Get("grant_options[]"); grant != "per-user" {
+		t.Errorf("Begin redirected with grant_options[]=%q, expected per-user", grant)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, newOAuthCallback("fooshop.myshopify.com", "thestate", "thestate"))
+	if installed == nil || !installed.Online() {
+		t.Fatalf("OnInstall received %+v, expected an online token", installed)
+	}
+	if token, _ := store.GetToken(context.Background(), "fooshop.myshopify.com"); token != nil {
+		t.Errorf("stored online token %+v, expected none", token)
+	}
+}
+
+type failingTokenStore struct {
+	MemoryTokenStore
+}
+
+func (s *failingTokenStore) SaveToken(ctx context.Context, token *OAuthToken) error {
+	return errors.New("database unavailable")
+}
+
+func TestOAuthHandlerCallbackRejects(t *testing.T) {
+	setup()
+	defer teardown()
+
+	httpmock.RegisterResponder("POST", "https://fooshop.myshopify.com/admin/oauth/access_token",
+		httpmock.NewStringResponder(200, `{"access_token":"footoken"}`))
+
+	app.Client = client
+	handler := NewOAuthHandler(app, NewMemoryTokenStore())
+
+	tampered := newOAuthCallback("fooshop.myshopify.com", "thestate", "thestate")
+	tampered.URL.RawQuery = strings.Replace(tampered.URL.RawQuery, "foocode", "othercode", 1)
+
+	cases := []struct {
+		name     string
+		handler  *OAuthHandler
+		req      *http.Request
+		expected int
+	}{
+		{"invalid shop", handler, newOAuthCallback("evil.com", "thestate", "thestate"), http.StatusBadRequest},
+		{"invalid hmac", handler, tampered, http.StatusUnauthorized},
+		{"missing cookie", handler, newOAuthCallback("fooshop.myshopify.com", "thestate", ""), http.StatusForbidden},
+		{"mismatched state", handler, newOAuthCallback("fooshop.myshopify.com", "thestate", "otherstate"), http.StatusForbidden},
+		{"empty state", handler, newOAuthCallback("fooshop.myshopify.com", "", ""), http.StatusForbidden},
+		{"failing store", NewOAuthHandler(app, &failingTokenStore{}), newOAuthCallback("fooshop.myshopify.com", "thestate", "thestate"), http.StatusInternalServerError},
+	}
+
+	for _, c := range cases {
+		rec := httptest.NewRecorder()
+		c.handler.ServeHTTP(rec, c.req)
+		if rec.Code != c.expected {
+			t.Errorf("%s: Callback responded %d, expected %d", c.name, rec.Code, c.expected)
+		}
+	}
+
+	info := httpmock.GetCallCountInfo()
+	if calls := info["POST https://fooshop.myshopify.com/admin/oauth/access_token"]; calls != 1 {
+		t.Errorf("code exchanged %d times, expected only for the failing store", calls)
+	}
+}
+
+func TestMemoryTokenStore(t *testing.T) {
+	store := NewMemoryTokenStore()
+	ctx := context.Background()
+
+	token, err := store.GetToken(ctx, "fooshop.myshopify.com")
+	if err != nil || token != nil {
+		t.Errorf("GetToken of a missing shop returned %v, %v, expected nil", token, err)
+	}
+
+	saved := &OAuthToken{Shop: "fooshop.myshopify.com", AccessToken: "footoken"}
+	if err := store.SaveToken(ctx, saved); err != nil {
+		t.Fatalf("SaveToken returned error: %v", err)
+	}
+	saved.AccessToken = "modified"
+
+	token, _ = store.GetToken(ctx, "fooshop.myshopify.com")
+	if token == nil || token.AccessToken != "footoken" {
+		t.Errorf("GetToken returned %+v, expected footoken", token)
+	}
+
+	if err := store.DeleteToken(ctx, "fooshop.myshopify.com"); err != nil {
+		t.Fatalf("DeleteToken returned error: %v", err)
+	}
+	if token, _ := store.GetToken(ctx, "fooshop.myshopify.com"); token != nil {
+		t.Errorf("GetToken returned %+v after DeleteToken, expected nil", token)
+	}
+}
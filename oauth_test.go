@@ -3,12 +3,16 @@ package goshopify
 import (
 	"context"
 	"encoding/base64"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"net/url"
+	"reflect"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/jarcoal/httpmock"
 )
@@ -67,6 +71,182 @@ func TestAppGetAccessToken(t *testing.T) {
 	}
 }
 
+func TestAppAuthorizeUrlPerUser(t *testing.T) {
+	setup()
+	defer teardown()
+
+	actual, err := app.AuthorizeUrl("fooshop", "thenonce", WithPerUserGrant())
+	if err != nil {
+		t.Fatalf("App.AuthorizeUrl(): %v", err)
+	}
+
+	expected := "https://fooshop.myshopify.com/admin/oauth/authorize?client_id=apikey&grant_options%5B%5D=per-user&redirect_uri=https%3A%2F%2Fexample.com%2Fcallback&scope=read_products&state=thenonce"
+	if actual != expected {
+		t.Errorf("App.AuthorizeUrl(): expected %s, actual %s", expected, actual)
+	}
+}
+
+func TestAppReauthorizeUrl(t *testing.T) {
+	setup()
+	defer teardown()
+
+	actual, err := app.ReauthorizeUrl("fooshop", "thenonce", NewScopeSet("write_orders", "read_customers"))
+	if err != nil {
+		t.Fatalf("App.ReauthorizeUrl(): %v", err)
+	}
+
+	expected := "https://fooshop.myshopify.com/admin/oauth/authorize?client_id=apikey&redirect_uri=https%3A%2F%2Fexample.com%2Fcallback&scope=read_customers%2Cwrite_orders&state=thenonce"
+	if actual != expected {
+		t.Errorf("App.ReauthorizeUrl(): expected %s, actual %s", expected, actual)
+	}
+
+	if _, err := app.ReauthorizeUrl("fooshop", "thenonce", NewScopeSet()); err == nil {
+		t.Errorf("App.ReauthorizeUrl() without missing scopes returned no error")
+	}
+}
+
+func TestAppGetOAuthTokenOnline(t *testing.T) {
+	setup()
+	defer teardown()
+
+	httpmock.RegisterResponder("POST", "https://fooshop.myshopify.com/admin/oauth/access_token",
+		httpmock.NewStringResponder(200, `{
+			"access_token": "f85632530bf277ec9ac6f649fc327f17",
+			"scope": "write_orders,read_customers",
+			"expires_in": 86399,
+			"associated_user_scope": "write_orders",
+			"associated_user": {
+				"id": 902541635,
+				"first_name": "John",
+				"last_name": "Smith",
+				"email": "john@example.com",
+				"email_verified": true,
+				"account_owner": true,
+				"locale": "en",
+				"collaborator": false
+			}
+		}`))
+
+	app.Client = client
+	before := time.Now()
+	token, err := app.GetOAuthToken(context.Background(), "fooshop", "foocode")
+	if err != nil {
+		t.Fatalf("App.GetOAuthToken(): %v", err)
+	}
+
+	if token.Shop != "fooshop.myshopify.com" || token.Scope != "write_orders,read_customers" || token.AssociatedUserScope != "write_orders" {
+		t.Errorf("App.GetOAuthToken() returned %+v", token)
+	}
+
+	expectedUser := AssociatedUser{
+		Id:            902541635,
+		FirstName:     "John",
+		LastName:      "Smith",
+		Email:         "john@example.com",
+		EmailVerified: true,
+		AccountOwner:  true,
+		Locale:        "en",
+	}
+	if !token.Online() || *token.AssociatedUser != expectedUser {
+		t.Errorf("token.AssociatedUser = %+v, expected %+v", token.AssociatedUser, expectedUser)
+	}
+
+	if token.ExpiresAt == nil || token.ExpiresAt.Before(before.Add(86399*time.Second)) {
+		t.Errorf("token.ExpiresAt = %v, expected in 86399 seconds", token.ExpiresAt)
+	}
+	if token.Expired() {
+		t.Errorf("token.Expired() = true, expected false")
+	}
+
+	past := time.Now().Add(-time.Second)
+	token.ExpiresAt = &past
+	if !token.Expired() {
+		t.Errorf("token.Expired() = false, expected true")
+	}
+}
+
+func TestAppGetOAuthTokenOffline(t *testing.T) {
+	setup()
+	defer teardown()
+
+	httpmock.RegisterResponder("POST", "https://fooshop.myshopify.com/admin/oauth/access_token",
+		httpmock.NewStringResponder(200, `{"access_token":"footoken","scope":"read_products"}`))
+
+	app.Client = client
+	token, err := app.GetOAuthToken(context.Background(), "fooshop", "foocode")
+	if err != nil {
+		t.Fatalf("App.GetOAuthToken(): %v", err)
+	}
+
+	if token.Online() || token.Expired() || token.ExpiresAt != nil {
+		t.Errorf("App.GetOAuthToken() returned %+v, expected an offline token", token)
+	}
+}
+
+func TestAppGetOAuthTokenExpiring(t *testing.T) {
+	setup()
+	defer teardown()
+
+	var body map[string]string
+	httpmock.RegisterResponder("POST", "https://fooshop.myshopify.com/admin/oauth/access_token",
+		func(req *http.Request) (*http.Response, error) {
+			data, _ := io.ReadAll(req.Body)
+			body = nil
+			json.Unmarshal(data, &body)
+			return httpmock.NewStringResponse(200, `{"access_token":"footoken","expires_in":3600,"refresh_token":"foorefresh","refresh_token_expires_in":7776000}`), nil
+		})
+
+	expiringApp := app
+	expiringApp.ExpiringOfflineTokens = true
+	expiringApp.Client = client
+	token, err := expiringApp.GetOAuthToken(context.Background(), "fooshop", "foocode")
+	if err != nil {
+		t.Fatalf("App.GetOAuthToken(): %v", err)
+	}
+
+	if body["expiring"] != "1" {
+		t.Errorf("token requested with %v, expected expiring=1", body)
+	}
+	if token.RefreshToken != "foorefresh" || token.ExpiresAt == nil || token.RefreshTokenExpiresAt == nil {
+		t.Errorf("App.GetOAuthToken() returned %+v, expected an expiring token", token)
+	}
+
+	app.Client = client
+	if _, err := app.GetOAuthToken(context.Background(), "fooshop", "foocode"); err != nil {
+		t.Fatalf("App.GetOAuthToken(): %v", err)
+	}
+	if _, ok := body["expiring"]; ok {
+		t.Errorf("token requested with %v, expected no expiring parameter", body)
+	}
+}
+
+func TestAppGetClientCredentialsToken(t *testing.T) {
+	setup()
+	defer teardown()
+
+	var body map[string]string
+	httpmock.RegisterResponder("POST", "https://fooshop.myshopify.com/admin/oauth/access_token",
+		func(req *http.Request) (*http.Response, error) {
+			data, _ := io.ReadAll(req.Body)
+			json.Unmarshal(data, &body)
+			return httpmock.NewStringResponse(200, `{"access_token":"footoken","scope":"read_products","expires_in":86399}`), nil
+		})
+
+	app.Client = client
+	token, err := app.GetClientCredentialsToken(context.Background(), "fooshop")
+	if err != nil {
+		t.Fatalf("App.GetClientCredentialsToken(): %v", err)
+	}
+
+	expectedBody := map[string]string{"client_id": "apikey", "client_secret": "hush", "grant_type": "client_credentials"}
+	if !reflect.DeepEqual(body, expectedBody) {
+		t.Errorf("token requested with %v, expected %v", body, expectedBody)
+	}
+	if token.Shop != "fooshop.myshopify.com" || token.AccessToken != "footoken" || token.ExpiresAt == nil {
+		t.Errorf("App.GetClientCredentialsToken() returned %+v", token)
+	}
+}
+
 func TestAppGetAccessTokenError(t *testing.T) {
 	setup()
 	defer teardown()
@@ -84,6 +264,7 @@ func TestAppGetAccessTokenError(t *testing.T) {
 	}
 
 	expectedError = errors.New("parse ://example.com: missing protocol scheme")
+	defer func(path string) { accessTokenRelPath = path }(accessTokenRelPath)
 	accessTokenRelPath = "://example.com" // cause NewRequest to trip a parse error
 	token, err = app.GetAccessToken(context.Background(), "fooshop", "")
 	if err == nil || !strings.Contains(err.Error(), "missing protocol scheme") {
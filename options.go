@@ -2,7 +2,9 @@ package goshopify
 
 import (
 	"fmt"
+	"log/slog"
 	"net/http"
+	"time"
 )
 
 // Option is used to configure client with options
@@ -28,12 +30,120 @@ func WithRetry(retries int) Option {
 	}
 }
 
+// WithRetryPolicy sets the policy deciding which failed requests are retried
+// and how long to wait in between, e.g. WithRetryPolicy(NewExponentialBackoff(5)).
+// It takes precedence over WithRetry, also for throttled GraphQL queries.
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(c *Client) {
+		c.retryPolicy = policy
+	}
+}
+
+// WithRateLimiter throttles REST API requests on the client side so they are
+// delayed before Shopify would answer with a 429, e.g.
+// WithRateLimiter(NewLeakyBucket(RateLimitPlanStandard)).
+// Share the same limiter between clients that talk to the same shop.
+func WithRateLimiter(limiter RateLimiter) Option {
+	return func(c *Client) {
+		c.rateLimiter = limiter
+	}
+}
+
+// WithGraphQLThrottler schedules GraphQL queries by their calculated cost so
+// they are only sent once enough points are available, e.g.
+// WithGraphQLThrottler(NewGraphQLCostThrottler()).
+// Share the same throttler between clients that talk to the same shop.
+func WithGraphQLThrottler(throttler GraphQLThrottler) Option {
+	return func(c *Client) {
+		c.graphQLThrottler = throttler
+	}
+}
+
+// WithMiddleware wraps every API call made by the client in the given
+// middleware, e.g. to add headers, record metrics or audit calls. Middleware
+// is applied in order, the first one being the outermost, and WithMiddleware
+// can be passed several times.
+func WithMiddleware(middleware ...Middleware) Option {
+	return func(c *Client) {
+		c.middleware = append(c.middleware, middleware...)
+	}
+}
+
 func WithLogger(logger LeveledLoggerInterface) Option {
 	return func(c *Client) {
 		c.log = logger
 	}
 }
 
+// WithSlogLogger logs through a *slog.Logger, recording API calls as
+// structured records with the method, path, status, request id and duration
+// as attributes. Bodies are logged at debug level.
+func WithSlogLogger(logger *slog.Logger) Option {
+	return func(c *Client) {
+		c.log = NewSlogLogger(logger)
+	}
+}
+
+// WithRedactor sets the Redactor masking sensitive values in logged bodies
+// and urls, defaults to NewRedactor(). Passing nil disables redaction.
+func WithRedactor(redactor *Redactor) Option {
+	return func(c *Client) {
+		c.redactor = redactor
+	}
+}
+
+// WithTokenExpiry makes the client refuse to send requests once its access
+// token expired at expiresAt, returning ErrTokenExpired instead of letting
+// Shopify answer with a 401, e.g. WithTokenExpiry(*token.ExpiresAt) for an
+// online access token. A zero time means the token doesn't expire.
+func WithTokenExpiry(expiresAt time.Time) Option {
+	return func(c *Client) {
+		c.tokenExpiresAt = expiresAt
+	}
+}
+
+// WithTokenRefresh authenticates the client with an expiring offline access
+// token, which is refreshed with its refresh token once it expired or when
+// Shopify answers with a 401, before the request is retried. The renewed
+// token is saved in store, if not nil, as the previous refresh token can't be
+// used again. It replaces the token passed to NewClient.
+func WithTokenRefresh(token *OAuthToken, store TokenStore) Option {
+	return func(c *Client) {
+		c.token = token.AccessToken
+		c.refreshToken = token.RefreshToken
+		c.tokenStore = store
+		c.tokenExpiresAt = time.Time{}
+		if token.ExpiresAt != nil {
+			c.tokenExpiresAt = *token.ExpiresAt
+		}
+		c.refreshTokenExpiresAt = time.Time{}
+		if token.RefreshTokenExpiresAt != nil {
+			c.refreshTokenExpiresAt = *token.RefreshTokenExpiresAt
+		}
+	}
+}
+
+// WithClientCredentials authenticates the client with access tokens requested
+// with the client credentials grant, see App.GetClientCredentialsToken. A
+// token is requested before the first request, and again once it expired or
+// when Shopify answers with a 401. It replaces the token passed to NewClient.
+func WithClientCredentials() Option {
+	return func(c *Client) {
+		c.token = ""
+		c.tokenExpiresAt = time.Time{}
+		c.clientCredentials = true
+	}
+}
+
+// WithWebhookTopicValidation makes WebhookService.Create, Update and Sync
+// reject the topics which are not known to this release before calling the
+// API, see WebhookTopic.Validate. Without it Shopify validates the topics.
+func WithWebhookTopicValidation() Option {
+	return func(c *Client) {
+		c.validateWebhookTopics = true
+	}
+}
+
 // WithHTTPClient is used to set a custom http client
 func WithHTTPClient(client *http.Client) Option {
 	return func(c *Client) {
@@ -1,10 +1,14 @@
 package goshopify
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"testing"
 	"time"
+
+	"github.com/jarcoal/httpmock"
 )
 
 func TestWithVersion(t *testing.T) {
@@ -72,3 +76,25 @@ func TestWithHTTPClient(t *testing.T) {
 		t.Errorf("WithVersion client.Client = %s, expected %s", c.Client.Timeout, expected)
 	}
 }
+
+func TestWithTokenExpiry(t *testing.T) {
+	setup()
+	defer teardown()
+
+	httpmock.RegisterResponder("GET", fmt.Sprintf("https://fooshop.myshopify.com/%s/shop.json", client.pathPrefix),
+		httpmock.NewStringResponder(200, `{"shop": {"id": 1}}`))
+
+	WithTokenExpiry(time.Now().Add(time.Hour))(client)
+	if _, err := client.Shop.Get(context.Background(), nil); err != nil {
+		t.Errorf("Shop.Get returned error %v with an unexpired token", err)
+	}
+
+	WithTokenExpiry(time.Now().Add(-time.Second))(client)
+	if _, err := client.Shop.Get(context.Background(), nil); !errors.Is(err, ErrTokenExpired) {
+		t.Errorf("Shop.Get returned error %v with an expired token, expected ErrTokenExpired", err)
+	}
+
+	if calls := httpmock.GetTotalCallCount(); calls != 1 {
+		t.Errorf("made %d calls, expected the expired token not to be sent", calls)
+	}
+}
@@ -21,6 +21,8 @@ type OrderService interface {
 	List(context.Context, interface{}) ([]Order, error)
 	ListAll(context.Context, interface{}) ([]Order, error)
 	ListWithPagination(context.Context, interface{}) ([]Order, *Pagination, error)
+	Pages(interface{}) *PageIterator[Order]
+	Iter(interface{}) *ListIterator[Order]
 	Count(context.Context, interface{}) (int, error)
 	Get(context.Context, uint64, interface{}) (*Order, error)
 	Create(context.Context, Order) (*Order, error)
@@ -672,25 +674,17 @@ func (s *OrderServiceOp) List(ctx context.Context, options interface{}) ([]Order
 
 // ListAll Lists all orders, iterating over pages
 func (s *OrderServiceOp) ListAll(ctx context.Context, options interface{}) ([]Order, error) {
-	collector := []Order{}
-
-	for {
-		entities, pagination, err := s.ListWithPagination(ctx, options)
-
-		if err != nil {
-			return collector, err
-		}
-
-		collector = append(collector, entities...)
-
-		if pagination.NextPageOptions == nil {
-			break
-		}
+	return listAll(ctx, s.ListWithPagination, options)
+}
 
-		options = pagination.NextPageOptions
-	}
+// Pages returns an iterator lazily walking the pages of orders
+func (s *OrderServiceOp) Pages(options interface{}) *PageIterator[Order] {
+	return newPageIterator(s.ListWithPagination, options)
+}
 
-	return collector, nil
+// Iter returns an iterator lazily walking orders one at a time
+func (s *OrderServiceOp) Iter(options interface{}) *ListIterator[Order] {
+	return newListIterator(s.ListWithPagination, options)
 }
 
 func (s *OrderServiceOp) ListWithPagination(ctx context.Context, options interface{}) ([]Order, *Pagination, error) {
@@ -0,0 +1,397 @@
+package goshopify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// ExportCheckpoint records how far an export has progressed.
+//
+// While an export is running PageInfo holds the cursor of the next page to
+// fetch, or Ranges the progress of each range of a parallel export. Once an
+// export completes PageInfo and Ranges are cleared and SinceId and
+// UpdatedAtMin hold the highest id and updated_at seen, which are used as a
+// watermark by the next export so it only fetches new or updated orders.
+type ExportCheckpoint struct {
+	PageInfo     string        `json:"page_info,omitempty"`
+	SinceId      uint64        `json:"since_id,omitempty"`
+	UpdatedAtMin *time.Time    `json:"updated_at_min,omitempty"`
+	Ranges       []ExportRange `json:"ranges,omitempty"`
+}
+
+// ExportRange is the progress of the export of the orders created between
+// CreatedAtMin and CreatedAtMax, both inclusive, see OrderExporter.Concurrency
+type ExportRange struct {
+	CreatedAtMin time.Time `json:"created_at_min"`
+	CreatedAtMax time.Time `json:"created_at_max"`
+	PageInfo     string    `json:"page_info,omitempty"`
+	Done         bool      `json:"done,omitempty"`
+}
+
+// CheckpointStore persists the progress of an export so it can be resumed
+// after a crash. Load returns a nil checkpoint if none has been saved yet.
+type CheckpointStore interface {
+	Load(context.Context) (*ExportCheckpoint, error)
+	Save(context.Context, ExportCheckpoint) error
+}
+
+// FileCheckpointStore is a CheckpointStore keeping the checkpoint as JSON in
+// a single file.
+type FileCheckpointStore struct {
+	Path string
+}
+
+// NewFileCheckpointStore returns a CheckpointStore persisting to the given path
+func NewFileCheckpointStore(path string) *FileCheckpointStore {
+	return &FileCheckpointStore{Path: path}
+}
+
+// Load reads the checkpoint from the file, returning nil if it does not exist
+func (s *FileCheckpointStore) Load(_ context.Context) (*ExportCheckpoint, error) {
+	b, err := os.ReadFile(s.Path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	checkpoint := new(ExportCheckpoint)
+	err = json.Unmarshal(b, checkpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	return checkpoint, nil
+}
+
+// Save writes the checkpoint to a temporary file and renames it over the
+// previous checkpoint so a crash never leaves a partially written file.
+func (s *FileCheckpointStore) Save(_ context.Context, checkpoint ExportCheckpoint) error {
+	b, err := json.Marshal(checkpoint)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.Path), filepath.Base(s.Path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(b)
+	if err != nil {
+		tmp.Close()
+		return err
+	}
+
+	err = tmp.Close()
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), s.Path)
+}
+
+// OrderExporter exports all orders of a shop page by page, persisting its
+// progress through a CheckpointStore so an interrupted export resumes where
+// it left off instead of starting over.
+type OrderExporter struct {
+	client  *Client
+	store   CheckpointStore
+	options OrderListOptions
+
+	// Concurrency is the number of ranges of creation dates a first export
+	// is split into, which are fetched in parallel. It defaults to 1, a
+	// single sequential export. The exports following a completed one only
+	// fetch the updated orders and are always sequential.
+	Concurrency int
+}
+
+// NewOrderExporter returns an exporter listing the orders matching options.
+// The filters in options are only applied when an export starts from the
+// beginning, resuming from a page cursor keeps only Limit and Fields as
+// Shopify doesn't allow changing the filters while paginating.
+func NewOrderExporter(client *Client, store CheckpointStore, options OrderListOptions) *OrderExporter {
+	return &OrderExporter{
+		client:  client,
+		store:   store,
+		options: options,
+	}
+}
+
+// Export starts the export in the background and returns a channel emitting
+// the orders and a channel receiving at most one error. Both channels are
+// closed once the export completes, fails or ctx is cancelled.
+//
+// Orders are handed over on an unbuffered channel and the cursor of the next
+// page is only saved once every order of the current page was received, so
+// orders are delivered at least once across restarts. The orders of a
+// parallel export are emitted in no particular order.
+func (e *OrderExporter) Export(ctx context.Context) (<-chan Order, <-chan error) {
+	orders := make(chan Order)
+	errs := make(chan error, 1)
+
+	go func() {
+		defer close(errs)
+		defer close(orders)
+
+		err := e.run(ctx, orders)
+		if err != nil {
+			errs <- err
+		}
+	}()
+
+	return orders, errs
+}
+
+func (e *OrderExporter) run(ctx context.Context, orders chan<- Order) error {
+	checkpoint, err := e.store.Load(ctx)
+	if err != nil {
+		return err
+	}
+	if checkpoint == nil {
+		checkpoint = &ExportCheckpoint{}
+		if e.Concurrency > 1 {
+			checkpoint.Ranges, err = e.splitRanges(ctx)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	if len(checkpoint.Ranges) > 0 {
+		return e.runRanges(ctx, *checkpoint, orders)
+	}
+
+	progress := *checkpoint
+	progress.PageInfo = ""
+
+	pages := e.client.Order.Pages(e.resumeOptions(*checkpoint))
+	for pages.Next(ctx) {
+		for _, order := range pages.Page() {
+			select {
+			case orders <- order:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+
+			progress.observe(order)
+		}
+
+		next := pages.Pagination().NextPageOptions
+		if next == nil {
+			break
+		}
+
+		pageCheckpoint := progress
+		pageCheckpoint.PageInfo = next.PageInfo
+		err = e.store.Save(ctx, pageCheckpoint)
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := pages.Err(); err != nil {
+		return err
+	}
+
+	return e.store.Save(ctx, progress)
+}
+
+// resumeOptions returns the list options to continue from the checkpoint
+func (e *OrderExporter) resumeOptions(checkpoint ExportCheckpoint) interface{} {
+	if checkpoint.PageInfo != "" {
+		return &ListOptions{
+			PageInfo: checkpoint.PageInfo,
+			Limit:    e.options.Limit,
+			Fields:   e.options.Fields,
+		}
+	}
+
+	options := e.options
+	if checkpoint.UpdatedAtMin != nil {
+		options.UpdatedAtMin = *checkpoint.UpdatedAtMin
+	} else if checkpoint.SinceId != 0 {
+		sinceId := checkpoint.SinceId
+		options.SinceId = &sinceId
+	}
+
+	return &options
+}
+
+// observe advances the watermark past the given order
+func (c *ExportCheckpoint) observe(order Order) {
+	if order.Id > c.SinceId {
+		c.SinceId = order.Id
+	}
+	if order.UpdatedAt != nil && (c.UpdatedAtMin == nil || order.UpdatedAt.After(*c.UpdatedAtMin)) {
+		updatedAt := *order.UpdatedAt
+		c.UpdatedAtMin = &updatedAt
+	}
+}
+
+// splitRanges splits the creation dates of the orders to export into
+// Concurrency ranges, from CreatedAtMin or the creation of the oldest order
+// to CreatedAtMax or now. It returns no range if there is no order.
+func (e *OrderExporter) splitRanges(ctx context.Context) ([]ExportRange, error) {
+	to := e.options.CreatedAtMax
+	if to.IsZero() {
+		to = time.Now().UTC().Truncate(time.Second)
+	}
+
+	from := e.options.CreatedAtMin
+	if from.IsZero() {
+		options := e.options
+		options.Limit = 1
+		options.Fields = "id,created_at"
+		options.Order = "created_at asc"
+		oldest, err := e.client.Order.List(ctx, &options)
+		if err != nil {
+			return nil, err
+		}
+		if len(oldest) == 0 || oldest[0].CreatedAt == nil {
+			return nil, nil
+		}
+		from = oldest[0].CreatedAt.UTC().Truncate(time.Second)
+	}
+
+	// Shopify compares creation dates to the second, so consecutive ranges
+	// are a second apart
+	step := (to.Sub(from) / time.Duration(e.Concurrency)).Truncate(time.Second)
+	if step < time.Second {
+		return []ExportRange{{CreatedAtMin: from, CreatedAtMax: to}}, nil
+	}
+
+	ranges := make([]ExportRange, e.Concurrency)
+	for i := range ranges {
+		ranges[i].CreatedAtMin = from.Add(time.Duration(i) * step)
+		ranges[i].CreatedAtMax = ranges[i].CreatedAtMin.Add(step - time.Second)
+	}
+	ranges[len(ranges)-1].CreatedAtMax = to
+	return ranges, nil
+}
+
+// runRanges exports the ranges of the checkpoint which aren't done yet in
+// parallel, stopping them all at the first error
+func (e *OrderExporter) runRanges(ctx context.Context, checkpoint ExportCheckpoint, orders chan<- Order) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	progress := &exportProgress{store: e.store, checkpoint: checkpoint}
+	errs := make(chan error, len(checkpoint.Ranges))
+	var wg sync.WaitGroup
+	for i, r := range checkpoint.Ranges {
+		if r.Done {
+			continue
+		}
+
+		wg.Add(1)
+		go func(i int, r ExportRange) {
+			defer wg.Done()
+			if err := e.runRange(ctx, progress, i, r, orders); err != nil {
+				errs <- err
+				cancel()
+			}
+		}(i, r)
+	}
+	wg.Wait()
+	close(errs)
+
+	// the first error is the one which cancelled the other ranges
+	if err := <-errs; err != nil {
+		return err
+	}
+	return progress.complete(ctx)
+}
+
+// runRange exports the orders of the i-th range of a parallel export
+func (e *OrderExporter) runRange(ctx context.Context, progress *exportProgress, i int, r ExportRange, orders chan<- Order) error {
+	var options interface{}
+	if r.PageInfo != "" {
+		options = &ListOptions{
+			PageInfo: r.PageInfo,
+			Limit:    e.options.Limit,
+			Fields:   e.options.Fields,
+		}
+	} else {
+		rangeOptions := e.options
+		rangeOptions.CreatedAtMin = r.CreatedAtMin
+		rangeOptions.CreatedAtMax = r.CreatedAtMax
+		options = &rangeOptions
+	}
+
+	pages := e.client.Order.Pages(options)
+	for pages.Next(ctx) {
+		for _, order := range pages.Page() {
+			select {
+			case orders <- order:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+
+			progress.observe(order)
+		}
+
+		next := pages.Pagination().NextPageOptions
+		if next == nil {
+			break
+		}
+
+		err := progress.save(ctx, i, next.PageInfo)
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := pages.Err(); err != nil {
+		return err
+	}
+
+	return progress.save(ctx, i, "")
+}
+
+// exportProgress is the checkpoint of a parallel export, updated by the
+// goroutines exporting its ranges
+type exportProgress struct {
+	mu         sync.Mutex
+	store      CheckpointStore
+	checkpoint ExportCheckpoint
+}
+
+func (p *exportProgress) observe(order Order) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.checkpoint.observe(order)
+}
+
+// save records the cursor of the next page of the i-th range, an empty
+// cursor marking the range as done. The lock is held while saving so
+// checkpoints are saved in order.
+func (p *exportProgress) save(ctx context.Context, i int, pageInfo string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	ranges := make([]ExportRange, len(p.checkpoint.Ranges))
+	copy(ranges, p.checkpoint.Ranges)
+	ranges[i].PageInfo = pageInfo
+	ranges[i].Done = pageInfo == ""
+	p.checkpoint.Ranges = ranges
+
+	return p.store.Save(ctx, p.checkpoint)
+}
+
+// complete saves the watermark of the export once all ranges are done
+func (p *exportProgress) complete(ctx context.Context) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.checkpoint.Ranges = nil
+	return p.store.Save(ctx, p.checkpoint)
+}
@@ -0,0 +1,262 @@
+package goshopify
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/jarcoal/httpmock"
+)
+
+func collectExport(exporter *OrderExporter) ([]uint64, error) {
+	orders, errs := exporter.Export(context.Background())
+
+	var ids []uint64
+	for order := range orders {
+		ids = append(ids, order.Id)
+	}
+
+	return ids, <-errs
+}
+
+func TestFileCheckpointStore(t *testing.T) {
+	store := NewFileCheckpointStore(filepath.Join(t.TempDir(), "checkpoint.json"))
+
+	checkpoint, err := store.Load(context.Background())
+	if err != nil {
+		t.Fatalf("FileCheckpointStore.Load returned error: %v", err)
+	}
+	if checkpoint != nil {
+		t.Errorf("FileCheckpointStore.Load returned %#v, expected nil", checkpoint)
+	}
+
+	updatedAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	expected := ExportCheckpoint{
+		PageInfo:     "pg2",
+		SinceId:      123,
+		UpdatedAtMin: &updatedAt,
+	}
+	err = store.Save(context.Background(), expected)
+	if err != nil {
+		t.Fatalf("FileCheckpointStore.Save returned error: %v", err)
+	}
+
+	checkpoint, err = store.Load(context.Background())
+	if err != nil {
+		t.Fatalf("FileCheckpointStore.Load returned error: %v", err)
+	}
+	if checkpoint == nil || !reflect.DeepEqual(*checkpoint, expected) {
+		t.Errorf("FileCheckpointStore.Load returned %#v, expected %#v", checkpoint, expected)
+	}
+}
+
+func TestOrderExporterExport(t *testing.T) {
+	setup()
+	defer teardown()
+
+	registerOrderPages()
+
+	store := NewFileCheckpointStore(filepath.Join(t.TempDir(), "checkpoint.json"))
+	ids, err := collectExport(NewOrderExporter(client, store, OrderListOptions{}))
+	if err != nil {
+		t.Fatalf("OrderExporter.Export returned error: %v", err)
+	}
+
+	expectedIds := []uint64{1, 2, 3, 4, 5}
+	if !reflect.DeepEqual(ids, expectedIds) {
+		t.Errorf("OrderExporter.Export ids = %v, expected %v", ids, expectedIds)
+	}
+
+	checkpoint, _ := store.Load(context.Background())
+	expected := &ExportCheckpoint{SinceId: 5}
+	if !reflect.DeepEqual(checkpoint, expected) {
+		t.Errorf("OrderExporter checkpoint = %#v, expected %#v", checkpoint, expected)
+	}
+
+	// no order had an updated_at, so there is no updated_at watermark
+	b, _ := os.ReadFile(store.Path)
+	if expected := `{"since_id":5}`; string(b) != expected {
+		t.Errorf("OrderExporter saved checkpoint %s, expected %s", b, expected)
+	}
+}
+
+func TestOrderExporterResume(t *testing.T) {
+	setup()
+	defer teardown()
+
+	listURL := fmt.Sprintf("https://fooshop.myshopify.com/%s/orders.json", client.pathPrefix)
+	httpmock.RegisterResponder("GET", listURL+"?page_info=pg2", func(req *http.Request) (*http.Response, error) {
+		resp := httpmock.NewStringResponse(http.StatusOK, `{"orders": [{"id":3}]}`)
+		resp.Header.Add("Link", `<http://valid.url?page_info=pg3>; rel="next"`)
+		return resp, nil
+	})
+	httpmock.RegisterResponder("GET", listURL+"?page_info=pg3",
+		httpmock.NewStringResponder(http.StatusInternalServerError, `{"errors":"boom"}`))
+
+	store := NewFileCheckpointStore(filepath.Join(t.TempDir(), "checkpoint.json"))
+	_ = store.Save(context.Background(), ExportCheckpoint{PageInfo: "pg2", SinceId: 2})
+
+	ids, err := collectExport(NewOrderExporter(client, store, OrderListOptions{}))
+	if err == nil || err.Error() != "boom" {
+		t.Errorf("OrderExporter.Export error = %v, expected boom", err)
+	}
+
+	expectedIds := []uint64{3}
+	if !reflect.DeepEqual(ids, expectedIds) {
+		t.Errorf("OrderExporter.Export ids = %v, expected %v", ids, expectedIds)
+	}
+
+	checkpoint, _ := store.Load(context.Background())
+	expected := &ExportCheckpoint{PageInfo: "pg3", SinceId: 3}
+	if !reflect.DeepEqual(checkpoint, expected) {
+		t.Errorf("OrderExporter checkpoint = %#v, expected %#v", checkpoint, expected)
+	}
+}
+
+func TestOrderExporterWatermark(t *testing.T) {
+	setup()
+	defer teardown()
+
+	updatedAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	listURL := fmt.Sprintf("https://fooshop.myshopify.com/%s/orders.json", client.pathPrefix)
+	httpmock.RegisterResponder("GET", listURL+"?status=any&updated_at_min=2024-01-02T03%3A04%3A05Z",
+		httpmock.NewStringResponder(http.StatusOK, `{"orders": [{"id":7,"updated_at":"2024-02-01T00:00:00Z"}]}`))
+
+	store := NewFileCheckpointStore(filepath.Join(t.TempDir(), "checkpoint.json"))
+	_ = store.Save(context.Background(), ExportCheckpoint{SinceId: 5, UpdatedAtMin: &updatedAt})
+
+	ids, err := collectExport(NewOrderExporter(client, store, OrderListOptions{Status: OrderStatusAny}))
+	if err != nil {
+		t.Fatalf("OrderExporter.Export returned error: %v", err)
+	}
+
+	expectedIds := []uint64{7}
+	if !reflect.DeepEqual(ids, expectedIds) {
+		t.Errorf("OrderExporter.Export ids = %v, expected %v", ids, expectedIds)
+	}
+
+	checkpoint, _ := store.Load(context.Background())
+	expectedUpdatedAt := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+	expected := &ExportCheckpoint{SinceId: 7, UpdatedAtMin: &expectedUpdatedAt}
+	if !reflect.DeepEqual(checkpoint, expected) {
+		t.Errorf("OrderExporter checkpoint = %#v, expected %#v", checkpoint, expected)
+	}
+}
+
+func TestOrderExporterCancel(t *testing.T) {
+	setup()
+	defer teardown()
+
+	registerOrderPages()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	store := NewFileCheckpointStore(filepath.Join(t.TempDir(), "checkpoint.json"))
+	orders, errs := NewOrderExporter(client, store, OrderListOptions{}).Export(ctx)
+
+	<-orders
+	cancel()
+
+	for range orders {
+	}
+
+	if err := <-errs; err != context.Canceled {
+		t.Errorf("OrderExporter.Export error = %v, expected %v", err, context.Canceled)
+	}
+}
+
+// registerOrderRanges registers the pages of the orders created in the two
+// halves of the first ten seconds of 2024
+func registerOrderRanges() {
+	listURL := fmt.Sprintf("https://fooshop.myshopify.com/%s/orders.json", client.pathPrefix)
+	pages := []struct {
+		query string
+		link  string
+		body  string
+	}{
+		{"created_at_max=2024-01-01T00%3A00%3A04Z&created_at_min=2024-01-01T00%3A00%3A00Z", `<http://valid.url?page_info=a2>; rel="next"`, `{"orders": [{"id":1},{"id":2}]}`},
+		{"page_info=a2", "", `{"orders": [{"id":3}]}`},
+		{"created_at_max=2024-01-01T00%3A00%3A10Z&created_at_min=2024-01-01T00%3A00%3A05Z", "", `{"orders": [{"id":4},{"id":5}]}`},
+	}
+
+	for _, p := range pages {
+		p := p
+		httpmock.RegisterResponder("GET", listURL+"?"+p.query, func(req *http.Request) (*http.Response, error) {
+			resp := httpmock.NewStringResponse(http.StatusOK, p.body)
+			if p.link != "" {
+				resp.Header.Add("Link", p.link)
+			}
+			return resp, nil
+		})
+	}
+}
+
+func TestOrderExporterConcurrency(t *testing.T) {
+	setup()
+	defer teardown()
+
+	registerOrderRanges()
+	listURL := fmt.Sprintf("https://fooshop.myshopify.com/%s/orders.json", client.pathPrefix)
+	httpmock.RegisterResponder("GET", listURL+"?created_at_max=2024-01-01T00%3A00%3A10Z&fields=id%2Ccreated_at&limit=1&order=created_at+asc",
+		httpmock.NewStringResponder(http.StatusOK, `{"orders": [{"id":1,"created_at":"2024-01-01T00:00:00Z"}]}`))
+
+	store := NewFileCheckpointStore(filepath.Join(t.TempDir(), "checkpoint.json"))
+	exporter := NewOrderExporter(client, store, OrderListOptions{
+		ListOptions: ListOptions{CreatedAtMax: time.Date(2024, 1, 1, 0, 0, 10, 0, time.UTC)},
+	})
+	exporter.Concurrency = 2
+
+	ids, err := collectExport(exporter)
+	if err != nil {
+		t.Fatalf("OrderExporter.Export returned error: %v", err)
+	}
+
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	expectedIds := []uint64{1, 2, 3, 4, 5}
+	if !reflect.DeepEqual(ids, expectedIds) {
+		t.Errorf("OrderExporter.Export ids = %v, expected %v", ids, expectedIds)
+	}
+
+	checkpoint, _ := store.Load(context.Background())
+	expected := &ExportCheckpoint{SinceId: 5}
+	if !reflect.DeepEqual(checkpoint, expected) {
+		t.Errorf("OrderExporter checkpoint = %#v, expected %#v", checkpoint, expected)
+	}
+}
+
+func TestOrderExporterResumeRanges(t *testing.T) {
+	setup()
+	defer teardown()
+
+	registerOrderRanges()
+
+	store := NewFileCheckpointStore(filepath.Join(t.TempDir(), "checkpoint.json"))
+	_ = store.Save(context.Background(), ExportCheckpoint{
+		SinceId: 5,
+		Ranges: []ExportRange{
+			{CreatedAtMin: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), CreatedAtMax: time.Date(2024, 1, 1, 0, 0, 4, 0, time.UTC), PageInfo: "a2"},
+			{CreatedAtMin: time.Date(2024, 1, 1, 0, 0, 5, 0, time.UTC), CreatedAtMax: time.Date(2024, 1, 1, 0, 0, 10, 0, time.UTC), Done: true},
+		},
+	})
+
+	ids, err := collectExport(NewOrderExporter(client, store, OrderListOptions{}))
+	if err != nil {
+		t.Fatalf("OrderExporter.Export returned error: %v", err)
+	}
+
+	expectedIds := []uint64{3}
+	if !reflect.DeepEqual(ids, expectedIds) {
+		t.Errorf("OrderExporter.Export ids = %v, expected %v", ids, expectedIds)
+	}
+
+	checkpoint, _ := store.Load(context.Background())
+	expected := &ExportCheckpoint{SinceId: 5}
+	if !reflect.DeepEqual(checkpoint, expected) {
+		t.Errorf("OrderExporter checkpoint = %#v, expected %#v", checkpoint, expected)
+	}
+}
@@ -17,6 +17,8 @@ type OrderRiskService interface {
 	List(context.Context, uint64, interface{}) ([]OrderRisk, error)
 	ListAll(context.Context, uint64, interface{}) ([]OrderRisk, error)
 	ListWithPagination(context.Context, uint64, interface{}) ([]OrderRisk, *Pagination, error)
+	Pages(uint64, interface{}) *PageIterator[OrderRisk]
+	Iter(uint64, interface{}) *ListIterator[OrderRisk]
 	Get(context.Context, uint64, uint64, interface{}) (*OrderRisk, error)
 	Create(context.Context, uint64, OrderRisk) (*OrderRisk, error)
 	Update(context.Context, uint64, uint64, OrderRisk) (*OrderRisk, error)
@@ -86,25 +88,23 @@ func (s *OrderRiskServiceOp) List(ctx context.Context, orderId uint64, options i
 
 // ListAll Lists all OrderRisk, iterating over pages
 func (s *OrderRiskServiceOp) ListAll(ctx context.Context, orderId uint64, options interface{}) ([]OrderRisk, error) {
-	collector := []OrderRisk{}
-
-	for {
-		entities, pagination, err := s.ListWithPagination(ctx, orderId, options)
-
-		if err != nil {
-			return collector, err
-		}
-
-		collector = append(collector, entities...)
-
-		if pagination.NextPageOptions == nil {
-			break
-		}
+	return listAll(ctx, func(ctx context.Context, options interface{}) ([]OrderRisk, *Pagination, error) {
+		return s.ListWithPagination(ctx, orderId, options)
+	}, options)
+}
 
-		options = pagination.NextPageOptions
-	}
+// Pages returns an iterator lazily walking the pages of OrderRisk
+func (s *OrderRiskServiceOp) Pages(orderId uint64, options interface{}) *PageIterator[OrderRisk] {
+	return newPageIterator(func(ctx context.Context, options interface{}) ([]OrderRisk, *Pagination, error) {
+		return s.ListWithPagination(ctx, orderId, options)
+	}, options)
+}
 
-	return collector, nil
+// Iter returns an iterator lazily walking OrderRisk one at a time
+func (s *OrderRiskServiceOp) Iter(orderId uint64, options interface{}) *ListIterator[OrderRisk] {
+	return newListIterator(func(ctx context.Context, options interface{}) ([]OrderRisk, *Pagination, error) {
+		return s.ListWithPagination(ctx, orderId, options)
+	}, options)
 }
 
 func (s *OrderRiskServiceOp) ListWithPagination(ctx context.Context, orderId uint64, options interface{}) ([]OrderRisk, *Pagination, error) {
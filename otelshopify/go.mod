@@ -0,0 +1,26 @@
+module github.com/bold-commerce/go-shopify/v4/otelshopify
+
+go 1.21
+
+require (
+	github.com/bold-commerce/go-shopify/v4 v4.0.1-0.20261017020432-c821443e4099
+	github.com/jarcoal/httpmock v1.3.0
+	go.opentelemetry.io/otel v1.28.0
+	go.opentelemetry.io/otel/metric v1.28.0
+	go.opentelemetry.io/otel/sdk v1.28.0
+	go.opentelemetry.io/otel/sdk/metric v1.28.0
+	go.opentelemetry.io/otel/trace v1.28.0
+)
+
+require (
+	github.com/go-logr/logr v1.4.2 // indirect
+	github.com/go-logr/stdr v1.2.2 // indirect
+	github.com/google/go-querystring v1.0.0 // indirect
+	github.com/google/uuid v1.6.0 // indirect
+	github.com/shopspring/decimal v0.0.0-20200105231215-408a2507e114 // indirect
+	golang.org/x/sys v0.21.0 // indirect
+)
+
+// builds against the root module of this repository, the replacement is
+// ignored by the modules requiring otelshopify
+replace github.com/bold-commerce/go-shopify/v4 => ../
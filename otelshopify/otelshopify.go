@@ -0,0 +1,284 @@
+// Package otelshopify instruments a goshopify.Client with OpenTelemetry
+// spans and metrics.
+//
+//	client, err := goshopify.NewClient(app, "shopname", "token",
+//		goshopify.WithMiddleware(otelshopify.NewMiddleware()))
+//
+// Every API call is recorded as a client span carrying the shop, API version,
+// resource, HTTP status, retry count and GraphQL query cost, and counted in
+// the following instruments, whose attributes leave out the shop to keep
+// their cardinality bounded:
+//
+//   - shopify.client.duration, a histogram of the call latency in seconds
+//   - shopify.client.rate_limited, the number of attempts rejected by a rate
+//     limit, i.e. 429 responses and throttled GraphQL queries
+//   - shopify.client.graphql.cost, the GraphQL points spent by queries
+//   - shopify.client.graphql.throttled_cost, the GraphQL points requested by
+//     queries which were throttled
+package otelshopify
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+
+	goshopify "github.com/bold-commerce/go-shopify/v4"
+)
+
+const instrumentationName = "github.com/bold-commerce/go-shopify/v4/otelshopify"
+
+// Attribute keys set on spans and metrics
+const (
+	ShopKey          = attribute.Key("shopify.shop")
+	ApiVersionKey    = attribute.Key("shopify.api_version")
+	ResourceKey      = attribute.Key("shopify.resource")
+	RequestIdKey     = attribute.Key("shopify.request_id")
+	RetryCountKey    = attribute.Key("shopify.retry_count")
+	RateLimitedKey   = attribute.Key("shopify.rate_limited")
+	RequestedCostKey = attribute.Key("shopify.graphql.requested_cost")
+	ActualCostKey    = attribute.Key("shopify.graphql.actual_cost")
+	AvailableCostKey = attribute.Key("shopify.graphql.available_cost")
+)
+
+// semantic convention keys
+const (
+	httpMethodKey     = attribute.Key("http.request.method")
+	httpStatusCodeKey = attribute.Key("http.response.status_code")
+	urlFullKey        = attribute.Key("url.full")
+	serverAddressKey  = attribute.Key("server.address")
+	errorTypeKey      = attribute.Key("error.type")
+)
+
+const defaultResourceName = "unknown"
+
+var (
+	// matches the prefix of versioned and unversioned admin api paths
+	apiPrefixRegex = regexp.MustCompile(`^/?admin/(api/([^/]+)/)?`)
+
+	// matches path segments that are ids
+	idSegmentRegex = regexp.MustCompile(`(^|/)\d+(/|\.json$|$)`)
+)
+
+// Option configures the middleware returned by NewMiddleware
+type Option func(c *config)
+
+type config struct {
+	tracerProvider trace.TracerProvider
+	meterProvider  metric.MeterProvider
+}
+
+// WithTracerProvider sets the provider spans are created with, defaults to
+// the global provider
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return func(c *config) {
+		c.tracerProvider = provider
+	}
+}
+
+// WithMeterProvider sets the provider instruments are created with, defaults
+// to the global provider
+func WithMeterProvider(provider metric.MeterProvider) Option {
+	return func(c *config) {
+		c.meterProvider = provider
+	}
+}
+
+type instruments struct {
+	tracer         trace.Tracer
+	duration       metric.Float64Histogram
+	rateLimited    metric.Int64Counter
+	graphQLCost    metric.Int64Counter
+	throttledCost  metric.Int64Counter
+	metricsEnabled bool
+}
+
+// NewMiddleware returns a goshopify.Middleware recording every API call made
+// by a client as a span and in metrics, see WithMiddleware.
+func NewMiddleware(opts ...Option) goshopify.Middleware {
+	c := config{
+		tracerProvider: otel.GetTracerProvider(),
+		meterProvider:  otel.GetMeterProvider(),
+	}
+	for _, opt := range opts {
+		opt(&c)
+	}
+
+	i := newInstruments(c)
+
+	return func(next goshopify.Handler) goshopify.Handler {
+		return func(req *http.Request) (*goshopify.Response, error) {
+			return i.record(next, req)
+		}
+	}
+}
+
+func newInstruments(c config) *instruments {
+	i := &instruments{
+		tracer: c.tracerProvider.Tracer(instrumentationName),
+	}
+
+	meter := c.meterProvider.Meter(instrumentationName)
+
+	var err error
+	i.duration, err = meter.Float64Histogram("shopify.client.duration",
+		metric.WithDescription("Duration of Shopify API calls, including retries"),
+		metric.WithUnit("s"))
+	if err != nil {
+		otel.Handle(err)
+		return i
+	}
+
+	i.rateLimited, err = meter.Int64Counter("shopify.client.rate_limited",
+		metric.WithDescription("Number of attempts rejected by a Shopify rate limit"),
+		metric.WithUnit("{attempt}"))
+	if err != nil {
+		otel.Handle(err)
+		return i
+	}
+
+	i.graphQLCost, err = meter.Int64Counter("shopify.client.graphql.cost",
+		metric.WithDescription("GraphQL query cost points spent"),
+		metric.WithUnit("{point}"))
+	if err != nil {
+		otel.Handle(err)
+		return i
+	}
+
+	i.throttledCost, err = meter.Int64Counter("shopify.client.graphql.throttled_cost",
+		metric.WithDescription("GraphQL query cost points requested by throttled queries"),
+		metric.WithUnit("{point}"))
+	if err != nil {
+		otel.Handle(err)
+		return i
+	}
+
+	i.metricsEnabled = true
+	return i
+}
+
+func (i *instruments) record(next goshopify.Handler, req *http.Request) (*goshopify.Response, error) {
+	resource, version := Resource(req.URL.Path)
+
+	// attrs are shared by spans and metrics, the shop is left out of the
+	// metrics as an app may be installed on any number of shops
+	attrs := []attribute.KeyValue{
+		ResourceKey.String(resource),
+		httpMethodKey.String(req.Method),
+	}
+
+	ctx, span := i.tracer.Start(req.Context(), fmt.Sprintf("%s %s", req.Method, resource),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attrs...),
+		trace.WithAttributes(
+			ShopKey.String(req.URL.Hostname()),
+			urlFullKey.String(req.URL.Redacted()),
+			serverAddressKey.String(req.URL.Hostname()),
+		))
+	defer span.End()
+
+	start := time.Now()
+	res, err := next(req.WithContext(ctx))
+	elapsed := time.Since(start)
+
+	if res == nil {
+		res = &goshopify.Response{}
+	}
+
+	if res.ApiVersion != "" {
+		version = res.ApiVersion
+	}
+	if version != "" {
+		attrs = append(attrs, ApiVersionKey.String(version))
+	}
+	if res.StatusCode != 0 {
+		attrs = append(attrs, httpStatusCodeKey.Int(res.StatusCode))
+	}
+	if err != nil {
+		attrs = append(attrs, errorTypeKey.String(errorType(res, err)))
+	}
+
+	span.SetAttributes(attrs...)
+	span.SetAttributes(
+		RetryCountKey.Int(max(res.Attempts-1, 0)),
+		RateLimitedKey.Int(res.RateLimited),
+	)
+	if res.RequestId != "" {
+		span.SetAttributes(RequestIdKey.String(res.RequestId))
+	}
+
+	cost := res.RateLimits.GraphQLCost
+	if cost != nil {
+		span.SetAttributes(
+			RequestedCostKey.Int(cost.RequestedQueryCost),
+			AvailableCostKey.Float64(cost.ThrottleStatus.CurrentlyAvailable),
+		)
+		if cost.ActualQueryCost != nil {
+			span.SetAttributes(ActualCostKey.Int(*cost.ActualQueryCost))
+		}
+	}
+
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	if !i.metricsEnabled {
+		return res, err
+	}
+
+	set := metric.WithAttributeSet(attribute.NewSet(attrs...))
+	i.duration.Record(ctx, elapsed.Seconds(), set)
+	if res.RateLimited > 0 {
+		i.rateLimited.Add(ctx, int64(res.RateLimited), set)
+	}
+	if cost != nil {
+		if cost.ActualQueryCost != nil {
+			i.graphQLCost.Add(ctx, int64(*cost.ActualQueryCost), set)
+		}
+		// the throttled attempts requested the cost of the same query
+		if res.RateLimited > 0 {
+			i.throttledCost.Add(ctx, int64(cost.RequestedQueryCost*res.RateLimited), set)
+		}
+	}
+
+	return res, err
+}
+
+// Resource returns the resource of an admin API path, with ids replaced by
+// {id} to keep the cardinality of metrics low, and the API version of the
+// path if it is versioned, e.g. "/admin/api/2024-01/products/123.json"
+// returns "products/{id}.json" and "2024-01".
+func Resource(path string) (resource, version string) {
+	if m := apiPrefixRegex.FindStringSubmatch(path); m != nil {
+		version = m[2]
+		path = path[len(m[0]):]
+	}
+
+	path = strings.TrimPrefix(path, "/")
+	if path == "" {
+		return defaultResourceName, version
+	}
+
+	// replace twice as adjacent ids share the separating slash
+	for j := 0; j < 2; j++ {
+		path = idSegmentRegex.ReplaceAllString(path, "$1{id}$2")
+	}
+
+	return path, version
+}
+
+// errorType returns a low cardinality description of a failed call
+func errorType(res *goshopify.Response, err error) string {
+	if res.StatusCode >= http.StatusBadRequest {
+		return fmt.Sprint(res.StatusCode)
+	}
+	return fmt.Sprintf("%T", err)
+}
@@ -0,0 +1,250 @@
+package otelshopify
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/jarcoal/httpmock"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	goshopify "github.com/bold-commerce/go-shopify/v4"
+)
+
+const apiVersion = "2024-01"
+
+type testEnv struct {
+	client *goshopify.Client
+	spans  *tracetest.SpanRecorder
+	reader *sdkmetric.ManualReader
+}
+
+func setup(t *testing.T) testEnv {
+	spans := tracetest.NewSpanRecorder()
+	reader := sdkmetric.NewManualReader()
+
+	middleware := NewMiddleware(
+		WithTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spans))),
+		WithMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))),
+	)
+
+	client := goshopify.MustNewClient(goshopify.App{}, "fooshop", "abcd",
+		goshopify.WithVersion(apiVersion),
+		goshopify.WithRetry(3),
+		goshopify.WithMiddleware(middleware))
+	httpmock.ActivateNonDefault(client.Client)
+	t.Cleanup(httpmock.DeactivateAndReset)
+
+	return testEnv{client: client, spans: spans, reader: reader}
+}
+
+func (e testEnv) span(t *testing.T) sdktrace.ReadOnlySpan {
+	t.Helper()
+
+	spans := e.spans.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("recorded %d spans, expected 1", len(spans))
+	}
+	return spans[0]
+}
+
+func (e testEnv) metrics(t *testing.T) map[string]metricdata.Aggregation {
+	t.Helper()
+
+	var rm metricdata.ResourceMetrics
+	if err := e.reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatalf("Collect returned error: %v", err)
+	}
+
+	metrics := map[string]metricdata.Aggregation{}
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			metrics[m.Name] = m.Data
+		}
+	}
+	return metrics
+}
+
+func assertAttributes(t *testing.T, attrs []attribute.KeyValue, expected ...attribute.KeyValue) {
+	t.Helper()
+
+	set := attribute.NewSet(attrs...)
+	for _, kv := range expected {
+		v, ok := set.Value(kv.Key)
+		if !ok {
+			t.Errorf("missing attribute %s", kv.Key)
+		} else if v != kv.Value {
+			t.Errorf("attribute %s = %s, expected %s", kv.Key, v.Emit(), kv.Value.Emit())
+		}
+	}
+}
+
+func sum(t *testing.T, data metricdata.Aggregation) int64 {
+	t.Helper()
+
+	s, ok := data.(metricdata.Sum[int64])
+	if !ok {
+		t.Fatalf("metric is %T, expected an int64 sum", data)
+	}
+
+	var total int64
+	for _, dp := range s.DataPoints {
+		total += dp.Value
+	}
+	return total
+}
+
+func TestMiddlewareREST(t *testing.T) {
+	e := setup(t)
+
+	attempts := 0
+	httpmock.RegisterResponder("GET", "https://fooshop.myshopify.com/admin/api/2024-01/products/1.json",
+		func(req *http.Request) (*http.Response, error) {
+			if !trace.SpanContextFromContext(req.Context()).IsValid() {
+				t.Errorf("request context does not carry the span")
+			}
+
+			attempts++
+			if attempts == 1 {
+				resp := httpmock.NewStringResponse(http.StatusTooManyRequests, `{"errors": "Exceeded 2 calls per second"}`)
+				resp.Header.Set("Retry-After", "0")
+				return resp, nil
+			}
+
+			resp := httpmock.NewStringResponse(http.StatusOK, `{"product": {"id": 1}}`)
+			resp.Header.Set("X-Request-Id", "abc-123")
+			resp.Header.Set("X-Shopify-API-Version", apiVersion)
+			return resp, nil
+		})
+
+	_, err := e.client.Product.Get(context.Background(), 1, nil)
+	if err != nil {
+		t.Fatalf("Product.Get returned error: %v", err)
+	}
+
+	span := e.span(t)
+	if span.Name() != "GET products/{id}.json" {
+		t.Errorf("span name = %s, expected GET products/{id}.json", span.Name())
+	}
+	if span.SpanKind() != trace.SpanKindClient {
+		t.Errorf("span kind = %s, expected client", span.SpanKind())
+	}
+	assertAttributes(t, span.Attributes(),
+		ShopKey.String("fooshop.myshopify.com"),
+		ApiVersionKey.String(apiVersion),
+		ResourceKey.String("products/{id}.json"),
+		RequestIdKey.String("abc-123"),
+		RetryCountKey.Int(1),
+		RateLimitedKey.Int(1),
+		httpStatusCodeKey.Int(http.StatusOK),
+	)
+
+	metrics := e.metrics(t)
+	duration, ok := metrics["shopify.client.duration"].(metricdata.Histogram[float64])
+	if !ok || len(duration.DataPoints) != 1 || duration.DataPoints[0].Count != 1 {
+		t.Fatalf("shopify.client.duration = %#v, expected a single call", metrics["shopify.client.duration"])
+	}
+	if _, ok := duration.DataPoints[0].Attributes.Value(ShopKey); ok {
+		t.Errorf("shopify.client.duration has the %s attribute, expected the shop on spans only", ShopKey)
+	}
+	if rateLimited := sum(t, metrics["shopify.client.rate_limited"]); rateLimited != 1 {
+		t.Errorf("shopify.client.rate_limited = %d, expected 1", rateLimited)
+	}
+}
+
+func TestMiddlewareError(t *testing.T) {
+	e := setup(t)
+
+	httpmock.RegisterResponder("GET", "https://fooshop.myshopify.com/admin/api/2024-01/products/1.json",
+		httpmock.NewStringResponder(http.StatusNotFound, `{"errors": "Not Found"}`))
+
+	_, err := e.client.Product.Get(context.Background(), 1, nil)
+	if err == nil {
+		t.Fatalf("Product.Get should have returned an error")
+	}
+
+	span := e.span(t)
+	if span.Status().Code != codes.Error {
+		t.Errorf("span status = %v, expected error", span.Status())
+	}
+	assertAttributes(t, span.Attributes(),
+		httpStatusCodeKey.Int(http.StatusNotFound),
+		errorTypeKey.String("404"),
+	)
+}
+
+func TestMiddlewareGraphQL(t *testing.T) {
+	e := setup(t)
+
+	attempts := 0
+	httpmock.RegisterResponder("POST", "https://fooshop.myshopify.com/admin/api/2024-01/graphql.json",
+		func(req *http.Request) (*http.Response, error) {
+			attempts++
+			if attempts == 1 {
+				return httpmock.NewStringResponse(http.StatusOK, `{
+					"errors": [{"message": "Throttled", "extensions": {"code": "THROTTLED"}}],
+					"extensions": {"cost": {"requestedQueryCost": 20, "throttleStatus": {"maximumAvailable": 1000, "currentlyAvailable": 20, "restoreRate": 50}}}
+				}`), nil
+			}
+			return httpmock.NewStringResponse(http.StatusOK, `{
+				"data": {},
+				"extensions": {"cost": {"requestedQueryCost": 20, "actualQueryCost": 12, "throttleStatus": {"maximumAvailable": 1000, "currentlyAvailable": 988, "restoreRate": 50}}}
+			}`), nil
+		})
+
+	err := e.client.GraphQL.Query(context.Background(), "query", nil, nil)
+	if err != nil {
+		t.Fatalf("GraphQL.Query returned error: %v", err)
+	}
+
+	// the throttled attempt is retried within the span of the query
+	spans := e.spans.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("recorded %d spans, expected 1", len(spans))
+	}
+	assertAttributes(t, spans[0].Attributes(),
+		ResourceKey.String("graphql.json"),
+		RetryCountKey.Int(1),
+		RateLimitedKey.Int(1),
+		RequestedCostKey.Int(20),
+		ActualCostKey.Int(12),
+		AvailableCostKey.Float64(988),
+	)
+
+	metrics := e.metrics(t)
+	if cost := sum(t, metrics["shopify.client.graphql.cost"]); cost != 12 {
+		t.Errorf("shopify.client.graphql.cost = %d, expected 12", cost)
+	}
+	if throttled := sum(t, metrics["shopify.client.graphql.throttled_cost"]); throttled != 20 {
+		t.Errorf("shopify.client.graphql.throttled_cost = %d, expected 20", throttled)
+	}
+}
+
+func TestResource(t *testing.T) {
+	cases := []struct {
+		path     string
+		resource string
+		version  string
+	}{
+		{"/admin/api/2024-01/products/123.json", "products/{id}.json", "2024-01"},
+		{"/admin/api/unstable/products/123/variants/456.json", "products/{id}/variants/{id}.json", "unstable"},
+		{"/admin/orders/1/2/3.json", "orders/{id}/{id}/{id}.json", ""},
+		{"/admin/api/2024-01/graphql.json", "graphql.json", "2024-01"},
+		{"/admin/api/2024-01/products/count.json", "products/count.json", "2024-01"},
+		{"/admin/oauth/access_token", "oauth/access_token", ""},
+		{"/", "unknown", ""},
+	}
+
+	for _, c := range cases {
+		resource, version := Resource(c.path)
+		if resource != c.resource || version != c.version {
+			t.Errorf("Resource(%q) = %q, %q, expected %q, %q", c.path, resource, version, c.resource, c.version)
+		}
+	}
+}
@@ -14,6 +14,8 @@ type PaymentsTransactionsService interface {
 	List(context.Context, interface{}) ([]PaymentsTransactions, error)
 	ListAll(context.Context, interface{}) ([]PaymentsTransactions, error)
 	ListWithPagination(context.Context, interface{}) ([]PaymentsTransactions, *Pagination, error)
+	Pages(interface{}) *PageIterator[PaymentsTransactions]
+	Iter(interface{}) *ListIterator[PaymentsTransactions]
 	Get(context.Context, uint64, interface{}) (*PaymentsTransactions, error)
 }
 
@@ -91,25 +93,17 @@ func (s *PaymentsTransactionsServiceOp) List(ctx context.Context, options interf
 
 // ListAll Lists all PaymentsTransactions, iterating over pages
 func (s *PaymentsTransactionsServiceOp) ListAll(ctx context.Context, options interface{}) ([]PaymentsTransactions, error) {
-	collector := []PaymentsTransactions{}
-
-	for {
-		entities, pagination, err := s.ListWithPagination(ctx, options)
-
-		if err != nil {
-			return collector, err
-		}
-
-		collector = append(collector, entities...)
-
-		if pagination.NextPageOptions == nil {
-			break
-		}
+	return listAll(ctx, s.ListWithPagination, options)
+}
 
-		options = pagination.NextPageOptions
-	}
+// Pages returns an iterator lazily walking the pages of PaymentsTransactions
+func (s *PaymentsTransactionsServiceOp) Pages(options interface{}) *PageIterator[PaymentsTransactions] {
+	return newPageIterator(s.ListWithPagination, options)
+}
 
-	return collector, nil
+// Iter returns an iterator lazily walking PaymentsTransactions one at a time
+func (s *PaymentsTransactionsServiceOp) Iter(options interface{}) *ListIterator[PaymentsTransactions] {
+	return newListIterator(s.ListWithPagination, options)
 }
 
 func (s *PaymentsTransactionsServiceOp) ListWithPagination(ctx context.Context, options interface{}) ([]PaymentsTransactions, *Pagination, error) {
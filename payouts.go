@@ -16,6 +16,8 @@ type PayoutsService interface {
 	List(context.Context, interface{}) ([]Payout, error)
 	ListAll(context.Context, interface{}) ([]Payout, error)
 	ListWithPagination(context.Context, interface{}) ([]Payout, *Pagination, error)
+	Pages(interface{}) *PageIterator[Payout]
+	Iter(interface{}) *ListIterator[Payout]
 	Get(context.Context, uint64, interface{}) (*Payout, error)
 }
 
@@ -78,25 +80,17 @@ func (s *PayoutsServiceOp) List(ctx context.Context, options interface{}) ([]Pay
 
 // ListAll Lists all payouts, iterating over pages
 func (s *PayoutsServiceOp) ListAll(ctx context.Context, options interface{}) ([]Payout, error) {
-	collector := []Payout{}
-
-	for {
-		entities, pagination, err := s.ListWithPagination(ctx, options)
-
-		if err != nil {
-			return collector, err
-		}
-
-		collector = append(collector, entities...)
-
-		if pagination.NextPageOptions == nil {
-			break
-		}
+	return listAll(ctx, s.ListWithPagination, options)
+}
 
-		options = pagination.NextPageOptions
-	}
+// Pages returns an iterator lazily walking the pages of payouts
+func (s *PayoutsServiceOp) Pages(options interface{}) *PageIterator[Payout] {
+	return newPageIterator(s.ListWithPagination, options)
+}
 
-	return collector, nil
+// Iter returns an iterator lazily walking payouts one at a time
+func (s *PayoutsServiceOp) Iter(options interface{}) *ListIterator[Payout] {
+	return newListIterator(s.ListWithPagination, options)
 }
 
 func (s *PayoutsServiceOp) ListWithPagination(ctx context.Context, options interface{}) ([]Payout, *Pagination, error) {
@@ -22,6 +22,8 @@ type ProductService interface {
 	List(context.Context, interface{}) ([]Product, error)
 	ListAll(context.Context, interface{}) ([]Product, error)
 	ListWithPagination(context.Context, interface{}) ([]Product, *Pagination, error)
+	Pages(interface{}) *PageIterator[Product]
+	Iter(interface{}) *ListIterator[Product]
 	Count(context.Context, interface{}) (int, error)
 	Get(context.Context, uint64, interface{}) (*Product, error)
 	Create(context.Context, Product) (*Product, error)
@@ -132,25 +134,17 @@ func (s *ProductServiceOp) List(ctx context.Context, options interface{}) ([]Pro
 
 // ListAll Lists all products, iterating over pages
 func (s *ProductServiceOp) ListAll(ctx context.Context, options interface{}) ([]Product, error) {
-	collector := []Product{}
-
-	for {
-		entities, pagination, err := s.ListWithPagination(ctx, options)
-
-		if err != nil {
-			return collector, err
-		}
-
-		collector = append(collector, entities...)
-
-		if pagination.NextPageOptions == nil {
-			break
-		}
+	return listAll(ctx, s.ListWithPagination, options)
+}
 
-		options = pagination.NextPageOptions
-	}
+// Pages returns an iterator lazily walking the pages of products
+func (s *ProductServiceOp) Pages(options interface{}) *PageIterator[Product] {
+	return newPageIterator(s.ListWithPagination, options)
+}
 
-	return collector, nil
+// Iter returns an iterator lazily walking products one at a time
+func (s *ProductServiceOp) Iter(options interface{}) *ListIterator[Product] {
+	return newListIterator(s.ListWithPagination, options)
 }
 
 // ListWithPagination lists products and return pagination to retrieve next/previous results.
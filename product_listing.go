@@ -15,6 +15,8 @@ type ProductListingService interface {
 	List(context.Context, interface{}) ([]ProductListing, error)
 	ListAll(context.Context, interface{}) ([]ProductListing, error)
 	ListWithPagination(context.Context, interface{}) ([]ProductListing, *Pagination, error)
+	Pages(interface{}) *PageIterator[ProductListing]
+	Iter(interface{}) *ListIterator[ProductListing]
 	Count(context.Context, interface{}) (int, error)
 	Get(context.Context, uint64, interface{}) (*ProductListing, error)
 	GetProductIds(context.Context, interface{}) ([]uint64, error)
@@ -86,25 +88,17 @@ func (s *ProductListingServiceOp) List(ctx context.Context, options interface{})
 
 // ListAll Lists all products, iterating over pages
 func (s *ProductListingServiceOp) ListAll(ctx context.Context, options interface{}) ([]ProductListing, error) {
-	collector := []ProductListing{}
-
-	for {
-		entities, pagination, err := s.ListWithPagination(ctx, options)
-
-		if err != nil {
-			return collector, err
-		}
-
-		collector = append(collector, entities...)
-
-		if pagination.NextPageOptions == nil {
-			break
-		}
+	return listAll(ctx, s.ListWithPagination, options)
+}
 
-		options = pagination.NextPageOptions
-	}
+// Pages returns an iterator lazily walking the pages of product listings
+func (s *ProductListingServiceOp) Pages(options interface{}) *PageIterator[ProductListing] {
+	return newPageIterator(s.ListWithPagination, options)
+}
 
-	return collector, nil
+// Iter returns an iterator lazily walking product listings one at a time
+func (s *ProductListingServiceOp) Iter(options interface{}) *ListIterator[ProductListing] {
+	return newListIterator(s.ListWithPagination, options)
 }
 
 // ListWithPagination lists products and return pagination to retrieve next/previous results.
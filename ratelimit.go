@@ -0,0 +1,168 @@
+package goshopify
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const callLimitHeader = "X-Shopify-Shop-Api-Call-Limit"
+
+// RateLimiter is used to throttle REST API requests before they are sent,
+// see WithRateLimiter.
+type RateLimiter interface {
+	// Wait blocks until a request may be sent or the context is done.
+	Wait(context.Context) error
+
+	// Observe is called with the bucket state reported by Shopify in the
+	// X-Shopify-Shop-Api-Call-Limit header of every response.
+	Observe(used, size int)
+}
+
+// RateLimitPlan describes the REST API leaky bucket of a Shopify plan.
+// See https://shopify.dev/docs/api/usage/rate-limits#rest-admin-api-rate-limits
+type RateLimitPlan struct {
+	// BucketSize is the number of requests that can be made in a burst.
+	BucketSize int
+	// LeakRate is the number of requests per second leaking out of the bucket.
+	LeakRate float64
+}
+
+var (
+	// RateLimitPlanStandard is the bucket of the standard Shopify plans
+	RateLimitPlanStandard = RateLimitPlan{BucketSize: 40, LeakRate: 2}
+
+	// RateLimitPlanAdvanced is the bucket of the Advanced Shopify plan
+	RateLimitPlanAdvanced = RateLimitPlan{BucketSize: 80, LeakRate: 4}
+
+	// RateLimitPlanPlus is the bucket of the Shopify Plus plan
+	RateLimitPlanPlus = RateLimitPlan{BucketSize: 400, LeakRate: 20}
+)
+
+// LeakyBucket is a client side implementation of Shopify's leaky bucket. Every
+// request adds one to the bucket which leaks at a constant rate, callers are
+// blocked while the bucket is full. It is safe for concurrent use so a single
+// LeakyBucket can be shared by all clients talking to the same shop.
+//
+// The bucket corrects itself from the call limit reported by Shopify, so
+// requests made by other processes are accounted for and the bucket size
+// (and leak rate) follow the shop's actual plan.
+type LeakyBucket struct {
+	mu       sync.Mutex
+	size     float64
+	leakRate float64
+	level    float64
+	last     time.Time
+
+	// Internal testing use only.
+	now   func() time.Time
+	sleep func(context.Context, time.Duration) error
+}
+
+// NewLeakyBucket returns an empty leaky bucket for the given plan
+func NewLeakyBucket(plan RateLimitPlan) *LeakyBucket {
+	return &LeakyBucket{
+		size:     float64(plan.BucketSize),
+		leakRate: plan.LeakRate,
+		now:      time.Now,
+		sleep:    sleepContext,
+	}
+}
+
+// Wait reserves a slot in the bucket, blocking until the slot is available.
+// Reservations are granted in the order Wait is called.
+func (b *LeakyBucket) Wait(ctx context.Context) error {
+	b.mu.Lock()
+	b.leak()
+	b.level++
+	wait := time.Duration((b.level - b.size) / b.leakRate * float64(time.Second))
+	b.mu.Unlock()
+
+	if wait <= 0 {
+		return nil
+	}
+
+	err := b.sleep(ctx, wait)
+	if err != nil {
+		// give back the reservation, the request is not going to be sent
+		b.mu.Lock()
+		b.leak()
+		b.level--
+		b.mu.Unlock()
+	}
+
+	return err
+}
+
+// Observe corrects the bucket with the state reported by Shopify
+func (b *LeakyBucket) Observe(used, size int) {
+	if size <= 0 {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.leak()
+
+	if float64(size) != b.size {
+		// all plans leak the full bucket in the same amount of time
+		b.leakRate = b.leakRate * float64(size) / b.size
+		b.size = float64(size)
+	}
+
+	// requests still in flight are not part of the reported state, so only
+	// ever fill the bucket up to what Shopify reports
+	if float64(used) > b.level {
+		b.level = float64(used)
+	}
+}
+
+// leak drains the bucket for the time elapsed since the last leak.
+// b.mu must be held.
+func (b *LeakyBucket) leak() {
+	now := b.now()
+	if !b.last.IsZero() {
+		b.level -= now.Sub(b.last).Seconds() * b.leakRate
+		if b.level < 0 {
+			b.level = 0
+		}
+	}
+	b.last = now
+}
+
+// sleepContext pauses for the given duration or until the context is done
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// parseCallLimit parses the X-Shopify-Shop-Api-Call-Limit header, e.g. "32/40"
+func parseCallLimit(h http.Header) (used, size int, ok bool) {
+	s := strings.Split(h.Get(callLimitHeader), "/")
+	if len(s) != 2 {
+		return 0, 0, false
+	}
+
+	used, err := strconv.Atoi(s[0])
+	if err != nil {
+		return 0, 0, false
+	}
+
+	size, err = strconv.Atoi(s[1])
+	if err != nil {
+		return 0, 0, false
+	}
+
+	return used, size, true
+}
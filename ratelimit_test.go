@@ -0,0 +1,207 @@
+package goshopify
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jarcoal/httpmock"
+)
+
+// fakeClock lets the leaky bucket be tested without actually sleeping
+type fakeClock struct {
+	mu    sync.Mutex
+	now   time.Time
+	slept []time.Duration
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Sleep(ctx context.Context, d time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.slept = append(c.slept, d)
+	return nil
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func newTestLeakyBucket(plan RateLimitPlan) (*LeakyBucket, *fakeClock) {
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	bucket := NewLeakyBucket(plan)
+	bucket.now = clock.Now
+	bucket.sleep = clock.Sleep
+	return bucket, clock
+}
+
+func TestLeakyBucketWait(t *testing.T) {
+	bucket, clock := newTestLeakyBucket(RateLimitPlan{BucketSize: 4, LeakRate: 2})
+
+	for i := 0; i < 4; i++ {
+		if err := bucket.Wait(context.Background()); err != nil {
+			t.Fatalf("LeakyBucket.Wait() returned error: %v", err)
+		}
+	}
+	if len(clock.slept) != 0 {
+		t.Fatalf("LeakyBucket.Wait() slept %v while the bucket was not full", clock.slept)
+	}
+
+	// the bucket is full, the next two callers queue behind each other
+	_ = bucket.Wait(context.Background())
+	_ = bucket.Wait(context.Background())
+
+	expected := []time.Duration{500 * time.Millisecond, time.Second}
+	if fmt.Sprint(clock.slept) != fmt.Sprint(expected) {
+		t.Errorf("LeakyBucket.Wait() slept %v, expected %v", clock.slept, expected)
+	}
+
+	// after 3 seconds the bucket has fully drained
+	clock.Advance(3 * time.Second)
+	clock.slept = nil
+	_ = bucket.Wait(context.Background())
+	if len(clock.slept) != 0 {
+		t.Errorf("LeakyBucket.Wait() slept %v after the bucket drained", clock.slept)
+	}
+}
+
+func TestLeakyBucketWaitCancelled(t *testing.T) {
+	bucket, _ := newTestLeakyBucket(RateLimitPlan{BucketSize: 1, LeakRate: 1})
+	_ = bucket.Wait(context.Background())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := bucket.Wait(ctx); err != context.Canceled {
+		t.Errorf("LeakyBucket.Wait() returned %v, expected %v", err, context.Canceled)
+	}
+
+	if bucket.level != 1 {
+		t.Errorf("LeakyBucket level = %v, expected the cancelled reservation to be returned", bucket.level)
+	}
+}
+
+func TestLeakyBucketObserve(t *testing.T) {
+	bucket, clock := newTestLeakyBucket(RateLimitPlanStandard)
+
+	bucket.Observe(40, 40)
+	_ = bucket.Wait(context.Background())
+	if len(clock.slept) != 1 || clock.slept[0] != 500*time.Millisecond {
+		t.Errorf("LeakyBucket.Wait() slept %v, expected [500ms]", clock.slept)
+	}
+
+	// a lower count than tracked locally is ignored, requests may be in flight
+	bucket.Observe(1, 40)
+	if bucket.level != 41 {
+		t.Errorf("LeakyBucket level = %v, expected 41", bucket.level)
+	}
+
+	// the shop turns out to be on Plus
+	bucket.Observe(10, 400)
+	if bucket.size != 400 || bucket.leakRate != 20 {
+		t.Errorf("LeakyBucket size = %v, leak rate = %v, expected 400 and 20", bucket.size, bucket.leakRate)
+	}
+}
+
+func TestLeakyBucketConcurrent(t *testing.T) {
+	bucket, clock := newTestLeakyBucket(RateLimitPlan{BucketSize: 10, LeakRate: 10})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_ = bucket.Wait(context.Background())
+		}()
+	}
+	wg.Wait()
+
+	if len(clock.slept) != 10 {
+		t.Errorf("LeakyBucket.Wait() slept %d times, expected 10", len(clock.slept))
+	}
+}
+
+func TestParseCallLimit(t *testing.T) {
+	cases := []struct {
+		header string
+		used   int
+		size   int
+		ok     bool
+	}{
+		{"32/40", 32, 40, true},
+		{"", 0, 0, false},
+		{"32", 0, 0, false},
+		{"a/40", 0, 0, false},
+		{"32/b", 0, 0, false},
+	}
+
+	for _, c := range cases {
+		h := http.Header{}
+		h.Set(callLimitHeader, c.header)
+		used, size, ok := parseCallLimit(h)
+		if used != c.used || size != c.size || ok != c.ok {
+			t.Errorf("parseCallLimit(%q) = %d, %d, %v, expected %d, %d, %v", c.header, used, size, ok, c.used, c.size, c.ok)
+		}
+	}
+}
+
+type recordingLimiter struct {
+	waits    int
+	observed []string
+}
+
+func (l *recordingLimiter) Wait(context.Context) error {
+	l.waits++
+	return nil
+}
+
+func (l *recordingLimiter) Observe(used, size int) {
+	l.observed = append(l.observed, fmt.Sprintf("%d/%d", used, size))
+}
+
+func TestWithRateLimiter(t *testing.T) {
+	limiter := &recordingLimiter{}
+	c := MustNewClient(app, "fooshop", "abcd", WithVersion(testApiVersion), WithRateLimiter(limiter))
+	httpmock.ActivateNonDefault(c.Client)
+	defer httpmock.DeactivateAndReset()
+
+	httpmock.RegisterResponder("GET", fmt.Sprintf("https://fooshop.myshopify.com/%s/shop.json", c.pathPrefix),
+		func(req *http.Request) (*http.Response, error) {
+			resp := httpmock.NewStringResponse(http.StatusOK, `{"shop": {}}`)
+			resp.Header.Set(callLimitHeader, "12/40")
+			return resp, nil
+		})
+	httpmock.RegisterResponder("POST", fmt.Sprintf("https://fooshop.myshopify.com/%s/graphql.json", c.pathPrefix),
+		httpmock.NewStringResponder(http.StatusOK, `{"data": {}}`))
+
+	_, err := c.Shop.Get(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("Shop.Get returned error: %v", err)
+	}
+
+	err = c.GraphQL.Query(context.Background(), "{ shop { id } }", nil, nil)
+	if err != nil {
+		t.Fatalf("GraphQL.Query returned error: %v", err)
+	}
+
+	if limiter.waits != 1 {
+		t.Errorf("RateLimiter.Wait called %d times, expected 1", limiter.waits)
+	}
+
+	if fmt.Sprint(limiter.observed) != "[12/40]" {
+		t.Errorf("RateLimiter.Observe called with %v, expected [12/40]", limiter.observed)
+	}
+}
@@ -0,0 +1,158 @@
+package goshopify
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+const defaultRedactionMask = "[REDACTED]"
+
+var (
+	// DefaultRedactedKeys are the JSON keys whose values are masked by
+	// NewRedactor, i.e. credentials, emails, phone numbers, names and
+	// addresses.
+	DefaultRedactedKeys = []string{
+		"access_token",
+		"refresh_token",
+		"client_secret",
+		"subject_token",
+		"id_token",
+		"password",
+		"password_confirmation",
+		"email",
+		"contact_email",
+		"customer_email",
+		"phone",
+		"first_name",
+		"last_name",
+		"name",
+		"company",
+		"address1",
+		"address2",
+		"city",
+		"zip",
+	}
+
+	// DefaultRedactedPatterns are masked by NewRedactor wherever they occur,
+	// i.e. Shopify access tokens, emails and international phone numbers.
+	DefaultRedactedPatterns = []*regexp.Regexp{
+		regexp.MustCompile(`shp(at|ca|pa|ss|rt)_[a-fA-F0-9]{32}`),
+		regexp.MustCompile(`[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}`),
+		regexp.MustCompile(`\+\d[\d\s().-]{6,}\d`),
+	}
+)
+
+// Redactor masks sensitive values in request and response bodies and urls
+// before they are logged, see WithRedactor.
+type Redactor struct {
+	// Keys are the JSON object keys whose values are masked, compared case
+	// insensitively.
+	Keys []string
+
+	// Patterns are masked wherever they match in string values, and in bodies
+	// that are not JSON.
+	Patterns []*regexp.Regexp
+
+	// Mask replaces redacted values, defaults to [REDACTED].
+	Mask string
+}
+
+// NewRedactor returns a Redactor masking DefaultRedactedKeys and
+// DefaultRedactedPatterns.
+func NewRedactor() *Redactor {
+	return &Redactor{
+		Keys:     append([]string(nil), DefaultRedactedKeys...),
+		Patterns: append([]*regexp.Regexp(nil), DefaultRedactedPatterns...),
+	}
+}
+
+// Redact returns a copy of body with the sensitive values masked. JSON bodies
+// are re-encoded with the values of Keys masked, other bodies only have
+// Patterns masked.
+func (r *Redactor) Redact(body []byte) []byte {
+	var v interface{}
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	if err := decoder.Decode(&v); err != nil || decoder.More() {
+		return []byte(r.RedactString(string(body)))
+	}
+
+	buf := &bytes.Buffer{}
+	encoder := json.NewEncoder(buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(r.redactValue(v)); err != nil {
+		return []byte(r.RedactString(string(body)))
+	}
+
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n"))
+}
+
+// RedactString returns s with Patterns masked.
+func (r *Redactor) RedactString(s string) string {
+	for _, pattern := range r.Patterns {
+		s = pattern.ReplaceAllString(s, r.mask())
+	}
+	return s
+}
+
+// RedactURL returns u as a string with the query values of Keys masked, and
+// Patterns masked in the other query values.
+func (r *Redactor) RedactURL(u *url.URL) string {
+	if u.RawQuery == "" {
+		return u.String()
+	}
+
+	redacted := *u
+	query := redacted.Query()
+	for key, values := range query {
+		for i, value := range values {
+			if r.isRedactedKey(key) {
+				values[i] = r.mask()
+			} else {
+				values[i] = r.RedactString(value)
+			}
+		}
+	}
+	redacted.RawQuery = query.Encode()
+
+	return redacted.String()
+}
+
+func (r *Redactor) redactValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for key, value := range v {
+			if value != nil && r.isRedactedKey(key) {
+				v[key] = r.mask()
+			} else {
+				v[key] = r.redactValue(value)
+			}
+		}
+	case []interface{}:
+		for i, value := range v {
+			v[i] = r.redactValue(value)
+		}
+	case string:
+		return r.RedactString(v)
+	}
+	return v
+}
+
+func (r *Redactor) isRedactedKey(key string) bool {
+	for _, k := range r.Keys {
+		if strings.EqualFold(k, key) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Redactor) mask() string {
+	if r.Mask != "" {
+		return r.Mask
+	}
+	return defaultRedactionMask
+}
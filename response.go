@@ -0,0 +1,117 @@
+package goshopify
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	requestIdHeader        = "X-Request-Id"
+	apiVersionHeader       = "X-Shopify-API-Version"
+	deprecatedReasonHeader = "X-Shopify-API-Deprecated-Reason"
+)
+
+// Response holds the metadata of a single call to the Shopify API, such as
+// the request id Shopify support asks for. See WithResponseCapture.
+type Response struct {
+	// StatusCode is the HTTP status of the final attempt
+	StatusCode int
+
+	// Header holds the response headers of the final attempt
+	Header http.Header
+
+	// RequestId is the X-Request-Id Shopify assigned to the request
+	RequestId string
+
+	// Attempts is the number of times the request was sent, see WithRetry.
+	// It includes the attempts of a GraphQL query which were throttled.
+	Attempts int
+
+	// RateLimited is the number of attempts rejected because of a rate limit,
+	// i.e. a 429 response or a throttled GraphQL query
+	RateLimited int
+
+	// RetryWait is the total time spent waiting before retrying the request
+	RetryWait time.Duration
+
+	// RateLimits is the rate limit state reported in this response
+	RateLimits RateLimitInfo
+
+	// ApiVersion is the API version that served the request
+	ApiVersion string
+
+	// DeprecatedReason is set when the request used a deprecated API,
+	// from the X-Shopify-API-Deprecated-Reason header
+	DeprecatedReason string
+}
+
+// responseCaptureKey is the context key under which a *Response is stored
+type responseCaptureKey struct{}
+
+// WithResponseCapture returns a copy of ctx which records the metadata of API
+// calls made with it into resp. When a single method performs several calls,
+// e.g. ListAll, resp holds the metadata of the last one. The metadata is also
+// recorded when the call returns an error.
+//
+//	var resp goshopify.Response
+//	order, err := client.Order.Get(goshopify.WithResponseCapture(ctx, &resp), orderId, nil)
+//	log.Println(resp.RequestId, resp.StatusCode, resp.Attempts)
+//
+// resp is written without synchronization, so a context returned by
+// WithResponseCapture must not be shared between goroutines.
+func WithResponseCapture(ctx context.Context, resp *Response) context.Context {
+	return context.WithValue(ctx, responseCaptureKey{}, resp)
+}
+
+// capturedResponse returns the *Response registered with WithResponseCapture
+func capturedResponse(ctx context.Context) *Response {
+	resp, _ := ctx.Value(responseCaptureKey{}).(*Response)
+	return resp
+}
+
+// update records the metadata of an http response
+func (r *Response) update(resp *http.Response) {
+	r.StatusCode = resp.StatusCode
+	r.Header = resp.Header
+	r.RequestId = resp.Header.Get(requestIdHeader)
+	r.ApiVersion = resp.Header.Get(apiVersionHeader)
+	r.DeprecatedReason = resp.Header.Get(deprecatedReasonHeader)
+
+	r.RateLimits = RateLimitInfo{}
+	if used, size, ok := parseCallLimit(resp.Header); ok {
+		r.RateLimits.RequestCount = used
+		r.RateLimits.BucketSize = size
+	}
+	r.RateLimits.RetryAfterSeconds, _ = strconv.ParseFloat(resp.Header.Get("Retry-After"), 64)
+}
+
+// merge records the metadata of next, a following attempt of the same call,
+// keeping the counts of the previous attempts
+func (r *Response) merge(next *Response) {
+	attempts := r.Attempts + next.Attempts
+	rateLimited := r.RateLimited + next.RateLimited
+	retryWait := r.RetryWait + next.RetryWait
+
+	*r = *next
+	r.Attempts = attempts
+	r.RateLimited = rateLimited
+	r.RetryWait = retryWait
+}
+
+// updateGraphQL records the query cost reported in a GraphQL response
+func (r *Response) updateGraphQL(gr *graphQLResponse) {
+	if gr.Extensions != nil {
+		cost := gr.Extensions.Cost
+		r.RateLimits.GraphQLCost = &cost
+		r.RateLimits.RetryAfterSeconds = cost.RetryAfterSeconds()
+	}
+
+	for _, err := range gr.Errors {
+		if err.Extensions != nil && err.Extensions.Code == graphQLErrorCodeThrottled {
+			r.RateLimited++
+			break
+		}
+	}
+}
@@ -0,0 +1,192 @@
+package goshopify
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/jarcoal/httpmock"
+)
+
+func TestWithResponseCapture(t *testing.T) {
+	setup()
+	defer teardown()
+
+	httpmock.RegisterResponder("GET", fmt.Sprintf("https://fooshop.myshopify.com/%s/products/1.json", client.pathPrefix),
+		func(req *http.Request) (*http.Response, error) {
+			resp := httpmock.NewStringResponse(http.StatusOK, `{"product": {"id": 1}}`)
+			resp.Header.Set(requestIdHeader, "abc-123")
+			resp.Header.Set(callLimitHeader, "12/40")
+			resp.Header.Set(apiVersionHeader, testApiVersion)
+			resp.Header.Set(deprecatedReasonHeader, "https://shopify.dev/changelog")
+			return resp, nil
+		})
+
+	var resp Response
+	_, err := client.Product.Get(WithResponseCapture(context.Background(), &resp), 1, nil)
+	if err != nil {
+		t.Fatalf("Product.Get returned error: %v", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Response.StatusCode = %d, expected %d", resp.StatusCode, http.StatusOK)
+	}
+	if resp.RequestId != "abc-123" {
+		t.Errorf("Response.RequestId = %s, expected abc-123", resp.RequestId)
+	}
+	if resp.Attempts != 1 {
+		t.Errorf("Response.Attempts = %d, expected 1", resp.Attempts)
+	}
+	if resp.ApiVersion != testApiVersion {
+		t.Errorf("Response.ApiVersion = %s, expected %s", resp.ApiVersion, testApiVersion)
+	}
+	if resp.DeprecatedReason != "https://shopify.dev/changelog" {
+		t.Errorf("Response.DeprecatedReason = %s, expected https://shopify.dev/changelog", resp.DeprecatedReason)
+	}
+
+	expectedRateLimits := RateLimitInfo{RequestCount: 12, BucketSize: 40}
+	if !reflect.DeepEqual(resp.RateLimits, expectedRateLimits) {
+		t.Errorf("Response.RateLimits = %#v, expected %#v", resp.RateLimits, expectedRateLimits)
+	}
+}
+
+func TestWithResponseCaptureError(t *testing.T) {
+	setup()
+	defer teardown()
+
+	attempts := 0
+	httpmock.RegisterResponder("GET", fmt.Sprintf("https://fooshop.myshopify.com/%s/products/1.json", client.pathPrefix),
+		func(req *http.Request) (*http.Response, error) {
+			attempts++
+			resp := httpmock.NewStringResponse(http.StatusServiceUnavailable, "")
+			resp.Header.Set(requestIdHeader, fmt.Sprintf("attempt-%d", attempts))
+			return resp, nil
+		})
+
+	var resp Response
+	_, err := client.Product.Get(WithResponseCapture(context.Background(), &resp), 1, nil)
+	if err == nil {
+		t.Fatalf("Product.Get should have returned an error")
+	}
+
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("Response.StatusCode = %d, expected %d", resp.StatusCode, http.StatusServiceUnavailable)
+	}
+	if resp.Attempts != maxRetries {
+		t.Errorf("Response.Attempts = %d, expected %d", resp.Attempts, maxRetries)
+	}
+	expectedRequestId := fmt.Sprintf("attempt-%d", maxRetries)
+	if resp.RequestId != expectedRequestId {
+		t.Errorf("Response.RequestId = %s, expected %s", resp.RequestId, expectedRequestId)
+	}
+}
+
+func TestWithResponseCaptureGraphQL(t *testing.T) {
+	setup()
+	defer teardown()
+
+	httpmock.RegisterResponder("POST", fmt.Sprintf("https://fooshop.myshopify.com/%s/graphql.json", client.pathPrefix),
+		func(req *http.Request) (*http.Response, error) {
+			resp := httpmock.NewStringResponse(http.StatusOK, `{
+				"data": {},
+				"extensions": {
+					"cost": {
+						"requestedQueryCost": 10,
+						"actualQueryCost": 5,
+						"throttleStatus": {"maximumAvailable": 1000, "currentlyAvailable": 995, "restoreRate": 50}
+					}
+				}
+			}`)
+			resp.Header.Set(requestIdHeader, "gql-1")
+			return resp, nil
+		})
+
+	var resp Response
+	err := client.GraphQL.Query(WithResponseCapture(context.Background(), &resp), "query", nil, nil)
+	if err != nil {
+		t.Fatalf("GraphQL.Query returned error: %v", err)
+	}
+
+	if resp.RequestId != "gql-1" {
+		t.Errorf("Response.RequestId = %s, expected gql-1", resp.RequestId)
+	}
+	if resp.RateLimits.GraphQLCost == nil || resp.RateLimits.GraphQLCost.RequestedQueryCost != 10 {
+		t.Errorf("Response.RateLimits.GraphQLCost = %#v, expected a requested query cost of 10", resp.RateLimits.GraphQLCost)
+	}
+}
+
+func TestWithResponseCaptureRateLimited(t *testing.T) {
+	setup()
+	defer teardown()
+
+	attempts := 0
+	httpmock.RegisterResponder("GET", fmt.Sprintf("https://fooshop.myshopify.com/%s/products/1.json", client.pathPrefix),
+		func(req *http.Request) (*http.Response, error) {
+			attempts++
+			if attempts == 1 {
+				resp := httpmock.NewStringResponse(http.StatusTooManyRequests, `{"errors": "Exceeded 2 calls per second"}`)
+				resp.Header.Set("Retry-After", "0")
+				return resp, nil
+			}
+			return httpmock.NewStringResponse(http.StatusOK, `{"product": {"id": 1}}`), nil
+		})
+
+	var resp Response
+	_, err := client.Product.Get(WithResponseCapture(context.Background(), &resp), 1, nil)
+	if err != nil {
+		t.Fatalf("Product.Get returned error: %v", err)
+	}
+
+	if resp.Attempts != 2 || resp.RateLimited != 1 {
+		t.Errorf("Response has %d attempts and %d rate limited, expected 2 and 1", resp.Attempts, resp.RateLimited)
+	}
+}
+
+func TestWithResponseCaptureGraphQLThrottled(t *testing.T) {
+	setup()
+	defer teardown()
+
+	client.retryPolicy = &ExponentialBackoff{MaxAttempts: 5, InitialInterval: time.Millisecond, Multiplier: 1}
+
+	attempts := 0
+	httpmock.RegisterResponder("POST", fmt.Sprintf("https://fooshop.myshopify.com/%s/graphql.json", client.pathPrefix),
+		func(req *http.Request) (*http.Response, error) {
+			attempts++
+			if attempts <= 2 {
+				return httpmock.NewStringResponse(http.StatusOK, `{
+					"errors": [{"message": "Throttled", "extensions": {"code": "THROTTLED"}}],
+					"extensions": {"cost": {"requestedQueryCost": 10, "throttleStatus": {"maximumAvailable": 1000, "currentlyAvailable": 20, "restoreRate": 50}}}
+				}`), nil
+			}
+			resp := httpmock.NewStringResponse(http.StatusOK, `{"data": {"shop": {"name": "foo"}}}`)
+			resp.Header.Set(requestIdHeader, "gql-3")
+			return resp, nil
+		})
+
+	var resp Response
+	var data struct {
+		Shop struct {
+			Name string `json:"name"`
+		} `json:"shop"`
+	}
+	err := client.GraphQL.Query(WithResponseCapture(context.Background(), &resp), "query", nil, &data)
+	if err != nil {
+		t.Fatalf("GraphQL.Query returned error: %v", err)
+	}
+
+	if attempts != 3 || data.Shop.Name != "foo" {
+		t.Errorf("GraphQL.Query sent %d requests and returned %+v, expected 3 requests", attempts, data)
+	}
+	if resp.Attempts != 3 || resp.RateLimited != 2 {
+		t.Errorf("Response has %d attempts and %d rate limited, expected 3 and 2", resp.Attempts, resp.RateLimited)
+	}
+	if resp.RetryWait != 2*time.Millisecond {
+		t.Errorf("Response.RetryWait = %s, expected 2ms", resp.RetryWait)
+	}
+	if resp.RequestId != "gql-3" {
+		t.Errorf("Response.RequestId = %s, expected the request id of the last attempt", resp.RequestId)
+	}
+}
@@ -0,0 +1,316 @@
+package goshopify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	"math/rand"
+	"net"
+	"net/http"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// RetryAttempt describes a failed attempt at sending a request.
+type RetryAttempt struct {
+	// Request is the request that failed. It is nil for GraphQL queries that
+	// were throttled, which were not executed by Shopify.
+	Request *http.Request
+
+	// Response is the response of the failed attempt, nil if no response was
+	// received. Its body has already been consumed.
+	Response *http.Response
+
+	// Err is the error the attempt failed with, either an error returned by
+	// the http client or an error returned by Shopify, e.g. RateLimitError.
+	Err error
+
+	// Attempt is the number of the failed attempt, starting at 1.
+	Attempt int
+
+	// Elapsed is the time elapsed since the first attempt was sent.
+	Elapsed time.Duration
+}
+
+// RetryPolicy decides whether a failed request is retried, see
+// WithRetryPolicy.
+type RetryPolicy interface {
+	// ShouldRetry returns whether the request should be retried and how long
+	// to wait before doing so.
+	ShouldRetry(RetryAttempt) (time.Duration, bool)
+}
+
+// DefaultRetryableStatuses are the response statuses retried by
+// ExponentialBackoff by default.
+var DefaultRetryableStatuses = []int{
+	http.StatusTooManyRequests,
+	http.StatusInternalServerError,
+	http.StatusBadGateway,
+	http.StatusServiceUnavailable,
+	http.StatusGatewayTimeout,
+}
+
+// ExponentialBackoff is a RetryPolicy waiting exponentially longer between
+// attempts, randomized to avoid many clients retrying in lockstep.
+//
+// Rate limited requests honour the Retry-After Shopify sends. Only requests
+// which are safe to send twice are retried after a transport error or a
+// server error, i.e. GET, HEAD, OPTIONS, PUT and DELETE requests and GraphQL
+// queries, unless RetryNonIdempotent is set. Rate limited requests are always retried as
+// Shopify rejected them without processing them.
+type ExponentialBackoff struct {
+	// MaxAttempts is the maximum number of attempts including the first one.
+	MaxAttempts int
+
+	// InitialInterval is the wait before the first retry.
+	InitialInterval time.Duration
+
+	// MaxInterval caps the wait between two attempts.
+	MaxInterval time.Duration
+
+	// Multiplier is the factor the wait grows by with every attempt.
+	Multiplier float64
+
+	// Jitter randomizes the wait by up to this fraction in either direction,
+	// e.g. 0.5 waits between 50% and 150% of the interval.
+	Jitter float64
+
+	// MaxElapsedTime stops retrying once the next attempt would start after
+	// this much time since the first attempt. Zero means no limit.
+	MaxElapsedTime time.Duration
+
+	// RetryableStatuses are the response statuses which are retried.
+	RetryableStatuses []int
+
+	// RetryNonIdempotent also retries POST and PATCH requests and GraphQL
+	// mutations after transport and server errors, which may result in them
+	// being applied twice.
+	RetryNonIdempotent bool
+
+	// Internal testing use only.
+	random func() float64
+}
+
+// NewExponentialBackoff returns an ExponentialBackoff making up to maxAttempts
+// attempts, starting with a 500ms wait doubling up to 30s between attempts.
+func NewExponentialBackoff(maxAttempts int) *ExponentialBackoff {
+	return &ExponentialBackoff{
+		MaxAttempts:       maxAttempts,
+		InitialInterval:   500 * time.Millisecond,
+		MaxInterval:       30 * time.Second,
+		Multiplier:        2,
+		Jitter:            0.5,
+		MaxElapsedTime:    2 * time.Minute,
+		RetryableStatuses: DefaultRetryableStatuses,
+	}
+}
+
+// ShouldRetry implements RetryPolicy
+func (b *ExponentialBackoff) ShouldRetry(attempt RetryAttempt) (time.Duration, bool) {
+	if attempt.Attempt >= b.MaxAttempts || !b.isRetryable(attempt) {
+		return 0, false
+	}
+
+	interval := float64(b.InitialInterval) * math.Pow(b.Multiplier, float64(attempt.Attempt-1))
+	if b.MaxInterval > 0 && interval > float64(b.MaxInterval) {
+		interval = float64(b.MaxInterval)
+	}
+
+	random := b.random
+	if random == nil {
+		random = rand.Float64
+	}
+	wait := time.Duration(interval * (1 + b.Jitter*(2*random()-1)))
+
+	var rateLimitErr RateLimitError
+	if errors.As(attempt.Err, &rateLimitErr) {
+		retryAfter := time.Duration(rateLimitErr.RetryAfter) * time.Second
+		if retryAfter > wait {
+			wait = retryAfter
+		}
+	}
+
+	if b.MaxElapsedTime > 0 && attempt.Elapsed+wait > b.MaxElapsedTime {
+		return 0, false
+	}
+
+	return wait, true
+}
+
+func (b *ExponentialBackoff) isRetryable(attempt RetryAttempt) bool {
+	if errors.Is(attempt.Err, context.Canceled) || errors.Is(attempt.Err, context.DeadlineExceeded) {
+		return false
+	}
+
+	// rate limited requests were rejected, so they can always be sent again
+	var rateLimitErr RateLimitError
+	if errors.As(attempt.Err, &rateLimitErr) {
+		return true
+	}
+
+	if attempt.Request != nil && !isIdempotent(attempt.Request) && !b.RetryNonIdempotent {
+		return false
+	}
+
+	if attempt.Response == nil {
+		return isRetryableError(attempt.Err)
+	}
+
+	for _, status := range b.RetryableStatuses {
+		if attempt.Response.StatusCode == status {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isIdempotent reports whether sending the request twice has the same effect
+// as sending it once. GraphQL queries are sent as POST requests too, only
+// mutations have side effects.
+func isIdempotent(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	case http.MethodPost:
+		return isGraphQLRequest(req) && !isGraphQLMutationRequest(req)
+	}
+	return false
+}
+
+// isGraphQLMutationRequest reports whether the GraphQL request may run a
+// mutation. Requests whose body cannot be read are assumed to.
+func isGraphQLMutationRequest(req *http.Request) bool {
+	if req.GetBody == nil {
+		return true
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return true
+	}
+	defer body.Close()
+
+	var data struct {
+		Query string `json:"query"`
+	}
+	if err := json.NewDecoder(body).Decode(&data); err != nil {
+		return true
+	}
+	return hasGraphQLMutation(data.Query)
+}
+
+// hasGraphQLMutation reports whether the GraphQL document defines a mutation,
+// i.e. has the mutation keyword outside of any selection set, arguments or
+// string. It errs on the side of finding one.
+func hasGraphQLMutation(doc string) bool {
+	depth := 0
+	for i := 0; i < len(doc); i++ {
+		switch c := doc[i]; {
+		case c == '#':
+			for i < len(doc) && doc[i] != '\n' {
+				i++
+			}
+		case c == '"':
+			i = skipGraphQLString(doc, i)
+		case c == '{' || c == '(' || c == '[':
+			depth++
+		case c == '}' || c == ')' || c == ']':
+			depth--
+		case isGraphQLNameChar(c):
+			j := i
+			for j < len(doc) && isGraphQLNameChar(doc[j]) {
+				j++
+			}
+			if depth == 0 && doc[i:j] == "mutation" {
+				return true
+			}
+			i = j - 1
+		}
+	}
+	return false
+}
+
+// skipGraphQLString returns the index of the closing quote of the string or
+// block string starting at i
+func skipGraphQLString(doc string, i int) int {
+	if strings.HasPrefix(doc[i:], `"""`) {
+		for j := i + 3; j < len(doc); j++ {
+			if strings.HasPrefix(doc[j:], `\"""`) {
+				j += 3
+			} else if strings.HasPrefix(doc[j:], `"""`) {
+				return j + 2
+			}
+		}
+		return len(doc)
+	}
+
+	for j := i + 1; j < len(doc); j++ {
+		switch doc[j] {
+		case '\\':
+			j++
+		case '"', '\n':
+			return j
+		}
+	}
+	return len(doc)
+}
+
+func isGraphQLNameChar(c byte) bool {
+	return c == '_' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9'
+}
+
+// isRetryableError reports whether a transport error is likely temporary,
+// i.e. timeouts, connection resets and connections closed early.
+func isRetryableError(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+
+	return errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.EPIPE) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, io.EOF)
+}
+
+// legacyRetryPolicy is the policy configured through WithRetry, retrying rate
+// limited requests after the Retry-After Shopify sends and immediately
+// retrying requests which failed with 503 Service Unavailable.
+type legacyRetryPolicy struct {
+	maxAttempts int
+}
+
+// ShouldRetry implements RetryPolicy
+func (p legacyRetryPolicy) ShouldRetry(attempt RetryAttempt) (time.Duration, bool) {
+	if attempt.Attempt >= p.maxAttempts {
+		return 0, false
+	}
+
+	var rateLimitErr RateLimitError
+	if errors.As(attempt.Err, &rateLimitErr) {
+		return time.Duration(rateLimitErr.RetryAfter) * time.Second, true
+	}
+
+	if attempt.Response != nil && attempt.Response.StatusCode == http.StatusServiceUnavailable {
+		return 0, true
+	}
+
+	return 0, false
+}
+
+// getRetryPolicy returns the policy set with WithRetryPolicy, falling back to
+// the behaviour of WithRetry.
+func (c *Client) getRetryPolicy() RetryPolicy {
+	if c.retryPolicy != nil {
+		return c.retryPolicy
+	}
+	return legacyRetryPolicy{maxAttempts: c.retries}
+}
@@ -0,0 +1,343 @@
+package goshopify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/jarcoal/httpmock"
+)
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func TestExponentialBackoffShouldRetry(t *testing.T) {
+	get, _ := http.NewRequest(http.MethodGet, "https://fooshop.myshopify.com/admin/products.json", nil)
+	post, _ := http.NewRequest(http.MethodPost, "https://fooshop.myshopify.com/admin/products.json", nil)
+	status := func(code int) *http.Response {
+		return &http.Response{StatusCode: code, Header: http.Header{}}
+	}
+
+	cases := []struct {
+		description string
+		attempt     RetryAttempt
+		retry       bool
+		wait        time.Duration
+	}{
+		{
+			description: "retries server errors",
+			attempt:     RetryAttempt{Request: get, Response: status(502), Err: ResponseError{Status: 502}, Attempt: 1},
+			retry:       true,
+			wait:        100 * time.Millisecond,
+		},
+		{
+			description: "waits exponentially longer",
+			attempt:     RetryAttempt{Request: get, Response: status(500), Err: ResponseError{Status: 500}, Attempt: 3},
+			retry:       true,
+			wait:        400 * time.Millisecond,
+		},
+		{
+			description: "caps the wait",
+			attempt:     RetryAttempt{Request: get, Response: status(504), Err: ResponseError{Status: 504}, Attempt: 9},
+			retry:       true,
+			wait:        time.Second,
+		},
+		{
+			description: "does not retry client errors",
+			attempt:     RetryAttempt{Request: get, Response: status(422), Err: ResponseError{Status: 422}, Attempt: 1},
+			retry:       false,
+		},
+		{
+			description: "stops after max attempts",
+			attempt:     RetryAttempt{Request: get, Response: status(503), Err: ResponseError{Status: 503}, Attempt: 10},
+			retry:       false,
+		},
+		{
+			description: "stops after max elapsed time",
+			attempt:     RetryAttempt{Request: get, Response: status(503), Err: ResponseError{Status: 503}, Attempt: 1, Elapsed: 10 * time.Second},
+			retry:       false,
+		},
+		{
+			description: "honours retry after",
+			attempt:     RetryAttempt{Request: get, Response: status(429), Err: RateLimitError{RetryAfter: 2}, Attempt: 1},
+			retry:       true,
+			wait:        2 * time.Second,
+		},
+		{
+			description: "retries rate limited posts",
+			attempt:     RetryAttempt{Request: post, Response: status(429), Err: RateLimitError{RetryAfter: 1}, Attempt: 1},
+			retry:       true,
+			wait:        time.Second,
+		},
+		{
+			description: "retries throttled graphql queries",
+			attempt:     RetryAttempt{Err: RateLimitError{RetryAfter: 1}, Attempt: 1},
+			retry:       true,
+			wait:        time.Second,
+		},
+		{
+			description: "does not retry posts after server errors",
+			attempt:     RetryAttempt{Request: post, Response: status(500), Err: ResponseError{Status: 500}, Attempt: 1},
+			retry:       false,
+		},
+		{
+			description: "retries timeouts",
+			attempt:     RetryAttempt{Request: get, Err: fmt.Errorf("get: %w", timeoutError{}), Attempt: 1},
+			retry:       true,
+			wait:        100 * time.Millisecond,
+		},
+		{
+			description: "retries connection resets",
+			attempt:     RetryAttempt{Request: get, Err: fmt.Errorf("read: %w", syscall.ECONNRESET), Attempt: 1},
+			retry:       true,
+			wait:        100 * time.Millisecond,
+		},
+		{
+			description: "retries connections closed early",
+			attempt:     RetryAttempt{Request: get, Err: io.ErrUnexpectedEOF, Attempt: 1},
+			retry:       true,
+			wait:        100 * time.Millisecond,
+		},
+		{
+			description: "does not retry unknown errors",
+			attempt:     RetryAttempt{Request: get, Err: errors.New("unsupported protocol scheme"), Attempt: 1},
+			retry:       false,
+		},
+		{
+			description: "does not retry cancelled requests",
+			attempt:     RetryAttempt{Request: get, Err: fmt.Errorf("get: %w", context.Canceled), Attempt: 1},
+			retry:       false,
+		},
+		{
+			description: "does not retry posts after timeouts",
+			attempt:     RetryAttempt{Request: post, Err: timeoutError{}, Attempt: 1},
+			retry:       false,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			policy := NewExponentialBackoff(10)
+			policy.InitialInterval = 100 * time.Millisecond
+			policy.MaxInterval = time.Second
+			policy.MaxElapsedTime = 10 * time.Second
+			policy.random = func() float64 { return 0.5 }
+
+			wait, retry := policy.ShouldRetry(c.attempt)
+			if retry != c.retry || wait != c.wait {
+				t.Errorf("ShouldRetry() = %s, %v, expected %s, %v", wait, retry, c.wait, c.retry)
+			}
+		})
+	}
+}
+
+func TestExponentialBackoffJitter(t *testing.T) {
+	policy := NewExponentialBackoff(10)
+	attempt := RetryAttempt{Err: io.ErrUnexpectedEOF, Attempt: 1}
+
+	for _, c := range []struct {
+		random float64
+		wait   time.Duration
+	}{
+		{0, 250 * time.Millisecond},
+		{0.5, 500 * time.Millisecond},
+		{1, 750 * time.Millisecond},
+	} {
+		policy.random = func() float64 { return c.random }
+		wait, _ := policy.ShouldRetry(attempt)
+		if wait != c.wait {
+			t.Errorf("ShouldRetry() with random %v waits %s, expected %s", c.random, wait, c.wait)
+		}
+	}
+}
+
+func TestExponentialBackoffNonIdempotent(t *testing.T) {
+	post, _ := http.NewRequest(http.MethodPost, "https://fooshop.myshopify.com/admin/products.json", nil)
+	policy := NewExponentialBackoff(10)
+	policy.RetryNonIdempotent = true
+
+	_, retry := policy.ShouldRetry(RetryAttempt{
+		Request:  post,
+		Response: &http.Response{StatusCode: 503},
+		Err:      ResponseError{Status: 503},
+		Attempt:  1,
+	})
+	if !retry {
+		t.Errorf("ShouldRetry() should retry posts when RetryNonIdempotent is set")
+	}
+}
+
+func TestHasGraphQLMutation(t *testing.T) {
+	cases := []struct {
+		doc      string
+		expected bool
+	}{
+		{`{ shop { name } }`, false},
+		{`query { shop { name } }`, false},
+		{`query products($query: String = "mutation") { products(query: $query) { edges { node { id } } } }`, false},
+		{"# mutation\nquery { shop { name } }", false},
+		{`query { shop { description(format: """ ) mutation \""" """) } }`, false},
+		{`fragment F on Mutation { id } query { shop { ...F } }`, false},
+		{`mutation { productDelete(input: {id: 1}) { deletedProductId } }`, true},
+		{"  # a comment\n  mutation productDelete { productDelete(input: {id: 1}) { deletedProductId } }", true},
+		{`query A { shop { name } } mutation B { productDelete(input: {id: 1}) { deletedProductId } }`, true},
+	}
+
+	for _, c := range cases {
+		if mutation := hasGraphQLMutation(c.doc); mutation != c.expected {
+			t.Errorf("hasGraphQLMutation(%q) = %v, expected %v", c.doc, mutation, c.expected)
+		}
+	}
+}
+
+func newRetryPolicyTestClient() *Client {
+	policy := NewExponentialBackoff(3)
+	policy.InitialInterval = time.Millisecond
+
+	c := MustNewClient(app, "fooshop", "abcd", WithVersion(testApiVersion), WithRetryPolicy(policy))
+	httpmock.ActivateNonDefault(c.Client)
+	return c
+}
+
+func TestWithRetryPolicy(t *testing.T) {
+	c := newRetryPolicyTestClient()
+	defer httpmock.DeactivateAndReset()
+
+	attempts := 0
+	httpmock.RegisterResponder("GET", fmt.Sprintf("https://fooshop.myshopify.com/%s/products/1.json", c.pathPrefix),
+		func(req *http.Request) (*http.Response, error) {
+			attempts++
+			switch attempts {
+			case 1:
+				return nil, io.ErrUnexpectedEOF
+			case 2:
+				return httpmock.NewStringResponse(http.StatusBadGateway, ""), nil
+			}
+			return httpmock.NewStringResponse(http.StatusOK, `{"product": {"id": 1}}`), nil
+		})
+
+	product, err := c.Product.Get(context.Background(), 1, nil)
+	if err != nil {
+		t.Fatalf("Product.Get returned error: %v", err)
+	}
+	if product.Id != 1 || attempts != 3 {
+		t.Errorf("Product.Get returned %#v after %d attempts, expected product 1 after 3 attempts", product, attempts)
+	}
+}
+
+func TestWithRetryPolicyPost(t *testing.T) {
+	c := newRetryPolicyTestClient()
+	defer httpmock.DeactivateAndReset()
+
+	attempts := 0
+	httpmock.RegisterResponder("POST", fmt.Sprintf("https://fooshop.myshopify.com/%s/products.json", c.pathPrefix),
+		func(req *http.Request) (*http.Response, error) {
+			attempts++
+			return httpmock.NewStringResponse(http.StatusInternalServerError, `{"errors": "oops"}`), nil
+		})
+
+	_, err := c.Product.Create(context.Background(), Product{})
+	if err == nil || err.Error() != "oops" {
+		t.Errorf("Product.Create returned %v, expected oops", err)
+	}
+	if attempts != 1 {
+		t.Errorf("Product.Create made %d attempts, expected 1", attempts)
+	}
+}
+
+func TestWithRetryPolicyContextCancelled(t *testing.T) {
+	policy := NewExponentialBackoff(3)
+	policy.InitialInterval = time.Hour
+	policy.MaxElapsedTime = 0
+
+	c := MustNewClient(app, "fooshop", "abcd", WithVersion(testApiVersion), WithRetryPolicy(policy))
+	httpmock.ActivateNonDefault(c.Client)
+	defer httpmock.DeactivateAndReset()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	httpmock.RegisterResponder("GET", fmt.Sprintf("https://fooshop.myshopify.com/%s/products/1.json", c.pathPrefix),
+		func(req *http.Request) (*http.Response, error) {
+			cancel()
+			return httpmock.NewStringResponse(http.StatusServiceUnavailable, ""), nil
+		})
+
+	done := make(chan error)
+	go func() {
+		_, err := c.Product.Get(ctx, 1, nil)
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Product.Get returned %v, expected %v", err, context.Canceled)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Product.Get did not return after the context was cancelled")
+	}
+}
+
+func TestWithRetryPolicyGraphQLThrottled(t *testing.T) {
+	c := newRetryPolicyTestClient()
+	defer httpmock.DeactivateAndReset()
+
+	attempts := 0
+	httpmock.RegisterResponder("POST", fmt.Sprintf("https://fooshop.myshopify.com/%s/graphql.json", c.pathPrefix),
+		func(req *http.Request) (*http.Response, error) {
+			attempts++
+			if attempts == 1 {
+				return httpmock.NewStringResponse(http.StatusOK, `{
+					"errors": [{"message": "Throttled", "extensions": {"code": "THROTTLED"}}],
+					"extensions": {"cost": {"requestedQueryCost": 1, "throttleStatus": {"maximumAvailable": 1000, "currentlyAvailable": 1, "restoreRate": 50}}}
+				}`), nil
+			}
+			return httpmock.NewStringResponse(http.StatusOK, `{"data": {}}`), nil
+		})
+
+	err := c.GraphQL.Query(context.Background(), "query", nil, nil)
+	if err != nil {
+		t.Fatalf("GraphQL.Query returned error: %v", err)
+	}
+	if attempts != 2 {
+		t.Errorf("GraphQL.Query made %d attempts, expected 2", attempts)
+	}
+}
+
+func TestWithRetryPolicyGraphQL(t *testing.T) {
+	c := newRetryPolicyTestClient()
+	defer httpmock.DeactivateAndReset()
+
+	attempts := 0
+	httpmock.RegisterResponder("POST", fmt.Sprintf("https://fooshop.myshopify.com/%s/graphql.json", c.pathPrefix),
+		func(req *http.Request) (*http.Response, error) {
+			attempts++
+			if attempts == 1 {
+				return httpmock.NewStringResponse(http.StatusServiceUnavailable, ""), nil
+			}
+			return httpmock.NewStringResponse(http.StatusOK, `{"data": {}}`), nil
+		})
+
+	if err := c.GraphQL.Query(context.Background(), "query { shop { name } }", nil, nil); err != nil {
+		t.Fatalf("GraphQL.Query returned error: %v", err)
+	}
+	if attempts != 2 {
+		t.Errorf("GraphQL.Query made %d attempts, expected 2", attempts)
+	}
+
+	attempts = 0
+	err := c.GraphQL.Mutate(context.Background(), "mutation { shopUpdate { userErrors { message } } }", nil, nil)
+	if err == nil {
+		t.Errorf("GraphQL.Mutate returned no error, expected the 503")
+	}
+	if attempts != 1 {
+		t.Errorf("GraphQL.Mutate made %d attempts, expected 1", attempts)
+	}
+}
@@ -0,0 +1,88 @@
+package goshopify
+
+import (
+	"sort"
+	"strings"
+)
+
+// writeScopePrefixes are the prefixes of the write scopes, which imply the
+// read scope of the same resource, e.g. write_products implies read_products
+var writeScopePrefixes = [][2]string{
+	{"write_", "read_"},
+	{"unauthenticated_write_", "unauthenticated_read_"},
+	{"customer_write_", "customer_read_"},
+}
+
+// ScopeSet is a set of access scope handles, e.g. read_products.
+// See: https://shopify.dev/docs/api/usage/access-scopes
+type ScopeSet map[string]struct{}
+
+// NewScopeSet returns a ScopeSet of the given handles.
+func NewScopeSet(handles ...string) ScopeSet {
+	s := make(ScopeSet, len(handles))
+	for _, handle := range handles {
+		handle = strings.ToLower(strings.TrimSpace(handle))
+		if handle != "" {
+			s[handle] = struct{}{}
+		}
+	}
+	return s
+}
+
+// ParseScopes parses a comma separated list of scopes, as in App.Scope or
+// OAuthToken.Scope.
+func ParseScopes(scope string) ScopeSet {
+	return NewScopeSet(strings.Split(scope, ",")...)
+}
+
+// impliedScope returns the read scope implied by a write scope
+func impliedScope(handle string) (string, bool) {
+	for _, prefixes := range writeScopePrefixes {
+		if resource, ok := strings.CutPrefix(handle, prefixes[0]); ok {
+			return prefixes[1] + resource, true
+		}
+	}
+	return "", false
+}
+
+// Has reports whether the set grants handle, directly or implied by the
+// write scope of the same resource.
+func (s ScopeSet) Has(handle string) bool {
+	handle = strings.ToLower(strings.TrimSpace(handle))
+	if _, ok := s[handle]; ok {
+		return true
+	}
+	for h := range s {
+		if implied, ok := impliedScope(h); ok && implied == handle {
+			return true
+		}
+	}
+	return false
+}
+
+// Missing returns the scopes of required the set doesn't grant.
+func (s ScopeSet) Missing(required ScopeSet) ScopeSet {
+	missing := make(ScopeSet)
+	for handle := range required {
+		if !s.Has(handle) {
+			missing[handle] = struct{}{}
+		}
+	}
+	return missing
+}
+
+// Handles returns the sorted handles of the set.
+func (s ScopeSet) Handles() []string {
+	handles := make([]string, 0, len(s))
+	for handle := range s {
+		handles = append(handles, handle)
+	}
+	sort.Strings(handles)
+	return handles
+}
+
+// String returns the comma separated list of the handles, as expected by the
+// scope parameter of AuthorizeUrl.
+func (s ScopeSet) String() string {
+	return strings.Join(s.Handles(), ",")
+}
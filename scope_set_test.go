@@ -0,0 +1,58 @@
+package goshopify
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseScopes(t *testing.T) {
+	scopes := ParseScopes(" read_products,Write_Orders,, read_products")
+	expected := []string{"read_products", "write_orders"}
+	if handles := scopes.Handles(); !reflect.DeepEqual(handles, expected) {
+		t.Errorf("ParseScopes returned %v, expected %v", handles, expected)
+	}
+	if s := scopes.String(); s != "read_products,write_orders" {
+		t.Errorf("ScopeSet.String() = %q, expected read_products,write_orders", s)
+	}
+	if len(ParseScopes("")) != 0 {
+		t.Errorf("ParseScopes of an empty string returned %v, expected an empty set", ParseScopes(""))
+	}
+}
+
+func TestScopeSetHas(t *testing.T) {
+	scopes := NewScopeSet("write_products", "unauthenticated_write_checkouts", "customer_write_orders", "read_all_orders")
+
+	cases := []struct {
+		handle   string
+		expected bool
+	}{
+		{"write_products", true},
+		{"read_products", true},
+		{"READ_PRODUCTS", true},
+		{"unauthenticated_read_checkouts", true},
+		{"customer_read_orders", true},
+		{"read_all_orders", true},
+		{"read_orders", false},
+		{"write_orders", false},
+		{"read_checkouts", false},
+	}
+
+	for _, c := range cases {
+		if has := scopes.Has(c.handle); has != c.expected {
+			t.Errorf("ScopeSet.Has(%q) = %v, expected %v", c.handle, has, c.expected)
+		}
+	}
+}
+
+func TestScopeSetMissing(t *testing.T) {
+	granted := NewScopeSet("write_products", "read_orders")
+	required := ParseScopes("read_products,write_orders,read_customers")
+
+	expected := NewScopeSet("write_orders", "read_customers")
+	if missing := granted.Missing(required); !reflect.DeepEqual(missing, expected) {
+		t.Errorf("ScopeSet.Missing returned %v, expected %v", missing, expected)
+	}
+	if missing := required.Missing(granted); len(missing) != 1 || !missing.Has("write_products") {
+		t.Errorf("ScopeSet.Missing returned %v, expected write_products", missing)
+	}
+}
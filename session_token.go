@@ -0,0 +1,185 @@
+package goshopify
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	tokenExchangeGrantType = "urn:ietf:params:oauth:grant-type:token-exchange"
+	idTokenType            = "urn:ietf:params:oauth:token-type:id_token"
+
+	// sessionTokenLeeway is the clock skew tolerated when checking the exp
+	// and nbf claims of a session token
+	sessionTokenLeeway = 5 * time.Second
+)
+
+// AccessTokenType is the type of access token requested by
+// App.ExchangeSessionToken.
+type AccessTokenType string
+
+const (
+	// OfflineAccessToken is an access token for the shop, see
+	// https://shopify.dev/docs/apps/auth/access-token-types/offline
+	OfflineAccessToken AccessTokenType = "urn:shopify:params:oauth:token-type:offline-access-token"
+
+	// OnlineAccessToken is an access token for the user of the session
+	// token, see https://shopify.dev/docs/apps/auth/access-token-types/online
+	OnlineAccessToken AccessTokenType = "urn:shopify:params:oauth:token-type:online-access-token"
+)
+
+// ErrInvalidSessionToken is wrapped by the errors returned when a session
+// token can't be verified.
+var ErrInvalidSessionToken = errors.New("invalid session token")
+
+// SessionTokenClaims are the claims of an App Bridge session token.
+// See: https://shopify.dev/docs/apps/auth/oauth/session-tokens
+type SessionTokenClaims struct {
+	// Issuer is the admin url of the shop, e.g. https://fooshop.myshopify.com/admin
+	Issuer string `json:"iss"`
+
+	// Dest is the url of the shop, e.g. https://fooshop.myshopify.com
+	Dest string `json:"dest"`
+
+	// Audience is the api key of the app
+	Audience string `json:"aud"`
+
+	// Subject is the id of the user
+	Subject string `json:"sub"`
+
+	ExpiresAt int64  `json:"exp"`
+	NotBefore int64  `json:"nbf"`
+	IssuedAt  int64  `json:"iat"`
+	Id        string `json:"jti"`
+	SessionId string `json:"sid"`
+}
+
+// Shop returns the myshopify domain of the shop the token was issued for.
+func (c *SessionTokenClaims) Shop() string {
+	u, err := url.Parse(c.Dest)
+	if err != nil {
+		return ""
+	}
+	return u.Host
+}
+
+// SessionTokenFromRequest returns the session token App Bridge sends in the
+// Authorization header of a request, or in the id_token parameter when the
+// app is loaded, and an empty string if there is none.
+func SessionTokenFromRequest(r *http.Request) string {
+	if auth := r.Header.Get("Authorization"); len(auth) > 7 && strings.EqualFold(auth[:7], "bearer ") {
+		return strings.TrimSpace(auth[7:])
+	}
+	return r.URL.Query().Get("id_token")
+}
+
+// VerifySessionToken verifies the signature of an App Bridge session token
+// with the ApiSecret of the app, and checks it was issued for the app by a
+// shop and is valid at the current time. The errors returned wrap
+// ErrInvalidSessionToken.
+func (app App) VerifySessionToken(token string) (*SessionTokenClaims, error) {
+	invalid := func(format string, a ...interface{}) error {
+		return fmt.Errorf("%w: %s", ErrInvalidSessionToken, fmt.Sprintf(format, a...))
+	}
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, invalid("malformed token")
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+	}
+	if err := decodeJWTSegment(parts[0], &header); err != nil {
+		return nil, invalid("decoding header: %s", err)
+	}
+	if header.Alg != "HS256" {
+		return nil, invalid("unexpected algorithm %q", header.Alg)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, invalid("decoding signature: %s", err)
+	}
+	mac := hmac.New(sha256.New, []byte(app.ApiSecret))
+	mac.Write([]byte(parts[0] + "." + parts[1]))
+	if !hmac.Equal(signature, mac.Sum(nil)) {
+		return nil, invalid("signature mismatch")
+	}
+
+	claims := new(SessionTokenClaims)
+	if err := decodeJWTSegment(parts[1], claims); err != nil {
+		return nil, invalid("decoding claims: %s", err)
+	}
+
+	now := time.Now()
+	if claims.Audience != app.ApiKey {
+		return nil, invalid("issued for %q", claims.Audience)
+	}
+	if now.After(time.Unix(claims.ExpiresAt, 0).Add(sessionTokenLeeway)) {
+		return nil, invalid("expired")
+	}
+	if now.Add(sessionTokenLeeway).Before(time.Unix(claims.NotBefore, 0)) {
+		return nil, invalid("not valid yet")
+	}
+
+	shop := claims.Shop()
+	if !ValidShopDomain(shop) || claims.Dest != "https://"+shop {
+		return nil, invalid("unexpected destination %q", claims.Dest)
+	}
+	if claims.Issuer != claims.Dest+"/admin" {
+		return nil, invalid("issuer %q doesn't match destination %q", claims.Issuer, claims.Dest)
+	}
+
+	return claims, nil
+}
+
+func decodeJWTSegment(segment string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// ExchangeSessionToken verifies an App Bridge session token and exchanges it
+// for an access token of the shop it was issued by, letting embedded apps
+// skip the redirects of the authorization code flow.
+// See: https://shopify.dev/docs/apps/auth/get-access-tokens/token-exchange
+func (app App) ExchangeSessionToken(ctx context.Context, sessionToken string, tokenType AccessTokenType) (*OAuthToken, error) {
+	claims, err := app.VerifySessionToken(sessionToken)
+	if err != nil {
+		return nil, err
+	}
+
+	data := struct {
+		ClientId           string          `json:"client_id"`
+		ClientSecret       string          `json:"client_secret"`
+		GrantType          string          `json:"grant_type"`
+		SubjectToken       string          `json:"subject_token"`
+		SubjectTokenType   string          `json:"subject_token_type"`
+		RequestedTokenType AccessTokenType `json:"requested_token_type"`
+		Expiring           string          `json:"expiring,omitempty"`
+	}{
+		ClientId:           app.ApiKey,
+		ClientSecret:       app.ApiSecret,
+		GrantType:          tokenExchangeGrantType,
+		SubjectToken:       sessionToken,
+		SubjectTokenType:   idTokenType,
+		RequestedTokenType: tokenType,
+	}
+	if tokenType == OfflineAccessToken {
+		data.Expiring = app.expiring()
+	}
+
+	return app.requestToken(ctx, claims.Shop(), data)
+}
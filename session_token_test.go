@@ -0,0 +1,217 @@
+package goshopify
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jarcoal/httpmock"
+)
+
+// newSessionToken signs claims as App Bridge does
+func newSessionToken(secret, alg string, claims interface{}) string {
+	encode := func(v interface{}) string {
+		data, _ := json.Marshal(v)
+		return base64.RawURLEncoding.EncodeToString(data)
+	}
+
+	unsigned := encode(map[string]string{"alg": alg, "typ": "JWT"}) + "." + encode(claims)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(unsigned))
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func validSessionTokenClaims(now time.Time) SessionTokenClaims {
+	return SessionTokenClaims{
+		Issuer:    "https://fooshop.myshopify.com/admin",
+		Dest:      "https://fooshop.myshopify.com",
+		Audience:  "apikey",
+		Subject:   "42",
+		ExpiresAt: now.Add(time.Minute).Unix(),
+		NotBefore: now.Add(-time.Second).Unix(),
+		IssuedAt:  now.Add(-time.Second).Unix(),
+		Id:        "f8912129-1af6-4cad-9ca3-76b0f7621087",
+		SessionId: "aaea182f2732d44c23057c0fea584021a4485b2bd25d3eb7fd349313ad24c685",
+	}
+}
+
+func TestVerifySessionToken(t *testing.T) {
+	setup()
+	defer teardown()
+
+	now := time.Now()
+	claims, err := app.VerifySessionToken(newSessionToken("hush", "HS256", validSessionTokenClaims(now)))
+	if err != nil {
+		t.Fatalf("App.VerifySessionToken returned error: %v", err)
+	}
+	if *claims != validSessionTokenClaims(now) {
+		t.Errorf("App.VerifySessionToken returned %+v, expected %+v", claims, validSessionTokenClaims(now))
+	}
+	if claims.Shop() != "fooshop.myshopify.com" {
+		t.Errorf("claims.Shop() = %s, expected fooshop.myshopify.com", claims.Shop())
+	}
+}
+
+func TestVerifySessionTokenInvalid(t *testing.T) {
+	setup()
+	defer teardown()
+
+	now := time.Now()
+	modified := func(modify func(c *SessionTokenClaims)) SessionTokenClaims {
+		c := validSessionTokenClaims(now)
+		modify(&c)
+		return c
+	}
+
+	cases := []struct {
+		name  string
+		token string
+	}{
+		{"malformed", "not.a.jwt.token"},
+		{"wrong secret", newSessionToken("other", "HS256", validSessionTokenClaims(now))},
+		{"unsigned", newSessionToken("hush", "none", validSessionTokenClaims(now))},
+		{"other app", newSessionToken("hush", "HS256", modified(func(c *SessionTokenClaims) { c.Audience = "otherkey" }))},
+		{"expired", newSessionToken("hush", "HS256", modified(func(c *SessionTokenClaims) { c.ExpiresAt = now.Add(-time.Minute).Unix() }))},
+		{"not yet valid", newSessionToken("hush", "HS256", modified(func(c *SessionTokenClaims) { c.NotBefore = now.Add(time.Minute).Unix() }))},
+		{"foreign dest", newSessionToken("hush", "HS256", modified(func(c *SessionTokenClaims) { c.Dest = "https://evil.com" }))},
+		{"dest with path", newSessionToken("hush", "HS256", modified(func(c *SessionTokenClaims) { c.Dest = "https://fooshop.myshopify.com/x" }))},
+		{"issuer mismatch", newSessionToken("hush", "HS256", modified(func(c *SessionTokenClaims) { c.Issuer = "https://barshop.myshopify.com/admin" }))},
+	}
+
+	for _, c := range cases {
+		_, err := app.VerifySessionToken(c.token)
+		if !errors.Is(err, ErrInvalidSessionToken) {
+			t.Errorf("%s: App.VerifySessionToken returned error %v, expected ErrInvalidSessionToken", c.name, err)
+		}
+	}
+}
+
+func TestVerifySessionTokenLeeway(t *testing.T) {
+	setup()
+	defer teardown()
+
+	now := time.Now()
+	claims := validSessionTokenClaims(now)
+	claims.ExpiresAt = now.Add(-2 * time.Second).Unix()
+	claims.NotBefore = now.Add(2 * time.Second).Unix()
+
+	if _, err := app.VerifySessionToken(newSessionToken("hush", "HS256", claims)); err != nil {
+		t.Errorf("App.VerifySessionToken returned error %v, expected the clock skew to be tolerated", err)
+	}
+}
+
+func TestSessionTokenFromRequest(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Authorization", "Bearer abc.def.ghi")
+	if token := SessionTokenFromRequest(req); token != "abc.def.ghi" {
+		t.Errorf("SessionTokenFromRequest() = %q, expected abc.def.ghi", token)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/?shop=fooshop.myshopify.com&id_token=abc.def.ghi", nil)
+	if token := SessionTokenFromRequest(req); token != "abc.def.ghi" {
+		t.Errorf("SessionTokenFromRequest() = %q, expected abc.def.ghi", token)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Authorization", "Basic Zm9vOmJhcg==")
+	if token := SessionTokenFromRequest(req); token != "" {
+		t.Errorf("SessionTokenFromRequest() = %q, expected none", token)
+	}
+}
+
+func TestExchangeSessionToken(t *testing.T) {
+	setup()
+	defer teardown()
+
+	sessionToken := newSessionToken("hush", "HS256", validSessionTokenClaims(time.Now()))
+
+	var body map[string]string
+	httpmock.RegisterResponder("POST", "https://fooshop.myshopify.com/admin/oauth/access_token",
+		func(req *http.Request) (*http.Response, error) {
+			data, _ := io.ReadAll(req.Body)
+			json.Unmarshal(data, &body)
+			return httpmock.NewStringResponse(200, `{"access_token":"usertoken","scope":"read_products","expires_in":86399,"associated_user":{"id":42}}`), nil
+		})
+
+	app.Client = client
+	token, err := app.ExchangeSessionToken(context.Background(), sessionToken, OnlineAccessToken)
+	if err != nil {
+		t.Fatalf("App.ExchangeSessionToken returned error: %v", err)
+	}
+
+	expectedBody := map[string]string{
+		"client_id":            "apikey",
+		"client_secret":        "hush",
+		"grant_type":           "urn:ietf:params:oauth:grant-type:token-exchange",
+		"subject_token":        sessionToken,
+		"subject_token_type":   "urn:ietf:params:oauth:token-type:id_token",
+		"requested_token_type": "urn:shopify:params:oauth:token-type:online-access-token",
+	}
+	for k, v := range expectedBody {
+		if body[k] != v {
+			t.Errorf("request %s = %q, expected %q", k, body[k], v)
+		}
+	}
+
+	if token.Shop != "fooshop.myshopify.com" || token.AccessToken != "usertoken" || !token.Online() || token.ExpiresAt == nil {
+		t.Errorf("App.ExchangeSessionToken returned %+v, expected an online token for fooshop", token)
+	}
+}
+
+func TestExchangeSessionTokenExpiring(t *testing.T) {
+	setup()
+	defer teardown()
+
+	sessionToken := newSessionToken("hush", "HS256", validSessionTokenClaims(time.Now()))
+
+	var body map[string]string
+	httpmock.RegisterResponder("POST", "https://fooshop.myshopify.com/admin/oauth/access_token",
+		func(req *http.Request) (*http.Response, error) {
+			data, _ := io.ReadAll(req.Body)
+			body = nil
+			json.Unmarshal(data, &body)
+			return httpmock.NewStringResponse(200, `{"access_token":"shoptoken","expires_in":3600,"refresh_token":"shoprefresh"}`), nil
+		})
+
+	expiringApp := app
+	expiringApp.ExpiringOfflineTokens = true
+	expiringApp.Client = client
+
+	token, err := expiringApp.ExchangeSessionToken(context.Background(), sessionToken, OfflineAccessToken)
+	if err != nil {
+		t.Fatalf("App.ExchangeSessionToken returned error: %v", err)
+	}
+	if body["expiring"] != "1" || token.RefreshToken != "shoprefresh" {
+		t.Errorf("token requested with %v returned %+v, expected an expiring offline token", body, token)
+	}
+
+	// online access tokens always expire
+	if _, err := expiringApp.ExchangeSessionToken(context.Background(), sessionToken, OnlineAccessToken); err != nil {
+		t.Fatalf("App.ExchangeSessionToken returned error: %v", err)
+	}
+	if _, ok := body["expiring"]; ok {
+		t.Errorf("online token requested with %v, expected no expiring parameter", body)
+	}
+}
+
+func TestExchangeSessionTokenInvalid(t *testing.T) {
+	setup()
+	defer teardown()
+
+	app.Client = client
+	sessionToken := newSessionToken("other", "HS256", validSessionTokenClaims(time.Now()))
+	if _, err := app.ExchangeSessionToken(context.Background(), sessionToken, OfflineAccessToken); !errors.Is(err, ErrInvalidSessionToken) {
+		t.Errorf("App.ExchangeSessionToken returned error %v, expected ErrInvalidSessionToken", err)
+	}
+	if calls := httpmock.GetTotalCallCount(); calls != 0 {
+		t.Errorf("App.ExchangeSessionToken made %d calls with an invalid session token, expected none", calls)
+	}
+}
@@ -0,0 +1,95 @@
+package goshopify
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+const (
+	storefrontApiPathPrefix = "api"
+
+	storefrontAccessTokenHeader  = "X-Shopify-Storefront-Access-Token"
+	storefrontPrivateTokenHeader = "Shopify-Storefront-Private-Token"
+	storefrontBuyerIPHeader      = "Shopify-Storefront-Buyer-IP"
+)
+
+// StorefrontClient manages communication with the Storefront GraphQL API of
+// a shop, authenticated with a token created by
+// StorefrontAccessTokenService.Create or a private token.
+// See https://shopify.dev/docs/api/storefront
+type StorefrontClient struct {
+	client *Client
+
+	// GraphQL runs queries and mutations against the Storefront API, the
+	// responses and errors are handled like those of the Admin API, see
+	// GraphQLResponseError and UserErrors
+	GraphQL GraphQLService
+	Cart    StorefrontCartService
+}
+
+// NewStorefrontClient returns a client of the Storefront API of the shop,
+// authenticated with a public access token, e.g. one created with
+// StorefrontAccessTokenService.Create. The shopName parameter is the shop's
+// myshopify domain, e.g. "theshop.myshopify.com", or simply "theshop". The
+// options of NewClient apply, e.g. WithVersion.
+func NewStorefrontClient(shopName, token string, opts ...Option) (*StorefrontClient, error) {
+	return newStorefrontClient(shopName, token, storefrontAccessTokenHeader, opts...)
+}
+
+// NewPrivateStorefrontClient returns a client of the Storefront API of the
+// shop authenticated with a private access token, meant for server side
+// requests. The IP of the buyer on whose behalf a request is made should be
+// set with WithBuyerIP, or Shopify may throttle all buyers together.
+func NewPrivateStorefrontClient(shopName, token string, opts ...Option) (*StorefrontClient, error) {
+	return newStorefrontClient(shopName, token, storefrontPrivateTokenHeader, opts...)
+}
+
+func newStorefrontClient(shopName, token, tokenHeader string, opts ...Option) (*StorefrontClient, error) {
+	c, err := NewClient(App{}, shopName, "", opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	c.storefrontToken = token
+	c.storefrontTokenHeader = tokenHeader
+	c.pathPrefix = storefrontApiPathPrefix
+	if apiVersionRegex.MatchString(c.apiVersion) || c.apiVersion == UnstableApiVersion {
+		c.pathPrefix = fmt.Sprintf("%s/%s", storefrontApiPathPrefix, c.apiVersion)
+	}
+
+	return &StorefrontClient{
+		client:  c,
+		GraphQL: c.GraphQL,
+		Cart:    &StorefrontCartServiceOp{client: c},
+	}, nil
+}
+
+// GetRateLimits returns the rate limit state reported by the last responses
+func (s *StorefrontClient) GetRateLimits() RateLimitInfo {
+	return s.client.GetRateLimits()
+}
+
+// buyerIPKey is the context key under which the IP set by WithBuyerIP is
+// stored
+type buyerIPKey struct{}
+
+// WithBuyerIP returns a copy of ctx which sends the IP of the buyer with the
+// Storefront API requests of a client created by NewPrivateStorefrontClient.
+//
+//	cart, err := storefront.Cart.Get(goshopify.WithBuyerIP(ctx, buyerIP), cartId)
+func WithBuyerIP(ctx context.Context, ip string) context.Context {
+	return context.WithValue(ctx, buyerIPKey{}, ip)
+}
+
+// setStorefrontHeaders authenticates req with the Storefront token of the
+// client, along with the IP of the buyer for private tokens
+func (c *Client) setStorefrontHeaders(req *http.Request) {
+	req.Header.Set(c.storefrontTokenHeader, c.storefrontToken)
+	if c.storefrontTokenHeader != storefrontPrivateTokenHeader {
+		return
+	}
+	if ip, _ := req.Context().Value(buyerIPKey{}).(string); ip != "" {
+		req.Header.Set(storefrontBuyerIPHeader, ip)
+	}
+}
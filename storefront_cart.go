@@ -0,0 +1,345 @@
+package goshopify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// StorefrontCartService is an interface to interact with the carts of the
+// Storefront API. Buyers complete their purchase on the CheckoutUrl of the
+// cart, which replaced the checkouts of the Storefront API.
+// See https://shopify.dev/docs/storefronts/headless/building-with-the-storefront-api/cart
+type StorefrontCartService interface {
+	Create(ctx context.Context, input CartInput) (*Cart, error)
+	Get(ctx context.Context, cartId GID) (*Cart, error)
+	AddLines(ctx context.Context, cartId GID, lines []CartLineInput) (*Cart, error)
+	UpdateLines(ctx context.Context, cartId GID, lines []CartLineUpdateInput) (*Cart, error)
+	RemoveLines(ctx context.Context, cartId GID, lineIds []GID) (*Cart, error)
+	UpdateBuyerIdentity(ctx context.Context, cartId GID, buyerIdentity CartBuyerIdentityInput) (*Cart, error)
+	UpdateDiscountCodes(ctx context.Context, cartId GID, discountCodes []string) (*Cart, error)
+	UpdateNote(ctx context.Context, cartId GID, note string) (*Cart, error)
+	CheckoutUrl(ctx context.Context, cartId GID) (string, error)
+}
+
+// StorefrontCartServiceOp handles communication with the cart related
+// methods of the Storefront API.
+type StorefrontCartServiceOp struct {
+	client *Client
+}
+
+// ErrCartNotFound is returned by StorefrontCartService when the cart doesn't
+// exist, e.g. because it expired or was completed.
+var ErrCartNotFound = errors.New("cart not found")
+
+// Cart represents a Storefront API cart
+type Cart struct {
+	// Id is the global id of the cart, including its key, e.g.
+	// gid://shopify/Cart/c1-abc?key=def
+	Id            GID                `json:"id"`
+	CheckoutUrl   string             `json:"checkoutUrl"`
+	CreatedAt     *time.Time         `json:"createdAt"`
+	UpdatedAt     *time.Time         `json:"updatedAt"`
+	Note          string             `json:"note"`
+	TotalQuantity int                `json:"totalQuantity"`
+	Attributes    []CartAttribute    `json:"attributes"`
+	BuyerIdentity *CartBuyerIdentity `json:"buyerIdentity"`
+	DiscountCodes []CartDiscountCode `json:"discountCodes"`
+	Cost          *CartCost          `json:"cost"`
+
+	// Lines are the first 250 lines of the cart
+	Lines []CartLine `json:"lines"`
+}
+
+// UnmarshalJSON implements json.Unmarshaler, flattening the lines connection
+// of the cart.
+func (c *Cart) UnmarshalJSON(data []byte) error {
+	type cart Cart
+	aux := struct {
+		*cart
+		Lines *struct {
+			Nodes []CartLine `json:"nodes"`
+		} `json:"lines"`
+	}{cart: (*cart)(c)}
+
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	if aux.Lines != nil {
+		c.Lines = aux.Lines.Nodes
+	}
+	return nil
+}
+
+// CartLine is a line of a cart, the quantity of a product variant
+type CartLine struct {
+	Id          GID             `json:"id"`
+	Quantity    int             `json:"quantity"`
+	Merchandise CartMerchandise `json:"merchandise"`
+	Cost        *CartLineCost   `json:"cost"`
+	Attributes  []CartAttribute `json:"attributes"`
+}
+
+// CartMerchandise is the product variant of a cart line
+type CartMerchandise struct {
+	Id           GID    `json:"id"`
+	Title        string `json:"title"`
+	Sku          string `json:"sku"`
+	ProductId    GID    `json:"-"`
+	ProductTitle string `json:"-"`
+}
+
+// UnmarshalJSON implements json.Unmarshaler, flattening the product of the
+// variant.
+func (m *CartMerchandise) UnmarshalJSON(data []byte) error {
+	type merchandise CartMerchandise
+	aux := struct {
+		*merchandise
+		Product *struct {
+			Id    GID    `json:"id"`
+			Title string `json:"title"`
+		} `json:"product"`
+	}{merchandise: (*merchandise)(m)}
+
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	if aux.Product != nil {
+		m.ProductId = aux.Product.Id
+		m.ProductTitle = aux.Product.Title
+	}
+	return nil
+}
+
+// CartAttribute is a custom key value pair of a cart or a cart line
+type CartAttribute struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// CartBuyerIdentity is the buyer of a cart, whose country decides the prices
+// and currency of the cart
+type CartBuyerIdentity struct {
+	Email       string `json:"email"`
+	Phone       string `json:"phone"`
+	CountryCode string `json:"countryCode"`
+}
+
+// CartDiscountCode is a discount code of a cart, Applicable is false when
+// the code doesn't apply to the cart
+type CartDiscountCode struct {
+	Code       string `json:"code"`
+	Applicable bool   `json:"applicable"`
+}
+
+// CartCost is the estimated cost of a cart, the final cost is only known at
+// checkout
+type CartCost struct {
+	SubtotalAmount Money `json:"subtotalAmount"`
+	TotalAmount    Money `json:"totalAmount"`
+}
+
+// CartLineCost is the estimated cost of a cart line
+type CartLineCost struct {
+	AmountPerQuantity Money `json:"amountPerQuantity"`
+	TotalAmount       Money `json:"totalAmount"`
+}
+
+// Money is an amount of the GraphQL MoneyV2 type
+type Money struct {
+	Amount       decimal.Decimal `json:"amount"`
+	CurrencyCode string          `json:"currencyCode"`
+}
+
+// CartInput are the fields of a new cart
+type CartInput struct {
+	Lines         []CartLineInput         `json:"lines,omitempty"`
+	Note          string                  `json:"note,omitempty"`
+	Attributes    []CartAttribute         `json:"attributes,omitempty"`
+	DiscountCodes []string                `json:"discountCodes,omitempty"`
+	BuyerIdentity *CartBuyerIdentityInput `json:"buyerIdentity,omitempty"`
+}
+
+// CartLineInput is a line added to a cart
+type CartLineInput struct {
+	MerchandiseId GID             `json:"merchandiseId"`
+	Quantity      int             `json:"quantity,omitempty"`
+	Attributes    []CartAttribute `json:"attributes,omitempty"`
+}
+
+// CartLineUpdateInput updates a line of a cart, unset fields are left
+// unchanged
+type CartLineUpdateInput struct {
+	Id            GID             `json:"id"`
+	MerchandiseId *GID            `json:"merchandiseId,omitempty"`
+	Quantity      *int            `json:"quantity,omitempty"`
+	Attributes    []CartAttribute `json:"attributes,omitempty"`
+}
+
+// CartBuyerIdentityInput sets the buyer of a cart. CustomerAccessToken
+// associates the cart with a logged in customer.
+type CartBuyerIdentityInput struct {
+	Email               string `json:"email,omitempty"`
+	Phone               string `json:"phone,omitempty"`
+	CountryCode         string `json:"countryCode,omitempty"`
+	CustomerAccessToken string `json:"customerAccessToken,omitempty"`
+}
+
+const cartFields = `id checkoutUrl createdAt updatedAt note totalQuantity
+	attributes { key value }
+	buyerIdentity { email phone countryCode }
+	discountCodes { code applicable }
+	cost { subtotalAmount { amount currencyCode } totalAmount { amount currencyCode } }
+	lines(first: 250) {
+		nodes {
+			id quantity
+			attributes { key value }
+			cost { amountPerQuantity { amount currencyCode } totalAmount { amount currencyCode } }
+			merchandise { ... on ProductVariant { id title sku product { id title } } }
+		}
+	}`
+
+// cartPayload is the payload of the cart mutations
+type cartPayload struct {
+	Cart *Cart `json:"cart"`
+}
+
+// mutate runs a cart mutation whose payload is returned in the field of
+// the same name
+func (s *StorefrontCartServiceOp) mutate(ctx context.Context, field, m string, vars map[string]interface{}) (*Cart, error) {
+	var resp map[string]cartPayload
+	err := s.client.GraphQL.Mutate(ctx, m, vars, &resp)
+	if err != nil {
+		return nil, err
+	}
+	cart := resp[field].Cart
+	if cart == nil {
+		return nil, ErrCartNotFound
+	}
+	return cart, nil
+}
+
+// Create creates a cart
+func (s *StorefrontCartServiceOp) Create(ctx context.Context, input CartInput) (*Cart, error) {
+	m := `mutation cartCreate($input: CartInput!) {
+		cartCreate(input: $input) {
+			cart { ` + cartFields + ` }
+			userErrors { field message code }
+		}
+	}`
+	return s.mutate(ctx, "cartCreate", m, map[string]interface{}{"input": input})
+}
+
+// Get retrieves a cart, returning ErrCartNotFound if it doesn't exist
+func (s *StorefrontCartServiceOp) Get(ctx context.Context, cartId GID) (*Cart, error) {
+	q := `query cart($id: ID!) {
+		cart(id: $id) { ` + cartFields + ` }
+	}`
+
+	var resp struct {
+		Cart *Cart `json:"cart"`
+	}
+	err := s.client.GraphQL.Query(ctx, q, map[string]interface{}{"id": cartId}, &resp)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Cart == nil {
+		return nil, ErrCartNotFound
+	}
+	return resp.Cart, nil
+}
+
+// AddLines adds lines to a cart
+func (s *StorefrontCartServiceOp) AddLines(ctx context.Context, cartId GID, lines []CartLineInput) (*Cart, error) {
+	m := `mutation cartLinesAdd($cartId: ID!, $lines: [CartLineInput!]!) {
+		cartLinesAdd(cartId: $cartId, lines: $lines) {
+			cart { ` + cartFields + ` }
+			userErrors { field message code }
+		}
+	}`
+	return s.mutate(ctx, "cartLinesAdd", m, map[string]interface{}{"cartId": cartId, "lines": lines})
+}
+
+// UpdateLines updates lines of a cart, e.g. their quantity
+func (s *StorefrontCartServiceOp) UpdateLines(ctx context.Context, cartId GID, lines []CartLineUpdateInput) (*Cart, error) {
+	m := `mutation cartLinesUpdate($cartId: ID!, $lines: [CartLineUpdateInput!]!) {
+		cartLinesUpdate(cartId: $cartId, lines: $lines) {
+			cart { ` + cartFields + ` }
+			userErrors { field message code }
+		}
+	}`
+	return s.mutate(ctx, "cartLinesUpdate", m, map[string]interface{}{"cartId": cartId, "lines": lines})
+}
+
+// RemoveLines removes lines from a cart
+func (s *StorefrontCartServiceOp) RemoveLines(ctx context.Context, cartId GID, lineIds []GID) (*Cart, error) {
+	m := `mutation cartLinesRemove($cartId: ID!, $lineIds: [ID!]!) {
+		cartLinesRemove(cartId: $cartId, lineIds: $lineIds) {
+			cart { ` + cartFields + ` }
+			userErrors { field message code }
+		}
+	}`
+	return s.mutate(ctx, "cartLinesRemove", m, map[string]interface{}{"cartId": cartId, "lineIds": lineIds})
+}
+
+// UpdateBuyerIdentity sets the buyer of a cart
+func (s *StorefrontCartServiceOp) UpdateBuyerIdentity(ctx context.Context, cartId GID, buyerIdentity CartBuyerIdentityInput) (*Cart, error) {
+	m := `mutation cartBuyerIdentityUpdate($cartId: ID!, $buyerIdentity: CartBuyerIdentityInput!) {
+		cartBuyerIdentityUpdate(cartId: $cartId, buyerIdentity: $buyerIdentity) {
+			cart { ` + cartFields + ` }
+			userErrors { field message code }
+		}
+	}`
+	return s.mutate(ctx, "cartBuyerIdentityUpdate", m, map[string]interface{}{"cartId": cartId, "buyerIdentity": buyerIdentity})
+}
+
+// UpdateDiscountCodes replaces the discount codes of a cart, an empty list
+// removes them
+func (s *StorefrontCartServiceOp) UpdateDiscountCodes(ctx context.Context, cartId GID, discountCodes []string) (*Cart, error) {
+	m := `mutation cartDiscountCodesUpdate($cartId: ID!, $discountCodes: [String!]!) {
+		cartDiscountCodesUpdate(cartId: $cartId, discountCodes: $discountCodes) {
+			cart { ` + cartFields + ` }
+			userErrors { field message code }
+		}
+	}`
+	if discountCodes == nil {
+		discountCodes = []string{}
+	}
+	return s.mutate(ctx, "cartDiscountCodesUpdate", m, map[string]interface{}{"cartId": cartId, "discountCodes": discountCodes})
+}
+
+// UpdateNote sets the note of a cart
+func (s *StorefrontCartServiceOp) UpdateNote(ctx context.Context, cartId GID, note string) (*Cart, error) {
+	m := `mutation cartNoteUpdate($cartId: ID!, $note: String!) {
+		cartNoteUpdate(cartId: $cartId, note: $note) {
+			cart { ` + cartFields + ` }
+			userErrors { field message code }
+		}
+	}`
+	return s.mutate(ctx, "cartNoteUpdate", m, map[string]interface{}{"cartId": cartId, "note": note})
+}
+
+// CheckoutUrl returns the url on which the buyer completes the purchase of
+// the cart
+func (s *StorefrontCartServiceOp) CheckoutUrl(ctx context.Context, cartId GID) (string, error) {
+	q := `query cartCheckoutUrl($id: ID!) {
+		cart(id: $id) { checkoutUrl }
+	}`
+
+	var resp struct {
+		Cart *struct {
+			CheckoutUrl string `json:"checkoutUrl"`
+		} `json:"cart"`
+	}
+	err := s.client.GraphQL.Query(ctx, q, map[string]interface{}{"id": cartId}, &resp)
+	if err != nil {
+		return "", err
+	}
+	if resp.Cart == nil {
+		return "", ErrCartNotFound
+	}
+	return resp.Cart.CheckoutUrl, nil
+}
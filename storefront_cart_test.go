@@ -0,0 +1,199 @@
+package goshopify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/shopspring/decimal"
+)
+
+const testCartId = "gid://shopify/Cart/c1-7a2abe82733a34e84aa472d57fb5c3c1?key=f3b1c1a8e0c5"
+
+// registerStorefrontResponder responds with the given data and records the
+// variables of the request
+func registerStorefrontResponder(data string) *map[string]interface{} {
+	vars := map[string]interface{}{}
+	httpmock.RegisterResponder("POST", fmt.Sprintf("https://fooshop.myshopify.com/api/%s/graphql.json", testApiVersion),
+		func(req *http.Request) (*http.Response, error) {
+			var body struct {
+				Variables map[string]interface{} `json:"variables"`
+			}
+			b, _ := io.ReadAll(req.Body)
+			json.Unmarshal(b, &body)
+			vars = body.Variables
+			return httpmock.NewStringResponse(200, `{"data":`+data+`}`), nil
+		})
+	return &vars
+}
+
+func cartTests(t *testing.T, cart *Cart) {
+	expectedId, _ := ParseGID(testCartId)
+	if cart.Id != expectedId {
+		t.Errorf("Cart.Id returned %+v, expected %+v", cart.Id, expectedId)
+	}
+	if cart.TotalQuantity != 3 || cart.Note != "Gift wrap please" {
+		t.Errorf("Cart returned %+v", cart)
+	}
+	if !cart.Cost.TotalAmount.Amount.Equal(decimal.RequireFromString("53.97")) || cart.Cost.TotalAmount.CurrencyCode != "CAD" {
+		t.Errorf("Cart.Cost.TotalAmount returned %+v, expected 53.97 CAD", cart.Cost.TotalAmount)
+	}
+	if len(cart.Lines) != 1 {
+		t.Fatalf("Cart.Lines returned %d lines, expected 1", len(cart.Lines))
+	}
+
+	merchandise := cart.Lines[0].Merchandise
+	expectedMerchandise := CartMerchandise{
+		Id:           NewGID(GIDTypeProductVariant, 808950810),
+		Title:        "Pink",
+		Sku:          "IPOD2008PINK",
+		ProductId:    NewGID(GIDTypeProduct, 632910392),
+		ProductTitle: "IPod Nano - 8GB",
+	}
+	if !reflect.DeepEqual(merchandise, expectedMerchandise) {
+		t.Errorf("CartLine.Merchandise returned %+v, expected %+v", merchandise, expectedMerchandise)
+	}
+}
+
+func TestStorefrontCartCreate(t *testing.T) {
+	setup()
+	defer teardown()
+
+	vars := registerStorefrontResponder(`{"cartCreate":{"cart":` + string(loadFixture("storefront_cart.json")) + `,"userErrors":[]}}`)
+
+	sc := storefrontTestClient(t, false)
+	cart, err := sc.Cart.Create(context.Background(), CartInput{
+		Lines: []CartLineInput{{MerchandiseId: NewGID(GIDTypeProductVariant, 808950810), Quantity: 3}},
+		Note:  "Gift wrap please",
+	})
+	if err != nil {
+		t.Fatalf("Cart.Create returned error: %v", err)
+	}
+	cartTests(t, cart)
+
+	expectedVars := map[string]interface{}{
+		"input": map[string]interface{}{
+			"lines": []interface{}{map[string]interface{}{"merchandiseId": "gid://shopify/ProductVariant/808950810", "quantity": float64(3)}},
+			"note":  "Gift wrap please",
+		},
+	}
+	if !reflect.DeepEqual(*vars, expectedVars) {
+		t.Errorf("Cart.Create sent variables %v, expected %v", *vars, expectedVars)
+	}
+}
+
+func TestStorefrontCartGet(t *testing.T) {
+	setup()
+	defer teardown()
+
+	vars := registerStorefrontResponder(`{"cart":` + string(loadFixture("storefront_cart.json")) + `}`)
+
+	sc := storefrontTestClient(t, false)
+	cartId, _ := ParseGID(testCartId)
+	cart, err := sc.Cart.Get(context.Background(), cartId)
+	if err != nil {
+		t.Fatalf("Cart.Get returned error: %v", err)
+	}
+	cartTests(t, cart)
+
+	if id := (*vars)["id"]; id != testCartId {
+		t.Errorf("Cart.Get sent id %v, expected %s", id, testCartId)
+	}
+}
+
+func TestStorefrontCartGetNotFound(t *testing.T) {
+	setup()
+	defer teardown()
+
+	registerStorefrontResponder(`{"cart":null}`)
+
+	sc := storefrontTestClient(t, false)
+	cartId, _ := ParseGID(testCartId)
+	if _, err := sc.Cart.Get(context.Background(), cartId); !errors.Is(err, ErrCartNotFound) {
+		t.Errorf("Cart.Get returned error %v, expected ErrCartNotFound", err)
+	}
+	if _, err := sc.Cart.CheckoutUrl(context.Background(), cartId); !errors.Is(err, ErrCartNotFound) {
+		t.Errorf("Cart.CheckoutUrl returned error %v, expected ErrCartNotFound", err)
+	}
+}
+
+func TestStorefrontCartUpdateLines(t *testing.T) {
+	setup()
+	defer teardown()
+
+	vars := registerStorefrontResponder(`{"cartLinesUpdate":{"cart":` + string(loadFixture("storefront_cart.json")) + `,"userErrors":[]}}`)
+
+	sc := storefrontTestClient(t, false)
+	cartId, _ := ParseGID(testCartId)
+	lineId, _ := ParseGID("gid://shopify/CartLine/a1b2c3?cart=c1-7a2abe82733a34e84aa472d57fb5c3c1")
+	quantity := 3
+	_, err := sc.Cart.UpdateLines(context.Background(), cartId, []CartLineUpdateInput{{Id: lineId, Quantity: &quantity}})
+	if err != nil {
+		t.Fatalf("Cart.UpdateLines returned error: %v", err)
+	}
+
+	expectedVars := map[string]interface{}{
+		"cartId": testCartId,
+		"lines":  []interface{}{map[string]interface{}{"id": lineId.String(), "quantity": float64(3)}},
+	}
+	if !reflect.DeepEqual(*vars, expectedVars) {
+		t.Errorf("Cart.UpdateLines sent variables %v, expected %v", *vars, expectedVars)
+	}
+}
+
+func TestStorefrontCartUserErrors(t *testing.T) {
+	setup()
+	defer teardown()
+
+	registerStorefrontResponder(`{"cartLinesAdd":{"cart":null,"userErrors":[{"field":["lines","0","merchandiseId"],"message":"The merchandise does not exist.","code":"INVALID"}]}}`)
+
+	sc := storefrontTestClient(t, false)
+	cartId, _ := ParseGID(testCartId)
+	_, err := sc.Cart.AddLines(context.Background(), cartId, []CartLineInput{{MerchandiseId: NewGID(GIDTypeProductVariant, 1), Quantity: 1}})
+
+	var userErrs UserErrors
+	if !errors.As(err, &userErrs) || len(userErrs) != 1 || userErrs[0].Code != "INVALID" {
+		t.Errorf("Cart.AddLines returned error %v, expected UserErrors", err)
+	}
+}
+
+func TestStorefrontCartUpdateDiscountCodes(t *testing.T) {
+	setup()
+	defer teardown()
+
+	vars := registerStorefrontResponder(`{"cartDiscountCodesUpdate":{"cart":` + string(loadFixture("storefront_cart.json")) + `,"userErrors":[]}}`)
+
+	sc := storefrontTestClient(t, false)
+	cartId, _ := ParseGID(testCartId)
+	if _, err := sc.Cart.UpdateDiscountCodes(context.Background(), cartId, nil); err != nil {
+		t.Fatalf("Cart.UpdateDiscountCodes returned error: %v", err)
+	}
+
+	// a nil list is sent as an empty list, removing the codes
+	if codes := (*vars)["discountCodes"]; !reflect.DeepEqual(codes, []interface{}{}) {
+		t.Errorf("Cart.UpdateDiscountCodes sent discount codes %v, expected []", codes)
+	}
+}
+
+func TestStorefrontCartCheckoutUrl(t *testing.T) {
+	setup()
+	defer teardown()
+
+	registerStorefrontResponder(`{"cart":{"checkoutUrl":"https://fooshop.myshopify.com/cart/c/c1-7a2abe82733a34e84aa472d57fb5c3c1?key=f3b1c1a8e0c5"}}`)
+
+	sc := storefrontTestClient(t, false)
+	cartId, _ := ParseGID(testCartId)
+	checkoutUrl, err := sc.Cart.CheckoutUrl(context.Background(), cartId)
+	if err != nil {
+		t.Fatalf("Cart.CheckoutUrl returned error: %v", err)
+	}
+	if expected := "https://fooshop.myshopify.com/cart/c/c1-7a2abe82733a34e84aa472d57fb5c3c1?key=f3b1c1a8e0c5"; checkoutUrl != expected {
+		t.Errorf("Cart.CheckoutUrl returned %q, expected %q", checkoutUrl, expected)
+	}
+}
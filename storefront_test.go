@@ -0,0 +1,111 @@
+package goshopify
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/jarcoal/httpmock"
+)
+
+// storefrontTestClient returns a Storefront client sharing the mocked http
+// client of the test client
+func storefrontTestClient(t *testing.T, private bool) *StorefrontClient {
+	newClient := NewStorefrontClient
+	if private {
+		newClient = NewPrivateStorefrontClient
+	}
+	sc, err := newClient("fooshop", "storefronttoken", WithVersion(testApiVersion), WithHTTPClient(client.Client))
+	if err != nil {
+		t.Fatalf("NewStorefrontClient returned error: %v", err)
+	}
+	return sc
+}
+
+func TestNewStorefrontClientPath(t *testing.T) {
+	cases := []struct {
+		version  string
+		expected string
+	}{
+		{testApiVersion, "api/" + testApiVersion},
+		{UnstableApiVersion, "api/unstable"},
+		{"", "api"},
+	}
+
+	for _, c := range cases {
+		sc, err := NewStorefrontClient("fooshop", "storefronttoken", WithVersion(c.version))
+		if err != nil {
+			t.Fatalf("NewStorefrontClient returned error: %v", err)
+		}
+		if sc.client.pathPrefix != c.expected {
+			t.Errorf("NewStorefrontClient(WithVersion(%q)) path prefix = %q, expected %q", c.version, sc.client.pathPrefix, c.expected)
+		}
+	}
+}
+
+func TestStorefrontClientHeaders(t *testing.T) {
+	setup()
+	defer teardown()
+
+	cases := []struct {
+		private          bool
+		expectedHeader   string
+		expectedBuyerIP  string
+		unexpectedHeader string
+	}{
+		{false, storefrontAccessTokenHeader, "", storefrontPrivateTokenHeader},
+		{true, storefrontPrivateTokenHeader, "192.0.2.1", storefrontAccessTokenHeader},
+	}
+
+	for _, c := range cases {
+		var header http.Header
+		httpmock.RegisterResponder("POST", "https://fooshop.myshopify.com/api/"+testApiVersion+"/graphql.json",
+			func(req *http.Request) (*http.Response, error) {
+				header = req.Header
+				return httpmock.NewStringResponse(200, `{"data":{"shop":{"name":"foo"}}}`), nil
+			})
+
+		sc := storefrontTestClient(t, c.private)
+		var resp struct {
+			Shop struct {
+				Name string `json:"name"`
+			} `json:"shop"`
+		}
+		ctx := WithBuyerIP(context.Background(), "192.0.2.1")
+		if err := sc.GraphQL.Query(ctx, "query { shop { name } }", nil, &resp); err != nil {
+			t.Fatalf("StorefrontClient.GraphQL.Query returned error: %v", err)
+		}
+
+		if resp.Shop.Name != "foo" {
+			t.Errorf("StorefrontClient.GraphQL.Query returned %+v", resp)
+		}
+		if token := header.Get(c.expectedHeader); token != "storefronttoken" {
+			t.Errorf("%s header = %q, expected storefronttoken", c.expectedHeader, token)
+		}
+		if ip := header.Get(storefrontBuyerIPHeader); ip != c.expectedBuyerIP {
+			t.Errorf("%s header = %q, expected %q", storefrontBuyerIPHeader, ip, c.expectedBuyerIP)
+		}
+		for _, unexpected := range []string{c.unexpectedHeader, accessTokenHeader, "Authorization"} {
+			if v := header.Get(unexpected); v != "" {
+				t.Errorf("unexpected %s header %q", unexpected, v)
+			}
+		}
+	}
+}
+
+func TestStorefrontClientErrors(t *testing.T) {
+	setup()
+	defer teardown()
+
+	httpmock.RegisterResponder("POST", "https://fooshop.myshopify.com/api/"+testApiVersion+"/graphql.json",
+		httpmock.NewStringResponder(200, `{"data":null,"errors":[{"message":"Field 'foo' doesn't exist on type 'QueryRoot'","extensions":{"code":"undefinedField"}}]}`))
+
+	sc := storefrontTestClient(t, false)
+	err := sc.GraphQL.Query(context.Background(), "query { foo }", nil, nil)
+
+	var gqlErr GraphQLError
+	if !errors.As(err, &gqlErr) || gqlErr.Code() != "undefinedField" {
+		t.Errorf("StorefrontClient.GraphQL.Query returned error %v, expected an undefinedField GraphQLError", err)
+	}
+}
@@ -0,0 +1,112 @@
+package goshopify
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+const accessTokenHeader = "X-Shopify-Access-Token"
+
+// canRefresh reports whether the client has a refresh token or uses the
+// client credentials grant
+func (c *Client) canRefresh() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.refreshToken != "" || c.clientCredentials
+}
+
+// ensureToken refreshes the token of the client before req is sent if it
+// expired, returning ErrTokenExpired if it can't be refreshed
+func (c *Client) ensureToken(req *http.Request) error {
+	c.mu.RLock()
+	expiresAt := c.tokenExpiresAt
+	token := c.token
+	missing := token == "" && c.clientCredentials
+	c.mu.RUnlock()
+
+	if !missing && (expiresAt.IsZero() || time.Now().Before(expiresAt)) {
+		// the token may have been refreshed since req was created
+		if used := req.Header.Get(accessTokenHeader); token != "" && used != token {
+			req.Header.Set(accessTokenHeader, token)
+		}
+		return nil
+	}
+
+	if !c.canRefresh() {
+		return ErrTokenExpired
+	}
+	return c.refreshAccessToken(req)
+}
+
+// refreshAccessToken renews the token of the client and sets it on req.
+// Concurrent calls only refresh the token once. An error saving the renewed
+// token is returned although the client uses it, as the previous refresh
+// token can't be used again.
+func (c *Client) refreshAccessToken(req *http.Request) error {
+	c.refreshMu.Lock()
+	defer c.refreshMu.Unlock()
+
+	c.mu.RLock()
+	token := c.token
+	expiresAt := c.tokenExpiresAt
+	refreshToken := c.refreshToken
+	refreshExpiresAt := c.refreshTokenExpiresAt
+	clientCredentials := c.clientCredentials
+	c.mu.RUnlock()
+
+	used := req.Header.Get(accessTokenHeader)
+	if token != "" && used != token && (expiresAt.IsZero() || time.Now().Before(expiresAt)) {
+		// refreshed by another request in the meantime
+		req.Header.Set(accessTokenHeader, token)
+		return nil
+	}
+
+	if !clientCredentials && !refreshExpiresAt.IsZero() && !time.Now().Before(refreshExpiresAt) {
+		return ErrTokenExpired
+	}
+
+	// the token is requested with the http client of c, not through it
+	app := c.app
+	if app.Client == nil {
+		app.Client = MustNewClient(app, c.baseURL.Host, "", WithHTTPClient(c.Client), WithLogger(c.log))
+	}
+
+	var renewed *OAuthToken
+	var err error
+	if clientCredentials {
+		renewed, err = app.GetClientCredentialsToken(req.Context(), c.baseURL.Host)
+	} else {
+		renewed, err = app.RefreshAccessToken(req.Context(), c.baseURL.Host, refreshToken)
+	}
+	if err != nil {
+		return fmt.Errorf("refreshing access token: %w", err)
+	}
+	if renewed.RefreshToken == "" {
+		renewed.RefreshToken = refreshToken
+	}
+
+	c.mu.Lock()
+	c.token = renewed.AccessToken
+	c.refreshToken = renewed.RefreshToken
+	c.tokenExpiresAt = time.Time{}
+	if renewed.ExpiresAt != nil {
+		c.tokenExpiresAt = *renewed.ExpiresAt
+	}
+	if renewed.RefreshTokenExpiresAt != nil {
+		c.refreshTokenExpiresAt = *renewed.RefreshTokenExpiresAt
+	}
+	c.mu.Unlock()
+
+	req.Header.Set(accessTokenHeader, renewed.AccessToken)
+	c.log.Infof("refreshed access token of %s", renewed.Shop)
+
+	if c.tokenStore != nil {
+		if err := c.tokenStore.SaveToken(req.Context(), renewed); err != nil {
+			return fmt.Errorf("saving refreshed access token of %s: %w", renewed.Shop, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,236 @@
+package goshopify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jarcoal/httpmock"
+)
+
+// registerRefreshResponders serves shop.json to requests authenticated with
+// validToken only, and renews the token when given refreshToken
+func registerRefreshResponders(t *testing.T, validToken, refreshToken string) {
+	httpmock.RegisterResponder("GET", fmt.Sprintf("https://fooshop.myshopify.com/%s/shop.json", client.pathPrefix),
+		func(req *http.Request) (*http.Response, error) {
+			if req.Header.Get("X-Shopify-Access-Token") != validToken {
+				return httpmock.NewStringResponse(401, `{"errors":"[API] Invalid API key or access token (unrecognized login or wrong password)"}`), nil
+			}
+			return httpmock.NewStringResponse(200, `{"shop":{"id":1}}`), nil
+		})
+
+	httpmock.RegisterResponder("POST", "https://fooshop.myshopify.com/admin/oauth/access_token",
+		func(req *http.Request) (*http.Response, error) {
+			var body map[string]string
+			data, _ := io.ReadAll(req.Body)
+			json.Unmarshal(data, &body)
+			if body["grant_type"] != "refresh_token" || body["refresh_token"] != refreshToken {
+				t.Errorf("token requested with %v, expected the refresh token grant", body)
+				return httpmock.NewStringResponse(400, `{"error":"invalid_request"}`), nil
+			}
+			return httpmock.NewStringResponse(200, fmt.Sprintf(
+				`{"access_token":%q,"expires_in":3600,"refresh_token":"newrefresh","refresh_token_expires_in":7776000,"scope":"read_products"}`,
+				validToken)), nil
+		})
+}
+
+func TestRefreshAccessToken(t *testing.T) {
+	setup()
+	defer teardown()
+
+	registerRefreshResponders(t, "newtoken", "oldrefresh")
+
+	app.Client = client
+	token, err := app.RefreshAccessToken(context.Background(), "fooshop", "oldrefresh")
+	if err != nil {
+		t.Fatalf("App.RefreshAccessToken returned error: %v", err)
+	}
+
+	if token.Shop != "fooshop.myshopify.com" || token.AccessToken != "newtoken" || token.RefreshToken != "newrefresh" {
+		t.Errorf("App.RefreshAccessToken returned %+v, expected newtoken and newrefresh", token)
+	}
+	if token.ExpiresAt == nil || token.RefreshTokenExpiresAt == nil || !token.ExpiresAt.Before(*token.RefreshTokenExpiresAt) {
+		t.Errorf("App.RefreshAccessToken returned expiries %v and %v", token.ExpiresAt, token.RefreshTokenExpiresAt)
+	}
+}
+
+func TestWithTokenRefreshOnExpiry(t *testing.T) {
+	setup()
+	defer teardown()
+
+	registerRefreshResponders(t, "newtoken", "oldrefresh")
+
+	expired := time.Now().Add(-time.Second)
+	store := NewMemoryTokenStore()
+	WithTokenRefresh(&OAuthToken{AccessToken: "oldtoken", ExpiresAt: &expired, RefreshToken: "oldrefresh"}, store)(client)
+
+	if _, err := client.Shop.Get(context.Background(), nil); err != nil {
+		t.Fatalf("Shop.Get returned error: %v", err)
+	}
+
+	info := httpmock.GetCallCountInfo()
+	if calls := info[fmt.Sprintf("GET https://fooshop.myshopify.com/%s/shop.json", client.pathPrefix)]; calls != 1 {
+		t.Errorf("shop.json requested %d times, expected the expired token not to be sent", calls)
+	}
+
+	saved, _ := store.GetToken(context.Background(), "fooshop.myshopify.com")
+	if saved == nil || saved.AccessToken != "newtoken" || saved.RefreshToken != "newrefresh" {
+		t.Errorf("stored token %+v, expected the renewed pair", saved)
+	}
+}
+
+func TestWithTokenRefreshOnUnauthorized(t *testing.T) {
+	setup()
+	defer teardown()
+
+	registerRefreshResponders(t, "newtoken", "oldrefresh")
+
+	store := NewMemoryTokenStore()
+	WithTokenRefresh(&OAuthToken{AccessToken: "oldtoken", RefreshToken: "oldrefresh"}, store)(client)
+
+	if _, err := client.Shop.Get(context.Background(), nil); err != nil {
+		t.Fatalf("Shop.Get returned error: %v", err)
+	}
+	if _, err := client.Shop.Get(context.Background(), nil); err != nil {
+		t.Fatalf("Shop.Get returned error: %v", err)
+	}
+
+	info := httpmock.GetCallCountInfo()
+	if calls := info["POST https://fooshop.myshopify.com/admin/oauth/access_token"]; calls != 1 {
+		t.Errorf("token refreshed %d times, expected once", calls)
+	}
+	if calls := info[fmt.Sprintf("GET https://fooshop.myshopify.com/%s/shop.json", client.pathPrefix)]; calls != 3 {
+		t.Errorf("shop.json requested %d times, expected 3", calls)
+	}
+	if saved, _ := store.GetToken(context.Background(), "fooshop.myshopify.com"); saved == nil || saved.AccessToken != "newtoken" {
+		t.Errorf("stored token %+v, expected newtoken", saved)
+	}
+}
+
+func TestWithTokenRefreshConcurrent(t *testing.T) {
+	setup()
+	defer teardown()
+
+	registerRefreshResponders(t, "newtoken", "oldrefresh")
+
+	expired := time.Now().Add(-time.Second)
+	WithTokenRefresh(&OAuthToken{AccessToken: "oldtoken", ExpiresAt: &expired, RefreshToken: "oldrefresh"}, nil)(client)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := client.Shop.Get(context.Background(), nil); err != nil {
+				t.Errorf("Shop.Get returned error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	info := httpmock.GetCallCountInfo()
+	if calls := info["POST https://fooshop.myshopify.com/admin/oauth/access_token"]; calls != 1 {
+		t.Errorf("token refreshed %d times, expected once", calls)
+	}
+}
+
+func TestWithTokenRefreshExpiredRefreshToken(t *testing.T) {
+	setup()
+	defer teardown()
+
+	registerRefreshResponders(t, "newtoken", "oldrefresh")
+
+	expired := time.Now().Add(-time.Second)
+	WithTokenRefresh(&OAuthToken{
+		AccessToken:           "oldtoken",
+		ExpiresAt:             &expired,
+		RefreshToken:          "oldrefresh",
+		RefreshTokenExpiresAt: &expired,
+	}, nil)(client)
+
+	if _, err := client.Shop.Get(context.Background(), nil); !errors.Is(err, ErrTokenExpired) {
+		t.Errorf("Shop.Get returned error %v, expected ErrTokenExpired", err)
+	}
+	if calls := httpmock.GetTotalCallCount(); calls != 0 {
+		t.Errorf("made %d calls with an expired refresh token, expected none", calls)
+	}
+}
+
+func TestWithTokenRefreshSaveError(t *testing.T) {
+	setup()
+	defer teardown()
+
+	registerRefreshResponders(t, "newtoken", "oldrefresh")
+
+	expired := time.Now().Add(-time.Second)
+	WithTokenRefresh(&OAuthToken{AccessToken: "oldtoken", ExpiresAt: &expired, RefreshToken: "oldrefresh"}, &failingTokenStore{})(client)
+
+	_, err := client.Shop.Get(context.Background(), nil)
+	if err == nil || !strings.Contains(err.Error(), "database unavailable") {
+		t.Fatalf("Shop.Get returned error %v, expected the error saving the token", err)
+	}
+
+	// the renewed token is used although it wasn't saved
+	if _, err := client.Shop.Get(context.Background(), nil); err != nil {
+		t.Fatalf("Shop.Get returned error: %v", err)
+	}
+	info := httpmock.GetCallCountInfo()
+	if calls := info["POST https://fooshop.myshopify.com/admin/oauth/access_token"]; calls != 1 {
+		t.Errorf("token refreshed %d times, expected once", calls)
+	}
+}
+
+func TestWithClientCredentials(t *testing.T) {
+	setup()
+	defer teardown()
+
+	tokens := 0
+	httpmock.RegisterResponder("POST", "https://fooshop.myshopify.com/admin/oauth/access_token",
+		func(req *http.Request) (*http.Response, error) {
+			var body map[string]string
+			data, _ := io.ReadAll(req.Body)
+			json.Unmarshal(data, &body)
+			if body["grant_type"] != "client_credentials" {
+				t.Errorf("token requested with %v, expected the client credentials grant", body)
+			}
+			tokens++
+			return httpmock.NewStringResponse(200, fmt.Sprintf(`{"access_token":"token%d","expires_in":86399}`, tokens)), nil
+		})
+
+	valid := "token1"
+	httpmock.RegisterResponder("GET", fmt.Sprintf("https://fooshop.myshopify.com/%s/shop.json", client.pathPrefix),
+		func(req *http.Request) (*http.Response, error) {
+			if req.Header.Get("X-Shopify-Access-Token") != valid {
+				return httpmock.NewStringResponse(401, `{"errors":"[API] Invalid API key or access token (unrecognized login or wrong password)"}`), nil
+			}
+			return httpmock.NewStringResponse(200, `{"shop":{"id":1}}`), nil
+		})
+
+	WithClientCredentials()(client)
+
+	// a token is requested before the first request, and reused
+	for i := 0; i < 2; i++ {
+		if _, err := client.Shop.Get(context.Background(), nil); err != nil {
+			t.Fatalf("Shop.Get returned error: %v", err)
+		}
+	}
+	if tokens != 1 {
+		t.Errorf("requested %d tokens, expected 1", tokens)
+	}
+
+	// and requested again once revoked
+	valid = "token2"
+	if _, err := client.Shop.Get(context.Background(), nil); err != nil {
+		t.Fatalf("Shop.Get returned error: %v", err)
+	}
+	if tokens != 2 {
+		t.Errorf("requested %d tokens, expected 2", tokens)
+	}
+}
@@ -0,0 +1,63 @@
+package goshopify
+
+import (
+	"context"
+	"sync"
+)
+
+// TokenStore persists the access tokens granted to an app, keyed by the
+// myshopify domain of the shop. Implementations must be safe for concurrent
+// use.
+type TokenStore interface {
+	// GetToken returns the token of shop, nil if there is none.
+	GetToken(ctx context.Context, shop string) (*OAuthToken, error)
+
+	// SaveToken stores token for token.Shop, replacing any previous token.
+	SaveToken(ctx context.Context, token *OAuthToken) error
+
+	// DeleteToken removes the token of shop, e.g. when the app is
+	// uninstalled. Deleting a missing token is not an error.
+	DeleteToken(ctx context.Context, shop string) error
+}
+
+// MemoryTokenStore is an in-memory TokenStore, mostly useful for tests and
+// development as tokens are lost on restart.
+type MemoryTokenStore struct {
+	mu     sync.RWMutex
+	tokens map[string]OAuthToken
+}
+
+// NewMemoryTokenStore returns an empty MemoryTokenStore.
+func NewMemoryTokenStore() *MemoryTokenStore {
+	return &MemoryTokenStore{tokens: map[string]OAuthToken{}}
+}
+
+// GetToken implements TokenStore
+func (s *MemoryTokenStore) GetToken(ctx context.Context, shop string) (*OAuthToken, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	token, ok := s.tokens[shop]
+	if !ok {
+		return nil, nil
+	}
+	return &token, nil
+}
+
+// SaveToken implements TokenStore
+func (s *MemoryTokenStore) SaveToken(ctx context.Context, token *OAuthToken) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.tokens[token.Shop] = *token
+	return nil
+}
+
+// DeleteToken implements TokenStore
+func (s *MemoryTokenStore) DeleteToken(ctx context.Context, shop string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.tokens, shop)
+	return nil
+}
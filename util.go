@@ -3,10 +3,13 @@ package goshopify
 import (
 	"fmt"
 	"net/url"
+	"regexp"
 	"strings"
 	"time"
 )
 
+var shopDomainRegexp = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9\-]*\.myshopify\.com$`)
+
 // Return the full shop name, including .myshopify.com
 func ShopFullName(name string) string {
 	name = strings.TrimSpace(name)
@@ -25,6 +28,13 @@ func ShopShortName(name string) string {
 	return strings.Replace(ShopFullName(name), ".myshopify.com", "", -1)
 }
 
+// ValidShopDomain reports whether shop is a myshopify domain, e.g.
+// fooshop.myshopify.com. Use it to check a shop parameter received from a
+// request before sending credentials to it.
+func ValidShopDomain(shop string) bool {
+	return shopDomainRegexp.MatchString(shop)
+}
+
 // Return the Shop's base url.
 func ShopBaseUrl(name string) string {
 	name = ShopFullName(name)
@@ -67,6 +67,30 @@ func TestShopBaseUrl(t *testing.T) {
 	}
 }
 
+func TestValidShopDomain(t *testing.T) {
+	cases := []struct {
+		in       string
+		expected bool
+	}{
+		{"myshop.myshopify.com", true},
+		{"my-shop-2.myshopify.com", true},
+		{"myshop", false},
+		{"-myshop.myshopify.com", false},
+		{"myshop.myshopify.com.evil.com", false},
+		{"evil.com/myshop.myshopify.com", false},
+		{"myshop.myshopify.com:8080", false},
+		{"my_shop.myshopify.com", false},
+		{"", false},
+	}
+
+	for _, c := range cases {
+		actual := ValidShopDomain(c.in)
+		if actual != c.expected {
+			t.Errorf("ValidShopDomain(%s): expected %v, actual %v", c.in, c.expected, actual)
+		}
+	}
+}
+
 func TestMetafieldPathPrefix(t *testing.T) {
 	cases := []struct {
 		resource   string
@@ -13,11 +13,16 @@ const webhooksBasePath = "webhooks"
 // See: https://shopify.dev/docs/api/admin-rest/latest/resources/webhook
 type WebhookService interface {
 	List(context.Context, interface{}) ([]Webhook, error)
+	ListAll(context.Context, interface{}) ([]Webhook, error)
+	ListWithPagination(context.Context, interface{}) ([]Webhook, *Pagination, error)
+	Pages(interface{}) *PageIterator[Webhook]
+	Iter(interface{}) *ListIterator[Webhook]
 	Count(context.Context, interface{}) (int, error)
 	Get(context.Context, uint64, interface{}) (*Webhook, error)
 	Create(context.Context, Webhook) (*Webhook, error)
 	Update(context.Context, Webhook) (*Webhook, error)
 	Delete(context.Context, uint64) error
+	Sync(context.Context, []Webhook, *WebhookSyncOptions) (*WebhookSyncPlan, error)
 }
 
 // WebhookServiceOp handles communication with the webhook-related methods of
@@ -64,6 +69,34 @@ func (s *WebhookServiceOp) List(ctx context.Context, options interface{}) ([]Web
 	return resource.Webhooks, err
 }
 
+// ListAll Lists all webhooks, iterating over pages
+func (s *WebhookServiceOp) ListAll(ctx context.Context, options interface{}) ([]Webhook, error) {
+	return listAll(ctx, s.ListWithPagination, options)
+}
+
+// ListWithPagination lists webhooks and returns pagination to retrieve next/previous results.
+func (s *WebhookServiceOp) ListWithPagination(ctx context.Context, options interface{}) ([]Webhook, *Pagination, error) {
+	path := fmt.Sprintf("%s.json", webhooksBasePath)
+	resource := new(WebhooksResource)
+
+	pagination, err := s.client.ListWithPagination(ctx, path, resource, options)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return resource.Webhooks, pagination, nil
+}
+
+// Pages returns an iterator lazily walking the pages of webhooks
+func (s *WebhookServiceOp) Pages(options interface{}) *PageIterator[Webhook] {
+	return newPageIterator(s.ListWithPagination, options)
+}
+
+// Iter returns an iterator lazily walking webhooks one at a time
+func (s *WebhookServiceOp) Iter(options interface{}) *ListIterator[Webhook] {
+	return newListIterator(s.ListWithPagination, options)
+}
+
 // Count webhooks
 func (s *WebhookServiceOp) Count(ctx context.Context, options interface{}) (int, error) {
 	path := fmt.Sprintf("%s/count.json", webhooksBasePath)
@@ -80,6 +113,9 @@ func (s *WebhookServiceOp) Get(ctx context.Context, webhookdId uint64, options i
 
 // Create a new webhook
 func (s *WebhookServiceOp) Create(ctx context.Context, webhook Webhook) (*Webhook, error) {
+	if err := s.validateTopic(webhook.Topic); err != nil {
+		return nil, err
+	}
 	path := fmt.Sprintf("%s.json", webhooksBasePath)
 	wrappedData := WebhookResource{Webhook: &webhook}
 	resource := new(WebhookResource)
@@ -89,6 +125,11 @@ func (s *WebhookServiceOp) Create(ctx context.Context, webhook Webhook) (*Webhoo
 
 // Update an existing webhook.
 func (s *WebhookServiceOp) Update(ctx context.Context, webhook Webhook) (*Webhook, error) {
+	if webhook.Topic != "" {
+		if err := s.validateTopic(webhook.Topic); err != nil {
+			return nil, err
+		}
+	}
 	path := fmt.Sprintf("%s/%d.json", webhooksBasePath, webhook.Id)
 	wrappedData := WebhookResource{Webhook: &webhook}
 	resource := new(WebhookResource)
@@ -100,3 +141,12 @@ func (s *WebhookServiceOp) Update(ctx context.Context, webhook Webhook) (*Webhoo
 func (s *WebhookServiceOp) Delete(ctx context.Context, Id uint64) error {
 	return s.client.Delete(ctx, fmt.Sprintf("%s/%d.json", webhooksBasePath, Id))
 }
+
+// validateTopic returns an error if the topic is unknown and the client
+// validates topics, see WithWebhookTopicValidation
+func (s *WebhookServiceOp) validateTopic(topic string) error {
+	if !s.client.validateWebhookTopics {
+		return nil
+	}
+	return WebhookTopic(topic).Validate()
+}
@@ -0,0 +1,519 @@
+package goshopify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	webhookTopicHeader       = "X-Shopify-Topic"
+	webhookShopDomainHeader  = "X-Shopify-Shop-Domain"
+	webhookIdHeader          = "X-Shopify-Webhook-Id"
+	webhookEventIdHeader     = "X-Shopify-Event-Id"
+	webhookApiVersionHeader  = "X-Shopify-API-Version"
+	webhookTriggeredAtHeader = "X-Shopify-Triggered-At"
+	webhookTestHeader        = "X-Shopify-Test"
+
+	// defaultWebhookMaxBodyBytes is the largest webhook payload read by
+	// default, Shopify payloads are far smaller
+	defaultWebhookMaxBodyBytes = 10 << 20
+)
+
+// WebhookMeta holds the standard headers Shopify sends with a webhook.
+type WebhookMeta struct {
+	// Topic is the webhook topic, e.g. orders/create
+	Topic WebhookTopic
+
+	// ShopDomain is the myshopify domain of the shop the webhook is from
+	ShopDomain string
+
+	// WebhookId uniquely identifies the delivery, it is the same when
+	// Shopify retries the delivery
+	WebhookId string
+
+	// EventId identifies the event, shared by the deliveries of the event to
+	// several subscriptions
+	EventId string
+
+	// ApiVersion is the API version the payload was serialized with
+	ApiVersion string
+
+	// TriggeredAt is when the event occurred, zero if not sent
+	TriggeredAt time.Time
+
+	// Test is set for webhooks sent from the Shopify admin or CLI for testing
+	Test bool
+}
+
+// NewWebhookMeta reads the standard Shopify headers of a webhook request.
+func NewWebhookMeta(r *http.Request) WebhookMeta {
+	meta := WebhookMeta{
+		Topic:      WebhookTopic(r.Header.Get(webhookTopicHeader)),
+		ShopDomain: r.Header.Get(webhookShopDomainHeader),
+		WebhookId:  r.Header.Get(webhookIdHeader),
+		EventId:    r.Header.Get(webhookEventIdHeader),
+		ApiVersion: r.Header.Get(webhookApiVersionHeader),
+	}
+	meta.TriggeredAt, _ = time.Parse(time.RFC3339Nano, r.Header.Get(webhookTriggeredAtHeader))
+	meta.Test, _ = strconv.ParseBool(r.Header.Get(webhookTestHeader))
+	return meta
+}
+
+// WebhookFunc handles the raw payload of a webhook, see WebhookHandler.Handle.
+type WebhookFunc func(ctx context.Context, meta WebhookMeta, body []byte) error
+
+// WebhookHandler is an http.Handler receiving Shopify webhooks. It verifies
+// the signature of every request, decodes the payload into the type matching
+// its topic and dispatches it to the callback registered for the topic.
+//
+//	handler := goshopify.NewWebhookHandler(app)
+//	handler.OnOrdersCreate(func(ctx context.Context, meta goshopify.WebhookMeta, order *goshopify.Order) error {
+//		return queue.Enqueue(ctx, meta.ShopDomain, order)
+//	})
+//	http.Handle("/webhooks", handler)
+//
+// It responds with:
+//   - 200 OK once the callback returned without error, if no callback is
+//     registered for the topic, or if the delivery was already processed or
+//     is stale, see Store
+//   - 401 Unauthorized if the signature is invalid
+//   - 400 Bad Request if the topic is missing or the payload can't be decoded
+//   - 409 Conflict if the delivery is being processed by another request, so
+//     Shopify retries it in case that processing fails
+//   - 500 Internal Server Error if the callback returned an error, so
+//     Shopify retries the delivery
+//
+// Callbacks must be registered before the handler serves requests.
+type WebhookHandler struct {
+	app      App
+	handlers map[WebhookTopic]WebhookFunc
+	fallback WebhookFunc
+
+	// MaxBodyBytes is the largest payload accepted, defaults to 10MB.
+	MaxBodyBytes int64
+
+	// Logger receives errors returned by callbacks and rejected requests,
+	// defaults to a LeveledLogger.
+	Logger LeveledLoggerInterface
+
+	// Store makes deliveries idempotent when set. A delivery already
+	// processed is acknowledged without calling the callback again, a
+	// delivery being processed is refused until it completes. Deliveries
+	// whose callback fails are released so they are processed when Shopify
+	// retries them.
+	Store WebhookStore
+
+	// StoreTTL is how long Store remembers deliveries, defaults to 24 hours.
+	StoreTTL time.Duration
+
+	// ClaimTTL is how long Store claims a delivery being processed, after
+	// which the claim expires if the process handling it crashed. It must
+	// exceed the time callbacks take and defaults to 1 minute.
+	ClaimTTL time.Duration
+
+	// IgnoreStale acknowledges, without calling the callback, deliveries
+	// about a resource older than a delivery of the same topic already
+	// processed for it, e.g. an orders/updated webhook arriving after a later
+	// one, or an update arriving after a later delete. Versions are compared
+	// using the updated_at of the payload, or X-Shopify-Triggered-At if it
+	// has none. Requires Store.
+	IgnoreStale bool
+}
+
+// NewWebhookHandler returns a WebhookHandler verifying webhooks with the
+// ApiSecret of app.
+func NewWebhookHandler(app App) *WebhookHandler {
+	return &WebhookHandler{
+		app:          app,
+		handlers:     map[WebhookTopic]WebhookFunc{},
+		MaxBodyBytes: defaultWebhookMaxBodyBytes,
+		Logger:       &LeveledLogger{},
+	}
+}
+
+// webhookDecodeError is returned when a payload can't be decoded
+type webhookDecodeError struct {
+	err error
+}
+
+func (e webhookDecodeError) Error() string {
+	return "decoding webhook payload: " + e.err.Error()
+}
+
+func (e webhookDecodeError) Unwrap() error {
+	return e.err
+}
+
+// ServeHTTP implements http.Handler
+func (h *WebhookHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	if h.MaxBodyBytes > 0 {
+		r.Body = http.MaxBytesReader(w, r.Body, h.MaxBodyBytes)
+	}
+
+	if ok, err := h.app.VerifyWebhookRequestVerbose(r); !ok {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			http.Error(w, http.StatusText(http.StatusRequestEntityTooLarge), http.StatusRequestEntityTooLarge)
+			return
+		}
+
+		h.Logger.Warnf("rejected webhook: %s", err)
+		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+
+	meta := NewWebhookMeta(r)
+	if meta.Topic == "" {
+		http.Error(w, "missing "+webhookTopicHeader, http.StatusBadRequest)
+		return
+	}
+
+	fn, ok := h.handlers[meta.Topic]
+	if !ok {
+		fn = h.fallback
+	}
+	if fn == nil {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	if h.Store != nil {
+		claim, err := h.claim(r.Context(), meta, body)
+		switch {
+		case err != nil:
+			h.Logger.Errorf("webhook %s from %s: %s", meta.Topic, meta.ShopDomain, err)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		case claim == WebhookInProgress:
+			http.Error(w, "delivery in progress", http.StatusConflict)
+			return
+		case claim == WebhookCompleted:
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+	}
+
+	err = fn(r.Context(), meta, body)
+	if h.Store != nil {
+		h.finish(r.Context(), meta, err)
+	}
+
+	var decodeErr webhookDecodeError
+	switch {
+	case errors.As(err, &decodeErr):
+		h.Logger.Errorf("webhook %s from %s: %s", meta.Topic, meta.ShopDomain, err)
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+	case err != nil:
+		h.Logger.Errorf("webhook %s from %s: %s", meta.Topic, meta.ShopDomain, err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+	default:
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+// claim records a delivery in the store. It returns WebhookClaimed if the
+// delivery should be processed, WebhookCompleted if it is a duplicate or stale
+// and WebhookInProgress if it is being processed.
+func (h *WebhookHandler) claim(ctx context.Context, meta WebhookMeta, body []byte) (WebhookClaim, error) {
+	claimTTL := h.ClaimTTL
+	if claimTTL <= 0 {
+		claimTTL = defaultWebhookClaimTTL
+	}
+
+	key := webhookDeliveryKey(meta)
+	claim, err := h.Store.Claim(ctx, key, claimTTL)
+	if err != nil || claim != WebhookClaimed {
+		if err == nil {
+			h.Logger.Debugf("webhook %s from %s: duplicate delivery %s", meta.Topic, meta.ShopDomain, key)
+		}
+		return claim, err
+	}
+
+	if !h.IgnoreStale {
+		return WebhookClaimed, nil
+	}
+
+	resource, version := webhookResourceVersion(meta, body)
+	if resource == "" || version.IsZero() {
+		return WebhookClaimed, nil
+	}
+
+	latest, err := h.Store.Advance(ctx, resource, version, h.storeTTL())
+	if err != nil {
+		h.finish(ctx, meta, err)
+		return WebhookInProgress, err
+	}
+	if !latest {
+		h.Logger.Debugf("webhook %s from %s: stale delivery for %s", meta.Topic, meta.ShopDomain, resource)
+		h.finish(ctx, meta, nil)
+		return WebhookCompleted, nil
+	}
+	return WebhookClaimed, nil
+}
+
+// finish completes a claimed delivery, or releases it if processing it failed.
+// It outlives the request, which Shopify may have given up on.
+func (h *WebhookHandler) finish(ctx context.Context, meta WebhookMeta, processErr error) {
+	ctx = context.WithoutCancel(ctx)
+	key := webhookDeliveryKey(meta)
+
+	if processErr != nil {
+		if err := h.Store.Release(ctx, key); err != nil {
+			h.Logger.Errorf("webhook %s from %s: releasing delivery: %s", meta.Topic, meta.ShopDomain, err)
+		}
+		return
+	}
+
+	if err := h.Store.Complete(ctx, key, h.storeTTL()); err != nil {
+		h.Logger.Errorf("webhook %s from %s: completing delivery: %s", meta.Topic, meta.ShopDomain, err)
+	}
+}
+
+func (h *WebhookHandler) storeTTL() time.Duration {
+	if h.StoreTTL > 0 {
+		return h.StoreTTL
+	}
+	return defaultWebhookStoreTTL
+}
+
+// Handle registers fn to receive the raw payload of the webhooks of topic,
+// replacing any callback registered for the topic.
+func (h *WebhookHandler) Handle(topic WebhookTopic, fn WebhookFunc) {
+	h.handlers[topic] = fn
+}
+
+// HandleDefault registers fn to receive the raw payload of the webhooks of
+// topics without a callback. Without it such webhooks are acknowledged.
+func (h *WebhookHandler) HandleDefault(fn WebhookFunc) {
+	h.fallback = fn
+}
+
+// WebhookPayloadFunc handles a payload decoded by DecodeWebhook, see
+// WebhookHandler.HandleDecoded.
+type WebhookPayloadFunc func(ctx context.Context, meta WebhookMeta, payload interface{}) error
+
+// HandleDecoded registers fn to receive the webhooks of topics without a
+// callback, with their payload decoded into the type registered for their
+// topic, e.g. a *Order for orders/create. Webhooks of unknown topics are
+// acknowledged without calling fn.
+func (h *WebhookHandler) HandleDecoded(fn WebhookPayloadFunc) {
+	h.HandleDefault(func(ctx context.Context, meta WebhookMeta, body []byte) error {
+		if !meta.Topic.Valid() {
+			h.Logger.Debugf("webhook %s from %s: unknown topic", meta.Topic, meta.ShopDomain)
+			return nil
+		}
+
+		payload, err := DecodeWebhook(meta.Topic, body)
+		if err != nil {
+			return webhookDecodeError{err: err}
+		}
+		return fn(ctx, meta, payload)
+	})
+}
+
+// HandleWebhook registers fn to receive the webhooks of topic, decoding
+// their payload into a T.
+func HandleWebhook[T any](h *WebhookHandler, topic WebhookTopic, fn func(context.Context, WebhookMeta, *T) error) {
+	h.Handle(topic, func(ctx context.Context, meta WebhookMeta, body []byte) error {
+		v := new(T)
+		if err := json.Unmarshal(body, v); err != nil {
+			return webhookDecodeError{err: err}
+		}
+		return fn(ctx, meta, v)
+	})
+}
+
+// OnOrdersCreate registers fn to receive orders/create webhooks
+func (h *WebhookHandler) OnOrdersCreate(fn func(context.Context, WebhookMeta, *Order) error) {
+	HandleWebhook(h, WebhookTopicOrdersCreate, fn)
+}
+
+// OnOrdersUpdated registers fn to receive orders/updated webhooks
+func (h *WebhookHandler) OnOrdersUpdated(fn func(context.Context, WebhookMeta, *Order) error) {
+	HandleWebhook(h, WebhookTopicOrdersUpdated, fn)
+}
+
+// OnOrdersPaid registers fn to receive orders/paid webhooks
+func (h *WebhookHandler) OnOrdersPaid(fn func(context.Context, WebhookMeta, *Order) error) {
+	HandleWebhook(h, WebhookTopicOrdersPaid, fn)
+}
+
+// OnOrdersCancelled registers fn to receive orders/cancelled webhooks
+func (h *WebhookHandler) OnOrdersCancelled(fn func(context.Context, WebhookMeta, *Order) error) {
+	HandleWebhook(h, WebhookTopicOrdersCancelled, fn)
+}
+
+// OnOrdersFulfilled registers fn to receive orders/fulfilled webhooks
+func (h *WebhookHandler) OnOrdersFulfilled(fn func(context.Context, WebhookMeta, *Order) error) {
+	HandleWebhook(h, WebhookTopicOrdersFulfilled, fn)
+}
+
+// OnOrdersPartiallyFulfilled registers fn to receive
+// orders/partially_fulfilled webhooks
+func (h *WebhookHandler) OnOrdersPartiallyFulfilled(fn func(context.Context, WebhookMeta, *Order) error) {
+	HandleWebhook(h, WebhookTopicOrdersPartiallyFulfilled, fn)
+}
+
+// OnOrdersDelete registers fn to receive orders/delete webhooks, whose
+// payload only holds the id of the order
+func (h *WebhookHandler) OnOrdersDelete(fn func(context.Context, WebhookMeta, *Order) error) {
+	HandleWebhook(h, WebhookTopicOrdersDelete, fn)
+}
+
+// OnProductsCreate registers fn to receive products/create webhooks
+func (h *WebhookHandler) OnProductsCreate(fn func(context.Context, WebhookMeta, *Product) error) {
+	HandleWebhook(h, WebhookTopicProductsCreate, fn)
+}
+
+// OnProductsUpdate registers fn to receive products/update webhooks
+func (h *WebhookHandler) OnProductsUpdate(fn func(context.Context, WebhookMeta, *Product) error) {
+	HandleWebhook(h, WebhookTopicProductsUpdate, fn)
+}
+
+// OnProductsDelete registers fn to receive products/delete webhooks, whose
+// payload only holds the id of the product
+func (h *WebhookHandler) OnProductsDelete(fn func(context.Context, WebhookMeta, *Product) error) {
+	HandleWebhook(h, WebhookTopicProductsDelete, fn)
+}
+
+// OnCustomersCreate registers fn to receive customers/create webhooks
+func (h *WebhookHandler) OnCustomersCreate(fn func(context.Context, WebhookMeta, *Customer) error) {
+	HandleWebhook(h, WebhookTopicCustomersCreate, fn)
+}
+
+// OnCustomersUpdate registers fn to receive customers/update webhooks
+func (h *WebhookHandler) OnCustomersUpdate(fn func(context.Context, WebhookMeta, *Customer) error) {
+	HandleWebhook(h, WebhookTopicCustomersUpdate, fn)
+}
+
+// OnCustomersDelete registers fn to receive customers/delete webhooks, whose
+// payload only holds the id of the customer
+func (h *WebhookHandler) OnCustomersDelete(fn func(context.Context, WebhookMeta, *Customer) error) {
+	HandleWebhook(h, WebhookTopicCustomersDelete, fn)
+}
+
+// OnFulfillmentsCreate registers fn to receive fulfillments/create webhooks
+func (h *WebhookHandler) OnFulfillmentsCreate(fn func(context.Context, WebhookMeta, *Fulfillment) error) {
+	HandleWebhook(h, WebhookTopicFulfillmentsCreate, fn)
+}
+
+// OnFulfillmentsUpdate registers fn to receive fulfillments/update webhooks
+func (h *WebhookHandler) OnFulfillmentsUpdate(fn func(context.Context, WebhookMeta, *Fulfillment) error) {
+	HandleWebhook(h, WebhookTopicFulfillmentsUpdate, fn)
+}
+
+// OnInventoryLevelsUpdate registers fn to receive inventory_levels/update
+// webhooks
+func (h *WebhookHandler) OnInventoryLevelsUpdate(fn func(context.Context, WebhookMeta, *InventoryLevel) error) {
+	HandleWebhook(h, WebhookTopicInventoryLevelsUpdate, fn)
+}
+
+// OnInventoryLevelsConnect registers fn to receive inventory_levels/connect
+// webhooks
+func (h *WebhookHandler) OnInventoryLevelsConnect(fn func(context.Context, WebhookMeta, *InventoryLevel) error) {
+	HandleWebhook(h, WebhookTopicInventoryLevelsConnect, fn)
+}
+
+// OnInventoryLevelsDisconnect registers fn to receive
+// inventory_levels/disconnect webhooks
+func (h *WebhookHandler) OnInventoryLevelsDisconnect(fn func(context.Context, WebhookMeta, *InventoryLevel) error) {
+	HandleWebhook(h, WebhookTopicInventoryLevelsDisconnect, fn)
+}
+
+// OnInventoryItemsCreate registers fn to receive inventory_items/create
+// webhooks
+func (h *WebhookHandler) OnInventoryItemsCreate(fn func(context.Context, WebhookMeta, *InventoryItem) error) {
+	HandleWebhook(h, WebhookTopicInventoryItemsCreate, fn)
+}
+
+// OnInventoryItemsUpdate registers fn to receive inventory_items/update
+// webhooks
+func (h *WebhookHandler) OnInventoryItemsUpdate(fn func(context.Context, WebhookMeta, *InventoryItem) error) {
+	HandleWebhook(h, WebhookTopicInventoryItemsUpdate, fn)
+}
+
+// OnInventoryItemsDelete registers fn to receive inventory_items/delete
+// webhooks, whose payload only holds the id of the inventory item
+func (h *WebhookHandler) OnInventoryItemsDelete(fn func(context.Context, WebhookMeta, *InventoryItem) error) {
+	HandleWebhook(h, WebhookTopicInventoryItemsDelete, fn)
+}
+
+// OnRefundsCreate registers fn to receive refunds/create webhooks
+func (h *WebhookHandler) OnRefundsCreate(fn func(context.Context, WebhookMeta, *Refund) error) {
+	HandleWebhook(h, WebhookTopicRefundsCreate, fn)
+}
+
+// OnDraftOrdersCreate registers fn to receive draft_orders/create webhooks
+func (h *WebhookHandler) OnDraftOrdersCreate(fn func(context.Context, WebhookMeta, *DraftOrder) error) {
+	HandleWebhook(h, WebhookTopicDraftOrdersCreate, fn)
+}
+
+// OnDraftOrdersUpdate registers fn to receive draft_orders/update webhooks
+func (h *WebhookHandler) OnDraftOrdersUpdate(fn func(context.Context, WebhookMeta, *DraftOrder) error) {
+	HandleWebhook(h, WebhookTopicDraftOrdersUpdate, fn)
+}
+
+// OnDraftOrdersDelete registers fn to receive draft_orders/delete webhooks,
+// whose payload only holds the id of the draft order
+func (h *WebhookHandler) OnDraftOrdersDelete(fn func(context.Context, WebhookMeta, *DraftOrder) error) {
+	HandleWebhook(h, WebhookTopicDraftOrdersDelete, fn)
+}
+
+// OnCheckoutsCreate registers fn to receive checkouts/create webhooks
+func (h *WebhookHandler) OnCheckoutsCreate(fn func(context.Context, WebhookMeta, *AbandonedCheckout) error) {
+	HandleWebhook(h, WebhookTopicCheckoutsCreate, fn)
+}
+
+// OnCheckoutsUpdate registers fn to receive checkouts/update webhooks
+func (h *WebhookHandler) OnCheckoutsUpdate(fn func(context.Context, WebhookMeta, *AbandonedCheckout) error) {
+	HandleWebhook(h, WebhookTopicCheckoutsUpdate, fn)
+}
+
+// OnCollectionsCreate registers fn to receive collections/create webhooks
+func (h *WebhookHandler) OnCollectionsCreate(fn func(context.Context, WebhookMeta, *Collection) error) {
+	HandleWebhook(h, WebhookTopicCollectionsCreate, fn)
+}
+
+// OnCollectionsUpdate registers fn to receive collections/update webhooks
+func (h *WebhookHandler) OnCollectionsUpdate(fn func(context.Context, WebhookMeta, *Collection) error) {
+	HandleWebhook(h, WebhookTopicCollectionsUpdate, fn)
+}
+
+// OnCollectionsDelete registers fn to receive collections/delete webhooks,
+// whose payload only holds the id of the collection
+func (h *WebhookHandler) OnCollectionsDelete(fn func(context.Context, WebhookMeta, *Collection) error) {
+	HandleWebhook(h, WebhookTopicCollectionsDelete, fn)
+}
+
+// OnShopUpdate registers fn to receive shop/update webhooks
+func (h *WebhookHandler) OnShopUpdate(fn func(context.Context, WebhookMeta, *Shop) error) {
+	HandleWebhook(h, WebhookTopicShopUpdate, fn)
+}
+
+// OnAppUninstalled registers fn to receive app/uninstalled webhooks
+func (h *WebhookHandler) OnAppUninstalled(fn func(context.Context, WebhookMeta, *Shop) error) {
+	HandleWebhook(h, WebhookTopicAppUninstalled, fn)
+}
+
+// OnBulkOperationsFinish registers fn to receive bulk_operations/finish
+// webhooks, see BulkOperationService.Get
+func (h *WebhookHandler) OnBulkOperationsFinish(fn func(context.Context, WebhookMeta, *BulkOperationWebhook) error) {
+	HandleWebhook(h, WebhookTopicBulkOperationsFinish, fn)
+}
@@ -0,0 +1,202 @@
+package goshopify
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+var webhookApp = App{ApiSecret: "hush"}
+
+func newWebhookRequest(topic, body string) *http.Request {
+	mac := hmac.New(sha256.New, []byte(webhookApp.ApiSecret))
+	mac.Write([]byte(body))
+
+	req := httptest.NewRequest(http.MethodPost, "/webhooks", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(shopifyChecksumHeader, base64.StdEncoding.EncodeToString(mac.Sum(nil)))
+	req.Header.Set(webhookTopicHeader, topic)
+	req.Header.Set(webhookShopDomainHeader, "fooshop.myshopify.com")
+	req.Header.Set(webhookIdHeader, "b54557e4-bdd9-4b37-8a5f-bf7d70bcd043")
+	req.Header.Set(webhookEventIdHeader, "98880550-7158-44d4-b7cd-2c97c8a091b5")
+	req.Header.Set(webhookApiVersionHeader, "2024-01")
+	req.Header.Set(webhookTriggeredAtHeader, "2024-01-02T03:04:05.123Z")
+	req.Header.Set(webhookTestHeader, "true")
+	return req
+}
+
+func serveWebhook(h http.Handler, req *http.Request) int {
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec.Code
+}
+
+func TestWebhookHandlerDispatch(t *testing.T) {
+	handler := NewWebhookHandler(webhookApp)
+
+	var gotMeta WebhookMeta
+	var gotOrder *Order
+	handler.OnOrdersCreate(func(ctx context.Context, meta WebhookMeta, order *Order) error {
+		gotMeta = meta
+		gotOrder = order
+		return nil
+	})
+	handler.OnProductsUpdate(func(ctx context.Context, meta WebhookMeta, product *Product) error {
+		t.Errorf("products/update callback called for an orders/create webhook")
+		return nil
+	})
+
+	status := serveWebhook(handler, newWebhookRequest("orders/create", `{"id": 450789469, "email": "bob@example.com"}`))
+	if status != http.StatusOK {
+		t.Errorf("ServeHTTP responded %d, expected %d", status, http.StatusOK)
+	}
+
+	if gotOrder == nil || gotOrder.Id != 450789469 || gotOrder.Email != "bob@example.com" {
+		t.Errorf("OnOrdersCreate received %#v, expected order 450789469", gotOrder)
+	}
+
+	expectedMeta := WebhookMeta{
+		Topic:       "orders/create",
+		ShopDomain:  "fooshop.myshopify.com",
+		WebhookId:   "b54557e4-bdd9-4b37-8a5f-bf7d70bcd043",
+		EventId:     "98880550-7158-44d4-b7cd-2c97c8a091b5",
+		ApiVersion:  "2024-01",
+		TriggeredAt: time.Date(2024, 1, 2, 3, 4, 5, 123000000, time.UTC),
+		Test:        true,
+	}
+	if gotMeta != expectedMeta {
+		t.Errorf("OnOrdersCreate received %#v, expected %#v", gotMeta, expectedMeta)
+	}
+}
+
+func TestWebhookHandlerTypedCallbacks(t *testing.T) {
+	handler := NewWebhookHandler(webhookApp)
+
+	received := map[WebhookTopic]uint64{}
+	handler.OnProductsUpdate(func(ctx context.Context, meta WebhookMeta, v *Product) error {
+		received[meta.Topic] = v.Id
+		return nil
+	})
+	handler.OnCustomersCreate(func(ctx context.Context, meta WebhookMeta, v *Customer) error {
+		received[meta.Topic] = v.Id
+		return nil
+	})
+	handler.OnFulfillmentsUpdate(func(ctx context.Context, meta WebhookMeta, v *Fulfillment) error {
+		received[meta.Topic] = v.Id
+		return nil
+	})
+	handler.OnInventoryLevelsUpdate(func(ctx context.Context, meta WebhookMeta, v *InventoryLevel) error {
+		received[meta.Topic] = v.InventoryItemId
+		return nil
+	})
+	handler.OnAppUninstalled(func(ctx context.Context, meta WebhookMeta, v *Shop) error {
+		received[meta.Topic] = v.Id
+		return nil
+	})
+
+	webhooks := map[string]string{
+		"products/update":         `{"id": 1}`,
+		"customers/create":        `{"id": 2}`,
+		"fulfillments/update":     `{"id": 3}`,
+		"inventory_levels/update": `{"inventory_item_id": 4, "location_id": 5, "available": 6}`,
+		"app/uninstalled":         `{"id": 7}`,
+	}
+	for topic, body := range webhooks {
+		if status := serveWebhook(handler, newWebhookRequest(topic, body)); status != http.StatusOK {
+			t.Errorf("ServeHTTP responded %d to %s, expected %d", status, topic, http.StatusOK)
+		}
+	}
+
+	expected := map[WebhookTopic]uint64{
+		"products/update":         1,
+		"customers/create":        2,
+		"fulfillments/update":     3,
+		"inventory_levels/update": 4,
+		"app/uninstalled":         7,
+	}
+	for topic, id := range expected {
+		if received[topic] != id {
+			t.Errorf("%s callback received id %d, expected %d", topic, received[topic], id)
+		}
+	}
+}
+
+func TestWebhookHandlerStatus(t *testing.T) {
+	handler := NewWebhookHandler(webhookApp)
+	handler.OnOrdersCreate(func(ctx context.Context, meta WebhookMeta, order *Order) error {
+		return errors.New("database unavailable")
+	})
+	handler.OnProductsCreate(func(ctx context.Context, meta WebhookMeta, product *Product) error {
+		return nil
+	})
+
+	invalidSignature := newWebhookRequest("products/create", `{"id": 1}`)
+	invalidSignature.Header.Set(shopifyChecksumHeader, base64.StdEncoding.EncodeToString(make([]byte, 32)))
+
+	missingTopic := newWebhookRequest("", `{"id": 1}`)
+
+	get := newWebhookRequest("products/create", `{"id": 1}`)
+	get.Method = http.MethodGet
+
+	cases := []struct {
+		description string
+		req         *http.Request
+		status      int
+	}{
+		{"valid webhook", newWebhookRequest("products/create", `{"id": 1}`), http.StatusOK},
+		{"unhandled topic", newWebhookRequest("themes/publish", `{"id": 1}`), http.StatusOK},
+		{"callback error", newWebhookRequest("orders/create", `{"id": 1}`), http.StatusInternalServerError},
+		{"invalid payload", newWebhookRequest("products/create", `{"id": "one"}`), http.StatusBadRequest},
+		{"invalid signature", invalidSignature, http.StatusUnauthorized},
+		{"missing topic", missingTopic, http.StatusBadRequest},
+		{"wrong method", get, http.StatusMethodNotAllowed},
+	}
+
+	for _, c := range cases {
+		if status := serveWebhook(handler, c.req); status != c.status {
+			t.Errorf("%s: ServeHTTP responded %d, expected %d", c.description, status, c.status)
+		}
+	}
+}
+
+func TestWebhookHandlerMaxBodyBytes(t *testing.T) {
+	handler := NewWebhookHandler(webhookApp)
+	handler.MaxBodyBytes = 8
+
+	status := serveWebhook(handler, newWebhookRequest("products/create", `{"id": 123456789}`))
+	if status != http.StatusRequestEntityTooLarge {
+		t.Errorf("ServeHTTP responded %d, expected %d", status, http.StatusRequestEntityTooLarge)
+	}
+}
+
+func TestWebhookHandlerDefault(t *testing.T) {
+	handler := NewWebhookHandler(webhookApp)
+
+	var got string
+	handler.HandleDefault(func(ctx context.Context, meta WebhookMeta, body []byte) error {
+		got = string(meta.Topic) + " " + string(body)
+		return nil
+	})
+
+	HandleWebhook(handler, "themes/publish", func(ctx context.Context, meta WebhookMeta, theme *Theme) error {
+		got = "theme " + theme.Name
+		return nil
+	})
+
+	serveWebhook(handler, newWebhookRequest("shop/update", `{"id": 1}`))
+	if got != `shop/update {"id": 1}` {
+		t.Errorf("default callback received %q", got)
+	}
+
+	serveWebhook(handler, newWebhookRequest("themes/publish", `{"name": "Dawn"}`))
+	if got != "theme Dawn" {
+		t.Errorf("themes/publish callback received %q", got)
+	}
+}
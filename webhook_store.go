@@ -0,0 +1,478 @@
+package goshopify
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultWebhookStoreTTL is how long deliveries are remembered by
+	// default, Shopify retries failed deliveries for a few hours
+	defaultWebhookStoreTTL = 24 * time.Hour
+
+	// defaultWebhookClaimTTL is how long a delivery being processed is
+	// claimed by default, Shopify gives up on a delivery after 5 seconds
+	defaultWebhookClaimTTL = time.Minute
+
+	// defaultMemoryWebhookStoreCapacity is the number of entries kept by a
+	// MemoryWebhookStore by default
+	defaultMemoryWebhookStoreCapacity = 10000
+)
+
+// WebhookClaim is the state of a delivery returned by WebhookStore.Claim
+type WebhookClaim int
+
+const (
+	// WebhookClaimed means the delivery was claimed by the caller, which
+	// must Complete or Release it
+	WebhookClaimed WebhookClaim = iota
+
+	// WebhookInProgress means the delivery is being processed by another
+	// caller
+	WebhookInProgress
+
+	// WebhookCompleted means the delivery was already processed
+	WebhookCompleted
+)
+
+// WebhookStore records the webhook deliveries processed by a WebhookHandler so
+// a delivery Shopify sends more than once is only processed once, see
+// WebhookHandler.Store. Implementations must be safe for concurrent use.
+//
+// All operations map onto a single SQL table keyed by key, e.g.
+//
+//	CREATE TABLE shopify_webhooks (key TEXT PRIMARY KEY, version TIMESTAMP, completed BOOLEAN, expires_at TIMESTAMP)
+//
+// where Claim is an INSERT ... ON CONFLICT DO NOTHING (after deleting an
+// expired row) selecting completed when no row was inserted, Complete an
+// upsert of completed and expires_at, Release a DELETE, and Advance an upsert
+// guarded by version < excluded.version.
+type WebhookStore interface {
+	// Claim records the delivery identified by key as being processed for
+	// ttl, after which the claim expires if the delivery was neither
+	// completed nor released. It returns WebhookInProgress or
+	// WebhookCompleted, leaving the delivery as is, if it was already
+	// claimed and the claim has not expired.
+	Claim(ctx context.Context, key string, ttl time.Duration) (WebhookClaim, error)
+
+	// Complete records the claimed delivery as processed for ttl.
+	Complete(ctx context.Context, key string, ttl time.Duration) error
+
+	// Release removes the claim of a delivery which failed, so it is
+	// processed again when Shopify retries it.
+	Release(ctx context.Context, key string) error
+
+	// Advance records version as the latest version of resource for ttl. It
+	// returns false, leaving the recorded version as is, if a later version
+	// is recorded.
+	Advance(ctx context.Context, resource string, version time.Time, ttl time.Duration) (bool, error)
+}
+
+// webhookDeliveryKey identifies a delivery by its event, shared by the
+// deliveries of the event to duplicate subscriptions, falling back to the id
+// of the delivery which is kept when Shopify retries it.
+func webhookDeliveryKey(meta WebhookMeta) string {
+	if meta.EventId != "" {
+		return fmt.Sprintf("%s/%s/event/%s", meta.ShopDomain, meta.Topic, meta.EventId)
+	}
+	return fmt.Sprintf("%s/webhook/%s", meta.ShopDomain, meta.WebhookId)
+}
+
+// webhookResourceVersion returns the resource a payload is about and its
+// version, the updated_at of the payload or when the event was triggered. The
+// resource is empty if the payload has no id.
+//
+// Updates and deletes of a resource share their versions, e.g.
+// fooshop.myshopify.com/orders/1, so an update is stale after a later delete.
+// The versions of other topics are kept apart, e.g.
+// fooshop.myshopify.com/orders/paid/1, as an orders/paid delivery is not
+// superseded by a later orders/updated one.
+func webhookResourceVersion(meta WebhookMeta, body []byte) (string, time.Time) {
+	var payload struct {
+		Id        json.Number `json:"id"`
+		UpdatedAt *time.Time  `json:"updated_at"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil || payload.Id == "" {
+		return "", time.Time{}
+	}
+
+	resource, action, _ := strings.Cut(string(meta.Topic), "/")
+	switch action {
+	case "update", "updated", "delete":
+	default:
+		resource = string(meta.Topic)
+	}
+
+	version := meta.TriggeredAt
+	if payload.UpdatedAt != nil {
+		version = *payload.UpdatedAt
+	}
+
+	return fmt.Sprintf("%s/%s/%s", meta.ShopDomain, resource, payload.Id), version
+}
+
+type memoryWebhookEntry struct {
+	key       string
+	version   time.Time
+	completed bool
+	expires   time.Time
+}
+
+// MemoryWebhookStore is an in-memory WebhookStore evicting the least recently
+// used entries once it holds Capacity entries. It only deduplicates the
+// deliveries received by a single process.
+type MemoryWebhookStore struct {
+	mu       sync.Mutex
+	capacity int
+	entries  map[string]*list.Element
+	lru      *list.List
+
+	// Internal testing use only.
+	now func() time.Time
+}
+
+// NewMemoryWebhookStore returns a MemoryWebhookStore holding up to capacity
+// entries, or 10000 if capacity is not positive.
+func NewMemoryWebhookStore(capacity int) *MemoryWebhookStore {
+	if capacity <= 0 {
+		capacity = defaultMemoryWebhookStoreCapacity
+	}
+	return &MemoryWebhookStore{
+		capacity: capacity,
+		entries:  map[string]*list.Element{},
+		lru:      list.New(),
+		now:      time.Now,
+	}
+}
+
+// Claim implements WebhookStore
+func (s *MemoryWebhookStore) Claim(ctx context.Context, key string, ttl time.Duration) (WebhookClaim, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if entry, ok := s.get("claim/" + key); ok {
+		if entry.completed {
+			return WebhookCompleted, nil
+		}
+		return WebhookInProgress, nil
+	}
+
+	s.set("claim/"+key, time.Time{}, ttl)
+	return WebhookClaimed, nil
+}
+
+// Complete implements WebhookStore
+func (s *MemoryWebhookStore) Complete(ctx context.Context, key string, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.set("claim/"+key, time.Time{}, ttl).completed = true
+	return nil
+}
+
+// Release implements WebhookStore
+func (s *MemoryWebhookStore) Release(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if e, ok := s.entries["claim/"+key]; ok {
+		s.remove(e)
+	}
+	return nil
+}
+
+// Advance implements WebhookStore
+func (s *MemoryWebhookStore) Advance(ctx context.Context, resource string, version time.Time, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if entry, ok := s.get("version/" + resource); ok && entry.version.After(version) {
+		return false, nil
+	}
+
+	s.set("version/"+resource, version, ttl)
+	return true, nil
+}
+
+// Len returns the number of entries held, including expired entries which
+// have not been evicted yet.
+func (s *MemoryWebhookStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.lru.Len()
+}
+
+// get returns the entry of key unless it expired
+func (s *MemoryWebhookStore) get(key string) (*memoryWebhookEntry, bool) {
+	e, ok := s.entries[key]
+	if !ok {
+		return nil, false
+	}
+
+	entry := e.Value.(*memoryWebhookEntry)
+	if !s.now().Before(entry.expires) {
+		s.remove(e)
+		return nil, false
+	}
+
+	s.lru.MoveToFront(e)
+	return entry, true
+}
+
+func (s *MemoryWebhookStore) set(key string, version time.Time, ttl time.Duration) *memoryWebhookEntry {
+	entry := &memoryWebhookEntry{key: key, version: version, expires: s.now().Add(ttl)}
+	if e, ok := s.entries[key]; ok {
+		e.Value = entry
+		s.lru.MoveToFront(e)
+		return entry
+	}
+
+	s.entries[key] = s.lru.PushFront(entry)
+	for s.lru.Len() > s.capacity {
+		s.remove(s.lru.Back())
+	}
+	return entry
+}
+
+func (s *MemoryWebhookStore) remove(e *list.Element) {
+	s.lru.Remove(e)
+	delete(s.entries, e.Value.(*memoryWebhookEntry).key)
+}
+
+// FileWebhookStore is a WebhookStore keeping one file per entry in Dir, so
+// deliveries are remembered across restarts. Claims are atomic across
+// processes sharing Dir, which must support hard links, versions are only
+// guarded within a process. Expired files are removed by Purge.
+type FileWebhookStore struct {
+	Dir string
+
+	mu sync.Mutex
+
+	// Internal testing use only.
+	now func() time.Time
+}
+
+type fileWebhookEntry struct {
+	Version   time.Time `json:"version,omitempty"`
+	Completed bool      `json:"completed,omitempty"`
+	Expires   time.Time `json:"expires"`
+}
+
+// NewFileWebhookStore returns a FileWebhookStore keeping its files in dir,
+// creating dir if needed.
+func NewFileWebhookStore(dir string) (*FileWebhookStore, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	return &FileWebhookStore{Dir: dir, now: time.Now}, nil
+}
+
+// Claim implements WebhookStore
+func (s *FileWebhookStore) Claim(ctx context.Context, key string, ttl time.Duration) (WebhookClaim, error) {
+	// the claim is written to a temporary file which is then linked into
+	// place, failing if the delivery is claimed, so a claim file is never
+	// seen partially written
+	tmp, err := s.writeTemp(fileWebhookEntry{Expires: s.time().Add(ttl)})
+	if err != nil {
+		return WebhookInProgress, err
+	}
+	defer os.Remove(tmp)
+
+	// a second attempt replaces an expired claim
+	path := s.path("claim/" + key)
+	for i := 0; i < 2; i++ {
+		err := os.Link(tmp, path)
+		if err == nil {
+			return WebhookClaimed, nil
+		}
+		if !errors.Is(err, fs.ErrExist) {
+			return WebhookInProgress, err
+		}
+
+		entry, err := s.read(path)
+		if err != nil {
+			return WebhookInProgress, err
+		}
+		if entry != nil && s.time().Before(entry.Expires) {
+			if entry.Completed {
+				return WebhookCompleted, nil
+			}
+			return WebhookInProgress, nil
+		}
+		if err := s.removeExpired(path); err != nil {
+			return WebhookInProgress, err
+		}
+	}
+
+	return WebhookInProgress, nil
+}
+
+// Complete implements WebhookStore
+func (s *FileWebhookStore) Complete(ctx context.Context, key string, ttl time.Duration) error {
+	tmp, err := s.writeTemp(fileWebhookEntry{Completed: true, Expires: s.time().Add(ttl)})
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp)
+
+	return os.Rename(tmp, s.path("claim/"+key))
+}
+
+// Release implements WebhookStore
+func (s *FileWebhookStore) Release(ctx context.Context, key string) error {
+	err := os.Remove(s.path("claim/" + key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// Advance implements WebhookStore
+func (s *FileWebhookStore) Advance(ctx context.Context, resource string, version time.Time, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	path := s.path("version/" + resource)
+	entry, err := s.read(path)
+	if err != nil {
+		return false, err
+	}
+	if entry != nil && s.time().Before(entry.Expires) && entry.Version.After(version) {
+		return false, nil
+	}
+
+	tmp, err := s.writeTemp(fileWebhookEntry{Version: version, Expires: s.time().Add(ttl)})
+	if err != nil {
+		return false, err
+	}
+	defer os.Remove(tmp)
+
+	return true, os.Rename(tmp, path)
+}
+
+// Purge removes the files of expired entries.
+func (s *FileWebhookStore) Purge(ctx context.Context) error {
+	files, err := os.ReadDir(s.Dir)
+	if err != nil {
+		return err
+	}
+
+	for _, file := range files {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if file.IsDir() || strings.HasPrefix(file.Name(), ".") {
+			continue
+		}
+
+		path := filepath.Join(s.Dir, file.Name())
+		entry, err := s.read(path)
+		if err != nil {
+			return err
+		}
+		if entry != nil && s.time().Before(entry.Expires) {
+			continue
+		}
+		if err := s.removeExpired(path); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// path returns the file of an entry, named after the hash of its key as keys
+// contain slashes
+func (s *FileWebhookStore) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(s.Dir, hex.EncodeToString(sum[:]))
+}
+
+// read returns the entry stored in path, nil if there is none or it is
+// corrupt. Entries are never partially written, they are renamed or linked
+// into place.
+func (s *FileWebhookStore) read(path string) (*fileWebhookEntry, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	entry := new(fileWebhookEntry)
+	if err := json.Unmarshal(data, entry); err != nil {
+		return nil, nil
+	}
+	return entry, nil
+}
+
+// writeTemp writes entry to a new temporary file in Dir and returns its path
+func (s *FileWebhookStore) writeTemp(entry fileWebhookEntry) (string, error) {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return "", err
+	}
+
+	tmp, err := os.CreateTemp(s.Dir, ".tmp-*")
+	if err != nil {
+		return "", err
+	}
+
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return "", err
+	}
+	return tmp.Name(), nil
+}
+
+// removeExpired removes the expired entry in path. The entry is moved aside
+// first and put back if it was replaced by a concurrent Claim or Advance since
+// it was read, so an entry which is not expired is never removed.
+func (s *FileWebhookStore) removeExpired(path string) error {
+	aside, err := os.CreateTemp(s.Dir, ".expired-*")
+	if err != nil {
+		return err
+	}
+	aside.Close()
+	defer os.Remove(aside.Name())
+
+	if err := os.Rename(path, aside.Name()); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+
+	entry, err := s.read(aside.Name())
+	if err != nil || entry == nil || !s.time().Before(entry.Expires) {
+		return err
+	}
+	if err := os.Link(aside.Name(), path); err != nil && !errors.Is(err, fs.ErrExist) {
+		return err
+	}
+	return nil
+}
+
+func (s *FileWebhookStore) time() time.Time {
+	if s.now != nil {
+		return s.now()
+	}
+	return time.Now()
+}
@@ -0,0 +1,305 @@
+package goshopify
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+)
+
+func newTestWebhookStores(t *testing.T) (map[string]WebhookStore, *fakeClock) {
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+
+	memory := NewMemoryWebhookStore(0)
+	memory.now = clock.Now
+
+	file, err := NewFileWebhookStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewFileWebhookStore returned error: %v", err)
+	}
+	file.now = clock.Now
+
+	return map[string]WebhookStore{"memory": memory, "file": file}, clock
+}
+
+func TestWebhookStoreClaim(t *testing.T) {
+	stores, clock := newTestWebhookStores(t)
+	ctx := context.Background()
+
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			expectClaim := func(key string, expected WebhookClaim) {
+				t.Helper()
+				claim, err := store.Claim(ctx, key, time.Hour)
+				if err != nil {
+					t.Fatalf("Claim returned error: %v", err)
+				}
+				if claim != expected {
+					t.Errorf("Claim(%s) = %v, expected %v", key, claim, expected)
+				}
+			}
+
+			expectClaim("a", WebhookClaimed)
+			expectClaim("a", WebhookInProgress)
+			expectClaim("b", WebhookClaimed)
+
+			if err := store.Complete(ctx, "b", 2*time.Hour); err != nil {
+				t.Fatalf("Complete returned error: %v", err)
+			}
+			expectClaim("b", WebhookCompleted)
+
+			if err := store.Release(ctx, "a"); err != nil {
+				t.Fatalf("Release returned error: %v", err)
+			}
+			if err := store.Release(ctx, "unknown"); err != nil {
+				t.Fatalf("Release of an unknown key returned error: %v", err)
+			}
+			expectClaim("a", WebhookClaimed)
+
+			// the claim of a is abandoned, the completion of b outlives it
+			clock.Advance(time.Hour)
+			expectClaim("a", WebhookClaimed)
+			expectClaim("b", WebhookCompleted)
+
+			clock.Advance(time.Hour)
+			expectClaim("b", WebhookClaimed)
+			expectClaim("b", WebhookInProgress)
+		})
+	}
+}
+
+func TestWebhookStoreAdvance(t *testing.T) {
+	stores, clock := newTestWebhookStores(t)
+	ctx := context.Background()
+
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			start := clock.Now()
+			expectAdvance := func(version time.Time, expected bool) {
+				t.Helper()
+				latest, err := store.Advance(ctx, "orders/1", version, time.Hour)
+				if err != nil {
+					t.Fatalf("Advance returned error: %v", err)
+				}
+				if latest != expected {
+					t.Errorf("Advance(%s) = %v, expected %v", version, latest, expected)
+				}
+			}
+
+			expectAdvance(start.Add(2*time.Minute), true)
+			expectAdvance(start.Add(time.Minute), false)
+			expectAdvance(start.Add(2*time.Minute), true)
+			expectAdvance(start.Add(3*time.Minute), true)
+			expectAdvance(start.Add(2*time.Minute), false)
+
+			clock.Advance(2 * time.Hour)
+			expectAdvance(start, true)
+		})
+	}
+}
+
+func TestMemoryWebhookStoreEviction(t *testing.T) {
+	store := NewMemoryWebhookStore(2)
+	ctx := context.Background()
+
+	store.Claim(ctx, "a", time.Hour)
+	store.Claim(ctx, "b", time.Hour)
+	store.Claim(ctx, "a", time.Hour) // a is now the most recently used
+	store.Claim(ctx, "c", time.Hour)
+
+	if store.Len() != 2 {
+		t.Errorf("Len() = %d, expected 2", store.Len())
+	}
+	if claim, _ := store.Claim(ctx, "a", time.Hour); claim != WebhookInProgress {
+		t.Errorf("Claim(a) = %v, a should not have been evicted", claim)
+	}
+	if claim, _ := store.Claim(ctx, "b", time.Hour); claim != WebhookClaimed {
+		t.Errorf("Claim(b) = %v, b should have been evicted", claim)
+	}
+}
+
+func TestFileWebhookStoreCorruptClaim(t *testing.T) {
+	stores, _ := newTestWebhookStores(t)
+	store := stores["file"].(*FileWebhookStore)
+	ctx := context.Background()
+
+	// e.g. left by a crash of a process writing its claim in place
+	if err := os.WriteFile(store.path("claim/a"), nil, 0o600); err != nil {
+		t.Fatalf("WriteFile returned error: %v", err)
+	}
+
+	if claim, err := store.Claim(ctx, "a", time.Hour); err != nil || claim != WebhookClaimed {
+		t.Errorf("Claim(a) = %v, %v, expected the corrupt claim to be replaced", claim, err)
+	}
+	if claim, _ := store.Claim(ctx, "a", time.Hour); claim != WebhookInProgress {
+		t.Errorf("Claim(a) = %v, expected %v", claim, WebhookInProgress)
+	}
+}
+
+func TestFileWebhookStorePurge(t *testing.T) {
+	stores, clock := newTestWebhookStores(t)
+	store := stores["file"].(*FileWebhookStore)
+	ctx := context.Background()
+
+	store.Claim(ctx, "short", time.Minute)
+	store.Claim(ctx, "long", time.Minute)
+	store.Complete(ctx, "long", time.Hour)
+	store.Advance(ctx, "orders/1", clock.Now(), time.Minute)
+
+	clock.Advance(30 * time.Minute)
+	if err := store.Purge(ctx); err != nil {
+		t.Fatalf("Purge returned error: %v", err)
+	}
+
+	files, err := os.ReadDir(store.Dir)
+	if err != nil {
+		t.Fatalf("ReadDir returned error: %v", err)
+	}
+	if len(files) != 1 {
+		t.Errorf("Purge left %d files, expected 1", len(files))
+	}
+	if claim, _ := store.Claim(ctx, "long", time.Hour); claim != WebhookCompleted {
+		t.Errorf("Claim(long) = %v after Purge, expected %v", claim, WebhookCompleted)
+	}
+}
+
+func TestWebhookHandlerStoreDuplicates(t *testing.T) {
+	handler := NewWebhookHandler(webhookApp)
+	handler.Store = NewMemoryWebhookStore(0)
+
+	calls := 0
+	var fail error
+	handler.OnOrdersPaid(func(ctx context.Context, meta WebhookMeta, order *Order) error {
+		calls++
+		return fail
+	})
+
+	newDelivery := func(webhookId, eventId string) *http.Request {
+		req := newWebhookRequest("orders/paid", `{"id": 1}`)
+		req.Header.Set(webhookIdHeader, webhookId)
+		req.Header.Set(webhookEventIdHeader, eventId)
+		return req
+	}
+
+	fail = errors.New("database unavailable")
+	if status := serveWebhook(handler, newDelivery("w1", "e1")); status != http.StatusInternalServerError {
+		t.Errorf("failed delivery responded %d, expected %d", status, http.StatusInternalServerError)
+	}
+
+	fail = nil
+	for _, req := range []*http.Request{
+		newDelivery("w1", "e1"), // retry of the failed delivery
+		newDelivery("w1", "e1"), // duplicate
+		newDelivery("w2", "e1"), // same event to a duplicate subscription
+		newDelivery("w3", ""),   // another delivery without an event id
+		newDelivery("w3", ""),
+	} {
+		if status := serveWebhook(handler, req); status != http.StatusOK {
+			t.Errorf("delivery responded %d, expected %d", status, http.StatusOK)
+		}
+	}
+
+	if calls != 3 {
+		t.Errorf("callback called %d times, expected 3", calls)
+	}
+}
+
+func TestWebhookHandlerStoreInProgress(t *testing.T) {
+	handler := NewWebhookHandler(webhookApp)
+	handler.Store = NewMemoryWebhookStore(0)
+
+	newDelivery := func() *http.Request {
+		req := newWebhookRequest("orders/paid", `{"id": 1}`)
+		req.Header.Set(webhookEventIdHeader, "e1")
+		return req
+	}
+
+	calls := 0
+	retryStatus := 0
+	handler.OnOrdersPaid(func(ctx context.Context, meta WebhookMeta, order *Order) error {
+		calls++
+		if calls > 1 {
+			return nil
+		}
+		// Shopify retries the delivery while it is processed, which then fails
+		retryStatus = serveWebhook(handler, newDelivery())
+		return errors.New("database unavailable")
+	})
+
+	if status := serveWebhook(handler, newDelivery()); status != http.StatusInternalServerError {
+		t.Errorf("failed delivery responded %d, expected %d", status, http.StatusInternalServerError)
+	}
+	if retryStatus != http.StatusConflict {
+		t.Errorf("delivery in progress responded %d, expected %d", retryStatus, http.StatusConflict)
+	}
+
+	for i := 0; i < 2; i++ {
+		if status := serveWebhook(handler, newDelivery()); status != http.StatusOK {
+			t.Errorf("delivery responded %d, expected %d", status, http.StatusOK)
+		}
+	}
+	if calls != 2 {
+		t.Errorf("callback called %d times, expected 2", calls)
+	}
+}
+
+func TestWebhookHandlerIgnoreStale(t *testing.T) {
+	handler := NewWebhookHandler(webhookApp)
+	handler.Store = NewMemoryWebhookStore(0)
+	handler.IgnoreStale = true
+
+	var received []string
+	handler.OnOrdersUpdated(func(ctx context.Context, meta WebhookMeta, order *Order) error {
+		received = append(received, meta.EventId)
+		return nil
+	})
+	handler.OnOrdersDelete(func(ctx context.Context, meta WebhookMeta, order *Order) error {
+		received = append(received, meta.EventId)
+		return nil
+	})
+	handler.OnOrdersCreate(func(ctx context.Context, meta WebhookMeta, order *Order) error {
+		received = append(received, meta.EventId)
+		return nil
+	})
+	handler.OnOrdersPaid(func(ctx context.Context, meta WebhookMeta, order *Order) error {
+		received = append(received, meta.EventId)
+		return nil
+	})
+
+	newDelivery := func(topic, eventId, triggeredAt, body string) *http.Request {
+		req := newWebhookRequest(topic, body)
+		req.Header.Set(webhookEventIdHeader, eventId)
+		req.Header.Set(webhookTriggeredAtHeader, triggeredAt)
+		return req
+	}
+
+	deliveries := []*http.Request{
+		newDelivery("orders/updated", "later", "2024-01-01T10:05:00Z", `{"id": 1, "updated_at": "2024-01-01T10:05:00Z"}`),
+		newDelivery("orders/updated", "earlier", "2024-01-01T10:00:00Z", `{"id": 1, "updated_at": "2024-01-01T10:00:00Z"}`),
+		// other events about the order are not superseded by the later update
+		newDelivery("orders/create", "create", "2024-01-01T10:00:00Z", `{"id": 1, "updated_at": "2024-01-01T10:00:00Z"}`),
+		newDelivery("orders/paid", "paid", "2024-01-01T10:02:00Z", `{"id": 1, "updated_at": "2024-01-01T10:02:00Z"}`),
+		newDelivery("orders/paid", "paid earlier", "2024-01-01T10:01:00Z", `{"id": 1, "updated_at": "2024-01-01T10:01:00Z"}`),
+		newDelivery("orders/updated", "other order", "2024-01-01T09:00:00Z", `{"id": 2, "updated_at": "2024-01-01T09:00:00Z"}`),
+		newDelivery("orders/delete", "delete", "2024-01-01T11:00:00Z", `{"id": 1}`),
+		newDelivery("orders/updated", "after delete", "2024-01-01T12:00:00Z", `{"id": 1, "updated_at": "2024-01-01T10:30:00Z"}`),
+	}
+	for _, req := range deliveries {
+		if status := serveWebhook(handler, req); status != http.StatusOK {
+			t.Errorf("delivery responded %d, expected %d", status, http.StatusOK)
+		}
+	}
+
+	expected := []string{"later", "create", "paid", "other order", "delete"}
+	if len(received) != len(expected) {
+		t.Fatalf("callbacks received %v, expected %v", received, expected)
+	}
+	for i := range expected {
+		if received[i] != expected[i] {
+			t.Errorf("callbacks received %v, expected %v", received, expected)
+			break
+		}
+	}
+}
@@ -0,0 +1,214 @@
+package goshopify
+
+import (
+	"context"
+	"fmt"
+	"sort"
+)
+
+// WebhookSyncOptions configures WebhookService.Sync
+type WebhookSyncOptions struct {
+	// DryRun computes the plan without changing any subscription
+	DryRun bool
+
+	// DeleteStale deletes the subscriptions which are not desired
+	DeleteStale bool
+}
+
+// WebhookDiff describes a subscription which drifted from its desired state
+type WebhookDiff struct {
+	// Current is the registered subscription
+	Current Webhook
+
+	// Desired is the state the subscription is updated to, with the id of
+	// the current subscription
+	Desired Webhook
+
+	// Fields are the names of the drifted attributes, e.g. address
+	Fields []string
+}
+
+// WebhookSyncPlan lists the changes made by WebhookService.Sync, or to be
+// made in a dry run.
+type WebhookSyncPlan struct {
+	// Create are the desired subscriptions which are not registered
+	Create []Webhook
+
+	// Update are the registered subscriptions which drifted
+	Update []WebhookDiff
+
+	// Stale are the registered subscriptions which are not desired, they
+	// are deleted when DeleteStale is set
+	Stale []Webhook
+
+	// Unchanged are the registered subscriptions matching their desired state
+	Unchanged []Webhook
+}
+
+// Empty reports whether the plan has no change to make, ignoring stale
+// subscriptions unless deleteStale is set.
+func (p *WebhookSyncPlan) Empty(deleteStale bool) bool {
+	return len(p.Create) == 0 && len(p.Update) == 0 && (!deleteStale || len(p.Stale) == 0)
+}
+
+// Sync reconciles the webhook subscriptions of the shop with the desired
+// ones. A desired subscription matches a registered one with the same topic
+// and address, or else a registered one with the same topic whose address is
+// updated. Matched subscriptions are updated when their fields, metafield
+// namespaces, format or API version differ; an empty format or API version
+// is not compared. Unmatched desired subscriptions are created, and
+// unmatched registered ones are stale.
+//
+// The returned plan lists the changes even if applying one of them failed.
+// With WithWebhookTopicValidation, nothing is changed if a desired topic is
+// unknown.
+func (s *WebhookServiceOp) Sync(ctx context.Context, desired []Webhook, options *WebhookSyncOptions) (*WebhookSyncPlan, error) {
+	if options == nil {
+		options = &WebhookSyncOptions{}
+	}
+
+	for _, webhook := range desired {
+		if err := s.validateTopic(webhook.Topic); err != nil {
+			return nil, err
+		}
+	}
+
+	current, err := s.ListAll(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := planWebhookSync(current, desired)
+	if options.DryRun {
+		return plan, nil
+	}
+
+	for _, diff := range plan.Update {
+		if _, err := s.Update(ctx, diff.Desired); err != nil {
+			return plan, fmt.Errorf("updating webhook %d for %s: %w", diff.Current.Id, diff.Current.Topic, err)
+		}
+	}
+
+	for _, webhook := range plan.Create {
+		if _, err := s.Create(ctx, webhook); err != nil {
+			return plan, fmt.Errorf("creating webhook for %s: %w", webhook.Topic, err)
+		}
+	}
+
+	if options.DeleteStale {
+		for _, webhook := range plan.Stale {
+			if err := s.Delete(ctx, webhook.Id); err != nil {
+				return plan, fmt.Errorf("deleting webhook %d for %s: %w", webhook.Id, webhook.Topic, err)
+			}
+		}
+	}
+
+	return plan, nil
+}
+
+// planWebhookSync diffs the registered subscriptions against the desired ones
+func planWebhookSync(current, desired []Webhook) *WebhookSyncPlan {
+	plan := &WebhookSyncPlan{}
+	matched := make([]bool, len(current))
+	var unmatched []Webhook
+
+	match := func(want Webhook, sameAddress bool) bool {
+		for i, have := range current {
+			if matched[i] || have.Topic != want.Topic || (sameAddress && have.Address != want.Address) {
+				continue
+			}
+
+			matched[i] = true
+			fields := webhookDrift(have, want)
+			if len(fields) == 0 {
+				plan.Unchanged = append(plan.Unchanged, have)
+			} else {
+				want.Id = have.Id
+				plan.Update = append(plan.Update, WebhookDiff{Current: have, Desired: want, Fields: fields})
+			}
+			return true
+		}
+		return false
+	}
+
+	// exact matches first so moving an address doesn't steal the
+	// subscription of another desired address
+	seen := map[[2]string]bool{}
+	for _, want := range desired {
+		key := [2]string{want.Topic, want.Address}
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		if !match(want, true) {
+			unmatched = append(unmatched, want)
+		}
+	}
+
+	for _, want := range unmatched {
+		if !match(want, false) {
+			plan.Create = append(plan.Create, want)
+		}
+	}
+
+	for i, have := range current {
+		if !matched[i] {
+			plan.Stale = append(plan.Stale, have)
+		}
+	}
+
+	// list updates in the order of the registered subscriptions regardless
+	// of the pass they were matched in
+	sort.SliceStable(plan.Update, func(i, j int) bool {
+		return plan.Update[i].Current.Id < plan.Update[j].Current.Id
+	})
+
+	return plan
+}
+
+// webhookDrift returns the names of the attributes of the registered
+// subscription which differ from the desired one
+func webhookDrift(have, want Webhook) []string {
+	var fields []string
+
+	if have.Address != want.Address {
+		fields = append(fields, "address")
+	}
+	if want.Format != "" && have.Format != want.Format {
+		fields = append(fields, "format")
+	}
+	if !sameStrings(have.Fields, want.Fields) {
+		fields = append(fields, "fields")
+	}
+	if !sameStrings(have.MetafieldNamespaces, want.MetafieldNamespaces) {
+		fields = append(fields, "metafield_namespaces")
+	}
+	if !sameStrings(have.PrivateMetafieldNamespaces, want.PrivateMetafieldNamespaces) {
+		fields = append(fields, "private_metafield_namespaces")
+	}
+	if want.ApiVersion != "" && have.ApiVersion != want.ApiVersion {
+		fields = append(fields, "api_version")
+	}
+
+	return fields
+}
+
+// sameStrings reports whether a and b hold the same strings in any order
+func sameStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	a = append([]string(nil), a...)
+	b = append([]string(nil), b...)
+	sort.Strings(a)
+	sort.Strings(b)
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,160 @@
+package goshopify
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/jarcoal/httpmock"
+)
+
+const registeredWebhooks = `{"webhooks": [
+	{"id": 1, "topic": "orders/create", "address": "https://example.com/webhooks", "format": "json", "fields": ["id", "updated_at"], "api_version": "2024-01"},
+	{"id": 2, "topic": "orders/paid", "address": "https://old.example.com/webhooks", "format": "json", "api_version": "2024-01"},
+	{"id": 3, "topic": "products/update", "address": "https://example.com/webhooks", "format": "json", "metafield_namespaces": ["custom"], "api_version": "2023-10"},
+	{"id": 4, "topic": "themes/publish", "address": "https://example.com/webhooks", "format": "json", "api_version": "2024-01"}
+]}`
+
+var desiredWebhooks = []Webhook{
+	{Topic: "orders/create", Address: "https://example.com/webhooks", Fields: []string{"updated_at", "id"}},
+	{Topic: "orders/paid", Address: "https://example.com/webhooks"},
+	{Topic: "products/update", Address: "https://example.com/webhooks", MetafieldNamespaces: []string{"custom", "inventory"}, ApiVersion: "2024-01"},
+	{Topic: "app/uninstalled", Address: "https://example.com/webhooks"},
+}
+
+func registerWebhookSyncResponders() map[string]int {
+	calls := map[string]int{}
+	record := func(key string, status int, body string) httpmock.Responder {
+		return func(req *http.Request) (*http.Response, error) {
+			calls[key]++
+			return httpmock.NewStringResponse(status, body), nil
+		}
+	}
+
+	httpmock.RegisterResponder("GET", fmt.Sprintf("https://fooshop.myshopify.com/%s/webhooks.json", client.pathPrefix),
+		record("list", http.StatusOK, registeredWebhooks))
+	httpmock.RegisterResponder("PUT", fmt.Sprintf("https://fooshop.myshopify.com/%s/webhooks/2.json", client.pathPrefix),
+		record("update 2", http.StatusOK, `{"webhook": {"id": 2}}`))
+	httpmock.RegisterResponder("PUT", fmt.Sprintf("https://fooshop.myshopify.com/%s/webhooks/3.json", client.pathPrefix),
+		record("update 3", http.StatusOK, `{"webhook": {"id": 3}}`))
+	httpmock.RegisterResponder("POST", fmt.Sprintf("https://fooshop.myshopify.com/%s/webhooks.json", client.pathPrefix),
+		record("create", http.StatusCreated, `{"webhook": {"id": 5}}`))
+	httpmock.RegisterResponder("DELETE", fmt.Sprintf("https://fooshop.myshopify.com/%s/webhooks/4.json", client.pathPrefix),
+		record("delete 4", http.StatusOK, `{}`))
+
+	return calls
+}
+
+func TestWebhookSyncPlan(t *testing.T) {
+	setup()
+	defer teardown()
+
+	calls := registerWebhookSyncResponders()
+
+	plan, err := client.Webhook.Sync(context.Background(), desiredWebhooks, &WebhookSyncOptions{DryRun: true})
+	if err != nil {
+		t.Fatalf("Webhook.Sync returned error: %v", err)
+	}
+
+	if len(plan.Unchanged) != 1 || plan.Unchanged[0].Id != 1 {
+		t.Errorf("plan.Unchanged = %+v, expected webhook 1", plan.Unchanged)
+	}
+
+	if len(plan.Update) != 2 {
+		t.Fatalf("plan.Update = %+v, expected webhooks 2 and 3", plan.Update)
+	}
+	if plan.Update[0].Desired.Id != 2 || !reflect.DeepEqual(plan.Update[0].Fields, []string{"address"}) {
+		t.Errorf("plan.Update[0] = %+v, expected the address of webhook 2", plan.Update[0])
+	}
+	expectedFields := []string{"metafield_namespaces", "api_version"}
+	if plan.Update[1].Desired.Id != 3 || !reflect.DeepEqual(plan.Update[1].Fields, expectedFields) {
+		t.Errorf("plan.Update[1] = %+v, expected the %v of webhook 3", plan.Update[1], expectedFields)
+	}
+
+	if len(plan.Create) != 1 || plan.Create[0].Topic != "app/uninstalled" {
+		t.Errorf("plan.Create = %+v, expected app/uninstalled", plan.Create)
+	}
+	if len(plan.Stale) != 1 || plan.Stale[0].Id != 4 {
+		t.Errorf("plan.Stale = %+v, expected webhook 4", plan.Stale)
+	}
+	if plan.Empty(false) {
+		t.Errorf("plan.Empty() = true, expected false")
+	}
+
+	expectedCalls := map[string]int{"list": 1}
+	if !reflect.DeepEqual(calls, expectedCalls) {
+		t.Errorf("dry run made calls %v, expected %v", calls, expectedCalls)
+	}
+}
+
+func TestWebhookSyncApply(t *testing.T) {
+	setup()
+	defer teardown()
+
+	calls := registerWebhookSyncResponders()
+
+	_, err := client.Webhook.Sync(context.Background(), desiredWebhooks, nil)
+	if err != nil {
+		t.Fatalf("Webhook.Sync returned error: %v", err)
+	}
+
+	expectedCalls := map[string]int{"list": 1, "update 2": 1, "update 3": 1, "create": 1}
+	if !reflect.DeepEqual(calls, expectedCalls) {
+		t.Errorf("Webhook.Sync made calls %v, expected %v", calls, expectedCalls)
+	}
+
+	_, err = client.Webhook.Sync(context.Background(), desiredWebhooks, &WebhookSyncOptions{DeleteStale: true})
+	if err != nil {
+		t.Fatalf("Webhook.Sync returned error: %v", err)
+	}
+	if calls["delete 4"] != 1 {
+		t.Errorf("Webhook.Sync deleted webhook 4 %d times, expected once", calls["delete 4"])
+	}
+}
+
+func TestWebhookSyncError(t *testing.T) {
+	setup()
+	defer teardown()
+
+	httpmock.RegisterResponder("GET", fmt.Sprintf("https://fooshop.myshopify.com/%s/webhooks.json", client.pathPrefix),
+		httpmock.NewStringResponder(http.StatusOK, `{"webhooks": []}`))
+	httpmock.RegisterResponder("POST", fmt.Sprintf("https://fooshop.myshopify.com/%s/webhooks.json", client.pathPrefix),
+		httpmock.NewStringResponder(http.StatusUnprocessableEntity, `{"errors": {"address": ["for this topic has already been taken"]}}`))
+
+	plan, err := client.Webhook.Sync(context.Background(), desiredWebhooks[:1], nil)
+	if err == nil {
+		t.Fatalf("Webhook.Sync should have returned an error")
+	}
+	if plan == nil || len(plan.Create) != 1 {
+		t.Errorf("Webhook.Sync returned plan %+v, expected the failed creation", plan)
+	}
+}
+
+func TestPlanWebhookSyncMovesAddress(t *testing.T) {
+	current := []Webhook{
+		{Id: 1, Topic: "orders/create", Address: "https://a.example.com"},
+		{Id: 2, Topic: "orders/create", Address: "https://b.example.com"},
+	}
+	desired := []Webhook{
+		{Topic: "orders/create", Address: "https://c.example.com"},
+		{Topic: "orders/create", Address: "https://b.example.com"},
+		{Topic: "orders/create", Address: "https://b.example.com"},
+	}
+
+	plan := planWebhookSync(current, desired)
+
+	if len(plan.Unchanged) != 1 || plan.Unchanged[0].Id != 2 {
+		t.Errorf("plan.Unchanged = %+v, expected webhook 2", plan.Unchanged)
+	}
+	if len(plan.Update) != 1 || plan.Update[0].Desired.Id != 1 || plan.Update[0].Desired.Address != "https://c.example.com" {
+		t.Errorf("plan.Update = %+v, expected webhook 1 moved to c.example.com", plan.Update)
+	}
+	if len(plan.Create) != 0 || len(plan.Stale) != 0 {
+		t.Errorf("plan creates %+v and has stale %+v, expected none", plan.Create, plan.Stale)
+	}
+	if !planWebhookSync(current, []Webhook{current[0], current[1]}).Empty(true) {
+		t.Errorf("plan of the registered webhooks should be empty")
+	}
+}
@@ -0,0 +1,470 @@
+package goshopify
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+)
+
+// WebhookTopic is the topic of a webhook subscription, e.g. orders/create.
+//
+// https://shopify.dev/docs/api/admin-rest/2024-01/resources/webhook#event-topics
+type WebhookTopic string
+
+const (
+	WebhookTopicAppUninstalled                          WebhookTopic = "app/uninstalled"
+	WebhookTopicAppPurchasesOneTimeUpdate               WebhookTopic = "app_purchases_one_time/update"
+	WebhookTopicAppSubscriptionsApproachingCappedAmount WebhookTopic = "app_subscriptions/approaching_capped_amount"
+	WebhookTopicAppSubscriptionsUpdate                  WebhookTopic = "app_subscriptions/update"
+
+	WebhookTopicBulkOperationsFinish WebhookTopic = "bulk_operations/finish"
+
+	WebhookTopicCartsCreate WebhookTopic = "carts/create"
+	WebhookTopicCartsUpdate WebhookTopic = "carts/update"
+
+	WebhookTopicChannelsDelete WebhookTopic = "channels/delete"
+
+	WebhookTopicCheckoutsCreate WebhookTopic = "checkouts/create"
+	WebhookTopicCheckoutsDelete WebhookTopic = "checkouts/delete"
+	WebhookTopicCheckoutsUpdate WebhookTopic = "checkouts/update"
+
+	WebhookTopicCollectionListingsAdd    WebhookTopic = "collection_listings/add"
+	WebhookTopicCollectionListingsRemove WebhookTopic = "collection_listings/remove"
+	WebhookTopicCollectionListingsUpdate WebhookTopic = "collection_listings/update"
+
+	WebhookTopicCollectionPublicationsCreate WebhookTopic = "collection_publications/create"
+	WebhookTopicCollectionPublicationsDelete WebhookTopic = "collection_publications/delete"
+	WebhookTopicCollectionPublicationsUpdate WebhookTopic = "collection_publications/update"
+
+	WebhookTopicCollectionsCreate WebhookTopic = "collections/create"
+	WebhookTopicCollectionsDelete WebhookTopic = "collections/delete"
+	WebhookTopicCollectionsUpdate WebhookTopic = "collections/update"
+
+	WebhookTopicCompaniesCreate WebhookTopic = "companies/create"
+	WebhookTopicCompaniesDelete WebhookTopic = "companies/delete"
+	WebhookTopicCompaniesUpdate WebhookTopic = "companies/update"
+
+	WebhookTopicCompanyContactRolesAssign WebhookTopic = "company_contact_roles/assign"
+	WebhookTopicCompanyContactRolesRevoke WebhookTopic = "company_contact_roles/revoke"
+
+	WebhookTopicCompanyContactsCreate WebhookTopic = "company_contacts/create"
+	WebhookTopicCompanyContactsDelete WebhookTopic = "company_contacts/delete"
+	WebhookTopicCompanyContactsUpdate WebhookTopic = "company_contacts/update"
+
+	WebhookTopicCompanyLocationsCreate WebhookTopic = "company_locations/create"
+	WebhookTopicCompanyLocationsDelete WebhookTopic = "company_locations/delete"
+	WebhookTopicCompanyLocationsUpdate WebhookTopic = "company_locations/update"
+
+	WebhookTopicCustomerAccountSettingsUpdate WebhookTopic = "customer_account_settings/update"
+
+	WebhookTopicCustomerTagsAdded   WebhookTopic = "customer.tags_added"
+	WebhookTopicCustomerTagsRemoved WebhookTopic = "customer.tags_removed"
+
+	WebhookTopicCustomerGroupsCreate WebhookTopic = "customer_groups/create"
+	WebhookTopicCustomerGroupsDelete WebhookTopic = "customer_groups/delete"
+	WebhookTopicCustomerGroupsUpdate WebhookTopic = "customer_groups/update"
+
+	WebhookTopicCustomerPaymentMethodsCreate WebhookTopic = "customer_payment_methods/create"
+	WebhookTopicCustomerPaymentMethodsRevoke WebhookTopic = "customer_payment_methods/revoke"
+	WebhookTopicCustomerPaymentMethodsUpdate WebhookTopic = "customer_payment_methods/update"
+
+	WebhookTopicCustomersCreate  WebhookTopic = "customers/create"
+	WebhookTopicCustomersDelete  WebhookTopic = "customers/delete"
+	WebhookTopicCustomersDisable WebhookTopic = "customers/disable"
+	WebhookTopicCustomersEnable  WebhookTopic = "customers/enable"
+	WebhookTopicCustomersMerge   WebhookTopic = "customers/merge"
+	WebhookTopicCustomersUpdate  WebhookTopic = "customers/update"
+
+	WebhookTopicCustomersEmailMarketingConsentUpdate WebhookTopic = "customers_email_marketing_consent/update"
+	WebhookTopicCustomersMarketingConsentUpdate      WebhookTopic = "customers_marketing_consent/update"
+
+	WebhookTopicDiscountsCreate            WebhookTopic = "discounts/create"
+	WebhookTopicDiscountsDelete            WebhookTopic = "discounts/delete"
+	WebhookTopicDiscountsRedeemcodeAdded   WebhookTopic = "discounts/redeemcode_added"
+	WebhookTopicDiscountsRedeemcodeRemoved WebhookTopic = "discounts/redeemcode_removed"
+	WebhookTopicDiscountsUpdate            WebhookTopic = "discounts/update"
+
+	WebhookTopicDisputesCreate WebhookTopic = "disputes/create"
+	WebhookTopicDisputesUpdate WebhookTopic = "disputes/update"
+
+	WebhookTopicDomainsCreate  WebhookTopic = "domains/create"
+	WebhookTopicDomainsDestroy WebhookTopic = "domains/destroy"
+	WebhookTopicDomainsUpdate  WebhookTopic = "domains/update"
+
+	WebhookTopicDraftOrdersCreate WebhookTopic = "draft_orders/create"
+	WebhookTopicDraftOrdersDelete WebhookTopic = "draft_orders/delete"
+	WebhookTopicDraftOrdersUpdate WebhookTopic = "draft_orders/update"
+
+	WebhookTopicFulfillmentEventsCreate WebhookTopic = "fulfillment_events/create"
+	WebhookTopicFulfillmentEventsDelete WebhookTopic = "fulfillment_events/delete"
+
+	WebhookTopicFulfillmentHoldsAdded    WebhookTopic = "fulfillment_holds/added"
+	WebhookTopicFulfillmentHoldsReleased WebhookTopic = "fulfillment_holds/released"
+
+	WebhookTopicFulfillmentOrdersCancellationRequestAccepted        WebhookTopic = "fulfillment_orders/cancellation_request_accepted"
+	WebhookTopicFulfillmentOrdersCancellationRequestRejected        WebhookTopic = "fulfillment_orders/cancellation_request_rejected"
+	WebhookTopicFulfillmentOrdersCancellationRequestSubmitted       WebhookTopic = "fulfillment_orders/cancellation_request_submitted"
+	WebhookTopicFulfillmentOrdersCancelled                          WebhookTopic = "fulfillment_orders/cancelled"
+	WebhookTopicFulfillmentOrdersFulfillmentRequestAccepted         WebhookTopic = "fulfillment_orders/fulfillment_request_accepted"
+	WebhookTopicFulfillmentOrdersFulfillmentRequestRejected         WebhookTopic = "fulfillment_orders/fulfillment_request_rejected"
+	WebhookTopicFulfillmentOrdersFulfillmentRequestSubmitted        WebhookTopic = "fulfillment_orders/fulfillment_request_submitted"
+	WebhookTopicFulfillmentOrdersFulfillmentServiceFailedToComplete WebhookTopic = "fulfillment_orders/fulfillment_service_failed_to_complete"
+	WebhookTopicFulfillmentOrdersHoldReleased                       WebhookTopic = "fulfillment_orders/hold_released"
+	WebhookTopicFulfillmentOrdersLineItemsPreparedForLocalDelivery  WebhookTopic = "fulfillment_orders/line_items_prepared_for_local_delivery"
+	WebhookTopicFulfillmentOrdersLineItemsPreparedForPickup         WebhookTopic = "fulfillment_orders/line_items_prepared_for_pickup"
+	WebhookTopicFulfillmentOrdersMerged                             WebhookTopic = "fulfillment_orders/merged"
+	WebhookTopicFulfillmentOrdersMoved                              WebhookTopic = "fulfillment_orders/moved"
+	WebhookTopicFulfillmentOrdersOrderRoutingComplete               WebhookTopic = "fulfillment_orders/order_routing_complete"
+	WebhookTopicFulfillmentOrdersPlacedOnHold                       WebhookTopic = "fulfillment_orders/placed_on_hold"
+	WebhookTopicFulfillmentOrdersRescheduled                        WebhookTopic = "fulfillment_orders/rescheduled"
+	WebhookTopicFulfillmentOrdersScheduledFulfillmentOrderReady     WebhookTopic = "fulfillment_orders/scheduled_fulfillment_order_ready"
+	WebhookTopicFulfillmentOrdersSplit                              WebhookTopic = "fulfillment_orders/split"
+
+	WebhookTopicFulfillmentsCreate WebhookTopic = "fulfillments/create"
+	WebhookTopicFulfillmentsUpdate WebhookTopic = "fulfillments/update"
+
+	WebhookTopicInventoryItemsCreate WebhookTopic = "inventory_items/create"
+	WebhookTopicInventoryItemsDelete WebhookTopic = "inventory_items/delete"
+	WebhookTopicInventoryItemsUpdate WebhookTopic = "inventory_items/update"
+
+	WebhookTopicInventoryLevelsConnect    WebhookTopic = "inventory_levels/connect"
+	WebhookTopicInventoryLevelsDisconnect WebhookTopic = "inventory_levels/disconnect"
+	WebhookTopicInventoryLevelsUpdate     WebhookTopic = "inventory_levels/update"
+
+	WebhookTopicLocalesCreate WebhookTopic = "locales/create"
+	WebhookTopicLocalesUpdate WebhookTopic = "locales/update"
+
+	WebhookTopicLocationsActivate   WebhookTopic = "locations/activate"
+	WebhookTopicLocationsCreate     WebhookTopic = "locations/create"
+	WebhookTopicLocationsDeactivate WebhookTopic = "locations/deactivate"
+	WebhookTopicLocationsDelete     WebhookTopic = "locations/delete"
+	WebhookTopicLocationsUpdate     WebhookTopic = "locations/update"
+
+	WebhookTopicMarketsCreate WebhookTopic = "markets/create"
+	WebhookTopicMarketsDelete WebhookTopic = "markets/delete"
+	WebhookTopicMarketsUpdate WebhookTopic = "markets/update"
+
+	WebhookTopicMetaobjectsCreate WebhookTopic = "metaobjects/create"
+	WebhookTopicMetaobjectsDelete WebhookTopic = "metaobjects/delete"
+	WebhookTopicMetaobjectsUpdate WebhookTopic = "metaobjects/update"
+
+	WebhookTopicOrderTransactionsCreate WebhookTopic = "order_transactions/create"
+
+	WebhookTopicOrdersCancelled                        WebhookTopic = "orders/cancelled"
+	WebhookTopicOrdersCreate                           WebhookTopic = "orders/create"
+	WebhookTopicOrdersDelete                           WebhookTopic = "orders/delete"
+	WebhookTopicOrdersEdited                           WebhookTopic = "orders/edited"
+	WebhookTopicOrdersFulfilled                        WebhookTopic = "orders/fulfilled"
+	WebhookTopicOrdersPaid                             WebhookTopic = "orders/paid"
+	WebhookTopicOrdersPartiallyFulfilled               WebhookTopic = "orders/partially_fulfilled"
+	WebhookTopicOrdersRiskAssessmentChanged            WebhookTopic = "orders/risk_assessment_changed"
+	WebhookTopicOrdersShopifyProtectEligibilityChanged WebhookTopic = "orders/shopify_protect_eligibility_changed"
+	WebhookTopicOrdersUpdated                          WebhookTopic = "orders/updated"
+
+	WebhookTopicPaymentSchedulesDue WebhookTopic = "payment_schedules/due"
+
+	WebhookTopicPaymentTermsCreate WebhookTopic = "payment_terms/create"
+	WebhookTopicPaymentTermsDelete WebhookTopic = "payment_terms/delete"
+	WebhookTopicPaymentTermsUpdate WebhookTopic = "payment_terms/update"
+
+	WebhookTopicProductFeedsCreate          WebhookTopic = "product_feeds/create"
+	WebhookTopicProductFeedsFullSync        WebhookTopic = "product_feeds/full_sync"
+	WebhookTopicProductFeedsIncrementalSync WebhookTopic = "product_feeds/incremental_sync"
+	WebhookTopicProductFeedsUpdate          WebhookTopic = "product_feeds/update"
+
+	WebhookTopicProductListingsAdd    WebhookTopic = "product_listings/add"
+	WebhookTopicProductListingsRemove WebhookTopic = "product_listings/remove"
+	WebhookTopicProductListingsUpdate WebhookTopic = "product_listings/update"
+
+	WebhookTopicProductPublicationsCreate WebhookTopic = "product_publications/create"
+	WebhookTopicProductPublicationsDelete WebhookTopic = "product_publications/delete"
+	WebhookTopicProductPublicationsUpdate WebhookTopic = "product_publications/update"
+
+	WebhookTopicProductsCreate WebhookTopic = "products/create"
+	WebhookTopicProductsDelete WebhookTopic = "products/delete"
+	WebhookTopicProductsUpdate WebhookTopic = "products/update"
+
+	WebhookTopicProfilesCreate WebhookTopic = "profiles/create"
+	WebhookTopicProfilesDelete WebhookTopic = "profiles/delete"
+	WebhookTopicProfilesUpdate WebhookTopic = "profiles/update"
+
+	WebhookTopicRefundsCreate WebhookTopic = "refunds/create"
+
+	WebhookTopicReturnsApprove WebhookTopic = "returns/approve"
+	WebhookTopicReturnsCancel  WebhookTopic = "returns/cancel"
+	WebhookTopicReturnsClose   WebhookTopic = "returns/close"
+	WebhookTopicReturnsDecline WebhookTopic = "returns/decline"
+	WebhookTopicReturnsReopen  WebhookTopic = "returns/reopen"
+	WebhookTopicReturnsRequest WebhookTopic = "returns/request"
+
+	WebhookTopicReverseDeliveriesAttachDeliverable WebhookTopic = "reverse_deliveries/attach_deliverable"
+	WebhookTopicReverseFulfillmentOrdersDispose    WebhookTopic = "reverse_fulfillment_orders/dispose"
+
+	WebhookTopicScheduledProductListingsAdd    WebhookTopic = "scheduled_product_listings/add"
+	WebhookTopicScheduledProductListingsRemove WebhookTopic = "scheduled_product_listings/remove"
+	WebhookTopicScheduledProductListingsUpdate WebhookTopic = "scheduled_product_listings/update"
+
+	WebhookTopicSegmentsCreate WebhookTopic = "segments/create"
+	WebhookTopicSegmentsDelete WebhookTopic = "segments/delete"
+	WebhookTopicSegmentsUpdate WebhookTopic = "segments/update"
+
+	WebhookTopicSellingPlanGroupsCreate WebhookTopic = "selling_plan_groups/create"
+	WebhookTopicSellingPlanGroupsDelete WebhookTopic = "selling_plan_groups/delete"
+	WebhookTopicSellingPlanGroupsUpdate WebhookTopic = "selling_plan_groups/update"
+
+	WebhookTopicShopUpdate WebhookTopic = "shop/update"
+
+	WebhookTopicSubscriptionBillingAttemptsChallenged WebhookTopic = "subscription_billing_attempts/challenged"
+	WebhookTopicSubscriptionBillingAttemptsFailure    WebhookTopic = "subscription_billing_attempts/failure"
+	WebhookTopicSubscriptionBillingAttemptsSuccess    WebhookTopic = "subscription_billing_attempts/success"
+
+	WebhookTopicSubscriptionBillingCycleEditsCreate WebhookTopic = "subscription_billing_cycle_edits/create"
+	WebhookTopicSubscriptionBillingCycleEditsDelete WebhookTopic = "subscription_billing_cycle_edits/delete"
+	WebhookTopicSubscriptionBillingCycleEditsUpdate WebhookTopic = "subscription_billing_cycle_edits/update"
+
+	WebhookTopicSubscriptionBillingCyclesSkip   WebhookTopic = "subscription_billing_cycles/skip"
+	WebhookTopicSubscriptionBillingCyclesUnskip WebhookTopic = "subscription_billing_cycles/unskip"
+
+	WebhookTopicSubscriptionContractsActivate WebhookTopic = "subscription_contracts/activate"
+	WebhookTopicSubscriptionContractsCancel   WebhookTopic = "subscription_contracts/cancel"
+	WebhookTopicSubscriptionContractsCreate   WebhookTopic = "subscription_contracts/create"
+	WebhookTopicSubscriptionContractsExpire   WebhookTopic = "subscription_contracts/expire"
+	WebhookTopicSubscriptionContractsFail     WebhookTopic = "subscription_contracts/fail"
+	WebhookTopicSubscriptionContractsPause    WebhookTopic = "subscription_contracts/pause"
+	WebhookTopicSubscriptionContractsUpdate   WebhookTopic = "subscription_contracts/update"
+
+	WebhookTopicTaxServicesCreate WebhookTopic = "tax_services/create"
+	WebhookTopicTaxServicesUpdate WebhookTopic = "tax_services/update"
+
+	WebhookTopicTenderTransactionsCreate WebhookTopic = "tender_transactions/create"
+
+	WebhookTopicThemesCreate  WebhookTopic = "themes/create"
+	WebhookTopicThemesDelete  WebhookTopic = "themes/delete"
+	WebhookTopicThemesPublish WebhookTopic = "themes/publish"
+	WebhookTopicThemesUpdate  WebhookTopic = "themes/update"
+
+	WebhookTopicVariantsInStock    WebhookTopic = "variants/in_stock"
+	WebhookTopicVariantsOutOfStock WebhookTopic = "variants/out_of_stock"
+
+	// Mandatory compliance topics, configured in the Partner Dashboard
+	// rather than through the API.
+	WebhookTopicCustomersDataRequest WebhookTopic = "customers/data_request"
+	WebhookTopicCustomersRedact      WebhookTopic = "customers/redact"
+	WebhookTopicShopRedact           WebhookTopic = "shop/redact"
+)
+
+// ErrUnknownWebhookTopic is wrapped by the errors returned for topics which
+// are not registered, see WebhookTopic.Validate
+var ErrUnknownWebhookTopic = errors.New("unknown webhook topic")
+
+var (
+	webhookTopicsMu sync.RWMutex
+
+	// webhookTopics maps the known topics to the type their payload is
+	// decoded into, nil for payloads without a matching type which are
+	// decoded into a map
+	webhookTopics = map[WebhookTopic]reflect.Type{}
+)
+
+func init() {
+	payloads := []struct {
+		payload interface{}
+		topics  []WebhookTopic
+	}{
+		{Order{}, []WebhookTopic{
+			WebhookTopicOrdersCancelled, WebhookTopicOrdersCreate, WebhookTopicOrdersDelete,
+			WebhookTopicOrdersFulfilled, WebhookTopicOrdersPaid, WebhookTopicOrdersPartiallyFulfilled,
+			WebhookTopicOrdersUpdated,
+		}},
+		{Product{}, []WebhookTopic{
+			WebhookTopicProductsCreate, WebhookTopicProductsDelete, WebhookTopicProductsUpdate,
+		}},
+		{Customer{}, []WebhookTopic{
+			WebhookTopicCustomersCreate, WebhookTopicCustomersDelete, WebhookTopicCustomersDisable,
+			WebhookTopicCustomersEnable, WebhookTopicCustomersUpdate,
+		}},
+		{BulkOperationWebhook{}, []WebhookTopic{WebhookTopicBulkOperationsFinish}},
+		{Collection{}, []WebhookTopic{
+			WebhookTopicCollectionsCreate, WebhookTopicCollectionsDelete, WebhookTopicCollectionsUpdate,
+		}},
+		{AbandonedCheckout{}, []WebhookTopic{
+			WebhookTopicCheckoutsCreate, WebhookTopicCheckoutsDelete, WebhookTopicCheckoutsUpdate,
+		}},
+		{DraftOrder{}, []WebhookTopic{
+			WebhookTopicDraftOrdersCreate, WebhookTopicDraftOrdersDelete, WebhookTopicDraftOrdersUpdate,
+		}},
+		{Fulfillment{}, []WebhookTopic{
+			WebhookTopicFulfillmentsCreate, WebhookTopicFulfillmentsUpdate,
+		}},
+		{FulfillmentEvent{}, []WebhookTopic{
+			WebhookTopicFulfillmentEventsCreate, WebhookTopicFulfillmentEventsDelete,
+		}},
+		{InventoryItem{}, []WebhookTopic{
+			WebhookTopicInventoryItemsCreate, WebhookTopicInventoryItemsDelete, WebhookTopicInventoryItemsUpdate,
+		}},
+		{InventoryLevel{}, []WebhookTopic{
+			WebhookTopicInventoryLevelsConnect, WebhookTopicInventoryLevelsDisconnect, WebhookTopicInventoryLevelsUpdate,
+		}},
+		{Location{}, []WebhookTopic{
+			WebhookTopicLocationsActivate, WebhookTopicLocationsCreate, WebhookTopicLocationsDeactivate,
+			WebhookTopicLocationsDelete, WebhookTopicLocationsUpdate,
+		}},
+		{ProductListingResource{}, []WebhookTopic{
+			WebhookTopicProductListingsAdd, WebhookTopicProductListingsRemove, WebhookTopicProductListingsUpdate,
+		}},
+		{Refund{}, []WebhookTopic{WebhookTopicRefundsCreate}},
+		{Transaction{}, []WebhookTopic{WebhookTopicOrderTransactionsCreate}},
+		{Shop{}, []WebhookTopic{WebhookTopicAppUninstalled, WebhookTopicShopUpdate}},
+		{Theme{}, []WebhookTopic{
+			WebhookTopicThemesCreate, WebhookTopicThemesDelete, WebhookTopicThemesPublish, WebhookTopicThemesUpdate,
+		}},
+		{Variant{}, []WebhookTopic{WebhookTopicVariantsInStock, WebhookTopicVariantsOutOfStock}},
+		{nil, []WebhookTopic{
+			WebhookTopicAppPurchasesOneTimeUpdate, WebhookTopicAppSubscriptionsApproachingCappedAmount,
+			WebhookTopicAppSubscriptionsUpdate,
+			WebhookTopicCartsCreate, WebhookTopicCartsUpdate, WebhookTopicChannelsDelete,
+			WebhookTopicCollectionListingsAdd, WebhookTopicCollectionListingsRemove, WebhookTopicCollectionListingsUpdate,
+			WebhookTopicCollectionPublicationsCreate, WebhookTopicCollectionPublicationsDelete, WebhookTopicCollectionPublicationsUpdate,
+			WebhookTopicCompaniesCreate, WebhookTopicCompaniesDelete, WebhookTopicCompaniesUpdate,
+			WebhookTopicCompanyContactRolesAssign, WebhookTopicCompanyContactRolesRevoke,
+			WebhookTopicCompanyContactsCreate, WebhookTopicCompanyContactsDelete, WebhookTopicCompanyContactsUpdate,
+			WebhookTopicCompanyLocationsCreate, WebhookTopicCompanyLocationsDelete, WebhookTopicCompanyLocationsUpdate,
+			WebhookTopicCustomerAccountSettingsUpdate, WebhookTopicCustomerTagsAdded, WebhookTopicCustomerTagsRemoved,
+			WebhookTopicCustomerGroupsCreate, WebhookTopicCustomerGroupsDelete, WebhookTopicCustomerGroupsUpdate,
+			WebhookTopicCustomerPaymentMethodsCreate, WebhookTopicCustomerPaymentMethodsRevoke, WebhookTopicCustomerPaymentMethodsUpdate,
+			WebhookTopicCustomersMerge, WebhookTopicCustomersEmailMarketingConsentUpdate, WebhookTopicCustomersMarketingConsentUpdate,
+			WebhookTopicDiscountsCreate, WebhookTopicDiscountsDelete, WebhookTopicDiscountsRedeemcodeAdded,
+			WebhookTopicDiscountsRedeemcodeRemoved, WebhookTopicDiscountsUpdate,
+			WebhookTopicDisputesCreate, WebhookTopicDisputesUpdate,
+			WebhookTopicDomainsCreate, WebhookTopicDomainsDestroy, WebhookTopicDomainsUpdate,
+			WebhookTopicFulfillmentHoldsAdded, WebhookTopicFulfillmentHoldsReleased,
+			WebhookTopicFulfillmentOrdersCancellationRequestAccepted, WebhookTopicFulfillmentOrdersCancellationRequestRejected,
+			WebhookTopicFulfillmentOrdersCancellationRequestSubmitted, WebhookTopicFulfillmentOrdersCancelled,
+			WebhookTopicFulfillmentOrdersFulfillmentRequestAccepted, WebhookTopicFulfillmentOrdersFulfillmentRequestRejected,
+			WebhookTopicFulfillmentOrdersFulfillmentRequestSubmitted, WebhookTopicFulfillmentOrdersFulfillmentServiceFailedToComplete,
+			WebhookTopicFulfillmentOrdersHoldReleased, WebhookTopicFulfillmentOrdersLineItemsPreparedForLocalDelivery,
+			WebhookTopicFulfillmentOrdersLineItemsPreparedForPickup, WebhookTopicFulfillmentOrdersMerged,
+			WebhookTopicFulfillmentOrdersMoved, WebhookTopicFulfillmentOrdersOrderRoutingComplete,
+			WebhookTopicFulfillmentOrdersPlacedOnHold, WebhookTopicFulfillmentOrdersRescheduled,
+			WebhookTopicFulfillmentOrdersScheduledFulfillmentOrderReady, WebhookTopicFulfillmentOrdersSplit,
+			WebhookTopicLocalesCreate, WebhookTopicLocalesUpdate,
+			WebhookTopicMarketsCreate, WebhookTopicMarketsDelete, WebhookTopicMarketsUpdate,
+			WebhookTopicMetaobjectsCreate, WebhookTopicMetaobjectsDelete, WebhookTopicMetaobjectsUpdate,
+			WebhookTopicOrdersEdited, WebhookTopicOrdersRiskAssessmentChanged,
+			WebhookTopicOrdersShopifyProtectEligibilityChanged, WebhookTopicPaymentSchedulesDue,
+			WebhookTopicPaymentTermsCreate, WebhookTopicPaymentTermsDelete, WebhookTopicPaymentTermsUpdate,
+			WebhookTopicProductFeedsCreate, WebhookTopicProductFeedsFullSync,
+			WebhookTopicProductFeedsIncrementalSync, WebhookTopicProductFeedsUpdate,
+			WebhookTopicProductPublicationsCreate, WebhookTopicProductPublicationsDelete, WebhookTopicProductPublicationsUpdate,
+			WebhookTopicProfilesCreate, WebhookTopicProfilesDelete, WebhookTopicProfilesUpdate,
+			WebhookTopicReturnsApprove, WebhookTopicReturnsCancel, WebhookTopicReturnsClose,
+			WebhookTopicReturnsDecline, WebhookTopicReturnsReopen, WebhookTopicReturnsRequest,
+			WebhookTopicReverseDeliveriesAttachDeliverable, WebhookTopicReverseFulfillmentOrdersDispose,
+			WebhookTopicScheduledProductListingsAdd, WebhookTopicScheduledProductListingsRemove, WebhookTopicScheduledProductListingsUpdate,
+			WebhookTopicSegmentsCreate, WebhookTopicSegmentsDelete, WebhookTopicSegmentsUpdate,
+			WebhookTopicSellingPlanGroupsCreate, WebhookTopicSellingPlanGroupsDelete, WebhookTopicSellingPlanGroupsUpdate,
+			WebhookTopicSubscriptionBillingAttemptsChallenged, WebhookTopicSubscriptionBillingAttemptsFailure,
+			WebhookTopicSubscriptionBillingAttemptsSuccess,
+			WebhookTopicSubscriptionBillingCycleEditsCreate, WebhookTopicSubscriptionBillingCycleEditsDelete,
+			WebhookTopicSubscriptionBillingCycleEditsUpdate,
+			WebhookTopicSubscriptionBillingCyclesSkip, WebhookTopicSubscriptionBillingCyclesUnskip,
+			WebhookTopicSubscriptionContractsActivate, WebhookTopicSubscriptionContractsCancel,
+			WebhookTopicSubscriptionContractsCreate, WebhookTopicSubscriptionContractsExpire,
+			WebhookTopicSubscriptionContractsFail, WebhookTopicSubscriptionContractsPause,
+			WebhookTopicSubscriptionContractsUpdate,
+			WebhookTopicTaxServicesCreate, WebhookTopicTaxServicesUpdate, WebhookTopicTenderTransactionsCreate,
+			WebhookTopicCustomersDataRequest, WebhookTopicCustomersRedact, WebhookTopicShopRedact,
+		}},
+	}
+
+	for _, p := range payloads {
+		for _, topic := range p.topics {
+			RegisterWebhookTopic(topic, p.payload)
+		}
+	}
+}
+
+// RegisterWebhookTopic adds topic to the known topics, or replaces the
+// payload type of a known one. Webhooks of topic are decoded into the type of
+// payload, e.g. Order{}, or into a map[string]interface{} if payload is nil.
+// It allows decoding topics Shopify added after this release.
+func RegisterWebhookTopic(topic WebhookTopic, payload interface{}) {
+	var t reflect.Type
+	if payload != nil {
+		t = reflect.TypeOf(payload)
+		for t.Kind() == reflect.Pointer {
+			t = t.Elem()
+		}
+	}
+
+	webhookTopicsMu.Lock()
+	defer webhookTopicsMu.Unlock()
+	webhookTopics[topic] = t
+}
+
+// WebhookTopics returns the known topics in alphabetical order.
+func WebhookTopics() []WebhookTopic {
+	webhookTopicsMu.RLock()
+	defer webhookTopicsMu.RUnlock()
+
+	topics := make([]WebhookTopic, 0, len(webhookTopics))
+	for topic := range webhookTopics {
+		topics = append(topics, topic)
+	}
+	sort.Slice(topics, func(i, j int) bool { return topics[i] < topics[j] })
+	return topics
+}
+
+// Valid reports whether the topic is known, see RegisterWebhookTopic.
+func (t WebhookTopic) Valid() bool {
+	webhookTopicsMu.RLock()
+	defer webhookTopicsMu.RUnlock()
+
+	_, ok := webhookTopics[t]
+	return ok
+}
+
+// PayloadType returns the type the payload of the topic is decoded into, nil
+// if the topic is unknown.
+func (t WebhookTopic) PayloadType() reflect.Type {
+	webhookTopicsMu.RLock()
+	payload, ok := webhookTopics[t]
+	webhookTopicsMu.RUnlock()
+
+	switch {
+	case !ok:
+		return nil
+	case payload == nil:
+		return reflect.TypeOf(map[string]interface{}{})
+	default:
+		return payload
+	}
+}
+
+// Validate returns an error wrapping ErrUnknownWebhookTopic if the topic is
+// unknown, e.g. a typo like order/create, see WithWebhookTopicValidation.
+func (t WebhookTopic) Validate() error {
+	if !t.Valid() {
+		return fmt.Errorf("%w %q, see RegisterWebhookTopic", ErrUnknownWebhookTopic, t)
+	}
+	return nil
+}
+
+// DecodeWebhook decodes the payload of a webhook of topic into a pointer to
+// its payload type, e.g. a *Order for orders/create, or a
+// *map[string]interface{} for topics without a matching type.
+func DecodeWebhook(topic WebhookTopic, body []byte) (interface{}, error) {
+	payloadType := topic.PayloadType()
+	if payloadType == nil {
+		return nil, topic.Validate()
+	}
+
+	payload := reflect.New(payloadType).Interface()
+	if err := json.Unmarshal(body, payload); err != nil {
+		return nil, err
+	}
+	return payload, nil
+}
@@ -0,0 +1,220 @@
+package goshopify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/jarcoal/httpmock"
+)
+
+func TestWebhookTopicValid(t *testing.T) {
+	cases := []struct {
+		topic    WebhookTopic
+		expected bool
+	}{
+		{WebhookTopicOrdersCreate, true},
+		{"customer.tags_added", true},
+		{"shop/redact", true},
+		{"discounts/create", true},
+		{"orders/risk_assessment_changed", true},
+		{"metaobjects/create", true},
+		{"fulfillment_holds/added", true},
+		{"customer_account_settings/update", true},
+		{"orders/shopify_protect_eligibility_changed", true},
+		{"order/create", false},
+		{"orders/created", false},
+		{"", false},
+	}
+
+	for _, c := range cases {
+		if valid := c.topic.Valid(); valid != c.expected {
+			t.Errorf("WebhookTopic(%q).Valid() = %v, expected %v", c.topic, valid, c.expected)
+		}
+	}
+}
+
+func TestWebhookTopicPayloadType(t *testing.T) {
+	cases := []struct {
+		topic    WebhookTopic
+		expected reflect.Type
+	}{
+		{WebhookTopicOrdersPaid, reflect.TypeOf(Order{})},
+		{WebhookTopicVariantsOutOfStock, reflect.TypeOf(Variant{})},
+		{WebhookTopicProductListingsAdd, reflect.TypeOf(ProductListingResource{})},
+		{WebhookTopicAppUninstalled, reflect.TypeOf(Shop{})},
+		{WebhookTopicBulkOperationsFinish, reflect.TypeOf(BulkOperationWebhook{})},
+		{WebhookTopicCartsCreate, reflect.TypeOf(map[string]interface{}{})},
+		{"order/create", nil},
+	}
+
+	for _, c := range cases {
+		if payloadType := c.topic.PayloadType(); payloadType != c.expected {
+			t.Errorf("WebhookTopic(%q).PayloadType() = %v, expected %v", c.topic, payloadType, c.expected)
+		}
+	}
+}
+
+func TestWebhookTopics(t *testing.T) {
+	topics := WebhookTopics()
+	if len(topics) < 150 {
+		t.Errorf("WebhookTopics() returned %d topics, expected at least 150", len(topics))
+	}
+
+	for i, topic := range topics {
+		if i > 0 && topics[i-1] >= topic {
+			t.Errorf("WebhookTopics() is not sorted at %s", topic)
+		}
+		if !strings.ContainsAny(string(topic), "/.") {
+			t.Errorf("WebhookTopics() returned malformed topic %q", topic)
+		}
+	}
+}
+
+func TestRegisterWebhookTopic(t *testing.T) {
+	topic := WebhookTopic("widgets/create")
+	defer func() {
+		webhookTopicsMu.Lock()
+		delete(webhookTopics, topic)
+		webhookTopicsMu.Unlock()
+	}()
+
+	if topic.Valid() {
+		t.Fatalf("%s should not be valid before it is registered", topic)
+	}
+
+	RegisterWebhookTopic(topic, &Product{})
+	if !topic.Valid() {
+		t.Errorf("%s should be valid once registered", topic)
+	}
+	if payloadType := topic.PayloadType(); payloadType != reflect.TypeOf(Product{}) {
+		t.Errorf("PayloadType() = %v, expected Product", payloadType)
+	}
+}
+
+func TestDecodeWebhook(t *testing.T) {
+	payload, err := DecodeWebhook(WebhookTopicOrdersCreate, []byte(`{"id": 1, "email": "bob@example.com"}`))
+	if err != nil {
+		t.Fatalf("DecodeWebhook returned error: %v", err)
+	}
+	order, ok := payload.(*Order)
+	if !ok || order.Id != 1 || order.Email != "bob@example.com" {
+		t.Errorf("DecodeWebhook returned %#v, expected order 1", payload)
+	}
+
+	payload, err = DecodeWebhook(WebhookTopicCartsCreate, []byte(`{"id": "eeafa272cebfd4b22385bc4b645e762c"}`))
+	if err != nil {
+		t.Fatalf("DecodeWebhook returned error: %v", err)
+	}
+	m, ok := payload.(*map[string]interface{})
+	if !ok || (*m)["id"] != "eeafa272cebfd4b22385bc4b645e762c" {
+		t.Errorf("DecodeWebhook returned %#v, expected a map", payload)
+	}
+
+	if _, err := DecodeWebhook("order/create", []byte(`{}`)); err == nil {
+		t.Errorf("DecodeWebhook of an unknown topic should return an error")
+	}
+	if _, err := DecodeWebhook(WebhookTopicOrdersCreate, []byte(`{"id": "x"}`)); err == nil {
+		t.Errorf("DecodeWebhook of an invalid payload should return an error")
+	}
+}
+
+func TestWebhookCreateUnregisteredTopic(t *testing.T) {
+	setup()
+	defer teardown()
+
+	httpmock.RegisterResponder("POST", fmt.Sprintf("https://fooshop.myshopify.com/%s/webhooks.json", client.pathPrefix),
+		httpmock.NewBytesResponder(200, loadFixture("webhook.json")))
+
+	// topics are validated by Shopify, not against the topics known to this release
+	_, err := client.Webhook.Create(context.Background(), Webhook{Topic: "widgets/create", Address: "http://example.com"})
+	if err != nil {
+		t.Errorf("Webhook.Create of an unregistered topic returned error: %v", err)
+	}
+
+	info := httpmock.GetCallCountInfo()
+	if calls := info[fmt.Sprintf("POST https://fooshop.myshopify.com/%s/webhooks.json", client.pathPrefix)]; calls != 1 {
+		t.Errorf("Webhook.Create called the API %d times, expected 1", calls)
+	}
+}
+
+func TestWithWebhookTopicValidation(t *testing.T) {
+	setup()
+	defer teardown()
+
+	WithWebhookTopicValidation()(client)
+	httpmock.RegisterResponder("GET", fmt.Sprintf("https://fooshop.myshopify.com/%s/webhooks.json", client.pathPrefix),
+		httpmock.NewStringResponder(200, `{"webhooks": []}`))
+	httpmock.RegisterResponder("POST", fmt.Sprintf("https://fooshop.myshopify.com/%s/webhooks.json", client.pathPrefix),
+		httpmock.NewBytesResponder(200, loadFixture("webhook.json")))
+	httpmock.RegisterResponder("PUT", fmt.Sprintf("https://fooshop.myshopify.com/%s/webhooks/4759306.json", client.pathPrefix),
+		httpmock.NewBytesResponder(200, loadFixture("webhook.json")))
+
+	ctx := context.Background()
+	_, err := client.Webhook.Create(ctx, Webhook{Topic: "order/create", Address: "http://example.com"})
+	if !errors.Is(err, ErrUnknownWebhookTopic) || !strings.Contains(err.Error(), `"order/create"`) {
+		t.Errorf("Webhook.Create returned error %v, expected ErrUnknownWebhookTopic", err)
+	}
+
+	_, err = client.Webhook.Update(ctx, Webhook{Id: 4759306, Topic: "order/create"})
+	if !errors.Is(err, ErrUnknownWebhookTopic) {
+		t.Errorf("Webhook.Update returned error %v, expected ErrUnknownWebhookTopic", err)
+	}
+
+	_, err = client.Webhook.Sync(ctx, []Webhook{
+		{Topic: "orders/paid", Address: "http://example.com"},
+		{Topic: "order/create", Address: "http://example.com"},
+	}, nil)
+	if !errors.Is(err, ErrUnknownWebhookTopic) {
+		t.Errorf("Webhook.Sync returned error %v, expected ErrUnknownWebhookTopic", err)
+	}
+
+	if calls := httpmock.GetTotalCallCount(); calls != 0 {
+		t.Errorf("made %d calls with an unknown topic, expected none", calls)
+	}
+
+	if _, err := client.Webhook.Create(ctx, Webhook{Topic: string(WebhookTopicOrdersCreate), Address: "http://example.com"}); err != nil {
+		t.Errorf("Webhook.Create returned error: %v", err)
+	}
+	if _, err := client.Webhook.Update(ctx, Webhook{Id: 4759306, Address: "http://example.com"}); err != nil {
+		t.Errorf("Webhook.Update without a topic returned error: %v", err)
+	}
+}
+
+func TestWebhookHandlerDecoded(t *testing.T) {
+	handler := NewWebhookHandler(webhookApp)
+
+	var got []interface{}
+	handler.HandleDecoded(func(ctx context.Context, meta WebhookMeta, payload interface{}) error {
+		got = append(got, payload)
+		return nil
+	})
+
+	for _, req := range []*http.Request{
+		newWebhookRequest("orders/paid", `{"id": 1}`),
+		newWebhookRequest("themes/publish", `{"id": 2, "name": "Dawn"}`),
+		newWebhookRequest("widgets/create", `{"id": 3}`),
+	} {
+		if status := serveWebhook(handler, req); status != http.StatusOK {
+			t.Errorf("ServeHTTP responded %d, expected %d", status, http.StatusOK)
+		}
+	}
+
+	if status := serveWebhook(handler, newWebhookRequest("orders/paid", `{"id": "x"}`)); status != http.StatusBadRequest {
+		t.Errorf("ServeHTTP responded %d to an invalid payload, expected %d", status, http.StatusBadRequest)
+	}
+
+	if len(got) != 2 {
+		t.Fatalf("callback received %d payloads, expected 2", len(got))
+	}
+	if order, ok := got[0].(*Order); !ok || order.Id != 1 {
+		t.Errorf("callback received %#v, expected order 1", got[0])
+	}
+	if theme, ok := got[1].(*Theme); !ok || theme.Name != "Dawn" {
+		t.Errorf("callback received %#v, expected theme Dawn", got[1])
+	}
+}